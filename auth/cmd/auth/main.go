@@ -1,15 +1,28 @@
 package main
 
 import (
+	"auth/internal/admin"
+	"auth/internal/bans"
+	"auth/internal/grpcserver"
 	"auth/internal/handlers/auth"
 	"auth/internal/handlers/auth/repository"
 	"auth/internal/handlers/auth/security"
 	"auth/internal/handlers/users"
+	"auth/internal/janitor"
+	"auth/internal/lib/digest"
+	"auth/internal/lib/pagination"
+	"auth/internal/lib/sealing"
+	"auth/internal/outbox"
 	"auth/internal/storage/postgresql"
+	"context"
+	"errors"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/flores666/profileshare-lib/config"
 	"github.com/flores666/profileshare-lib/eventBus"
@@ -50,17 +63,83 @@ func main() {
 		}
 	}(storage)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	deps := buildDependencies(logger, storage)
+
+	go janitor.NewJanitor(storage, janitor.MustLoadSettings(), logger).Run(ctx)
+
+	// events are saved to the outbox with the state changes and published by the relay with the aggregate id as the key
+	producer := outbox.NewKafkaProducer(cfg.Producer.Brokers)
+	defer func() {
+		_ = producer.Close()
+	}()
+
+	go outbox.NewRelay(storage, producer, deps.sealer, outbox.MustLoadSettings(), logger).Run(ctx)
+
+	bansWorker := bans.NewWorker(eventBus.NewConsumer(cfg.Consumer.Brokers, auth.UserBanRequestedTopic, "auth_bans"), deps.unitOfWork, logger)
+
+	go func() {
+		if consumeErr := bansWorker.Run(ctx); consumeErr != nil {
+			logger.Error("consume error", slog.String("error", consumeErr.Error()))
+		}
+	}()
+
+	grpcSettings := grpcserver.MustLoadSettings()
+	grpcServer := grpcserver.NewServer(
+		grpcSettings,
+		deps.settings.InternalClients,
+		logger,
+		grpcserver.NewAuthServer(deps.usersService, deps.jwtService, deps.unitOfWork.Denylist(), logger),
+	)
+
+	listener, err := net.Listen("tcp", grpcSettings.Address)
+	if err != nil {
+		logger.Error("failed to listen grpc address", plog.Error(err))
+		os.Exit(1)
+	}
+
+	go func() {
+		logger.Info("starting grpc server", slog.String("address", grpcSettings.Address))
+		if serveErr := grpcServer.Serve(listener); serveErr != nil {
+			logger.Error("failed to start grpc server", plog.Error(serveErr))
+		}
+	}()
+
 	server := &http.Server{
 		Addr:         cfg.HttpServer.Address,
-		Handler:      buildHandler(logger, storage, cfg),
+		Handler:      buildHandler(logger, cfg, deps),
 		ReadTimeout:  cfg.HttpServer.Timeout,
 		WriteTimeout: cfg.HttpServer.Timeout,
 		IdleTimeout:  cfg.HttpServer.IddleTimeout,
 	}
 
+	// diagnostics are served on a separate listener, so they are not exposed with the public api
+	adminServer := admin.NewServer(admin.MustLoadSettings())
+
+	go func() {
+		logger.Info("starting admin server", slog.String("address", adminServer.Addr))
+		if serveErr := adminServer.ListenAndServe(); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			logger.Error("failed to start admin server", plog.Error(serveErr))
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("shutting down gracefully")
+
+		grpcServer.GracefulStop()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HttpServer.Timeout)
+		defer cancel()
+		_ = adminServer.Shutdown(shutdownCtx)
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
 	logger.Info("starting application", slog.String("address", cfg.HttpServer.Address))
 
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("failed to start http server", plog.Error(err))
 	}
 
@@ -82,21 +161,47 @@ func setupLogger(env string) *slog.Logger {
 	return logger
 }
 
-func buildHandler(logger *slog.Logger, storage *sqlx.DB, cfg *config.Config) http.Handler {
+type dependencies struct {
+	settings     security.Settings
+	jwtService   *security.JWTService
+	hasher       *digest.Hasher
+	sealer       *sealing.Sealer
+	unitOfWork   repository.UnitOfWork
+	usersService users.Service
+}
+
+// buildDependencies creates services shared by the http and grpc servers
+func buildDependencies(logger *slog.Logger, storage *sqlx.DB) *dependencies {
+	settings := security.MustLoadSettings()
+	hasher := digest.NewHasher(settings.TokenPepper)
+	sealer := sealing.NewSealer(settings.OutboxKey)
+
+	return &dependencies{
+		settings:     settings,
+		jwtService:   security.NewJWTService(settings),
+		hasher:       hasher,
+		sealer:       sealer,
+		unitOfWork:   repository.NewUnitOfWork(storage, hasher, sealer),
+		usersService: users.NewService(users.NewRepository(storage), pagination.NewPaginator(pagination.MustLoadSettings()), logger),
+	}
+}
+
+func buildHandler(logger *slog.Logger, cfg *config.Config, deps *dependencies) http.Handler {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
 	router.Use(plog.NewRequestLogMiddleware(logger))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.Use(security.ImpersonationMiddleware(deps.jwtService))
 
-	users.NewUsersHandler(users.NewService(users.NewRepository(storage), logger)).RegisterRoutes(router)
+	users.NewUsersHandler(deps.usersService).RegisterRoutes(router)
 	auth.NewAuthHandler(auth.NewService(
-		repository.NewUnitOfWork(storage),
-		security.NewJWTService(security.MustLoadSettings()),
+		deps.unitOfWork,
+		deps.jwtService,
+		deps.hasher,
 		logger,
-		eventBus.NewProducer(cfg.Producer.Brokers),
-	)).RegisterRoutes(router)
+	), security.ClientAuthMiddleware(deps.settings.InternalClients)).RegisterRoutes(router)
 
 	return router
 }
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"auth/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// Address accepts only local connections by default
+	Address string
+	Timeout time.Duration
+}
+
+func MustLoadSettings() Settings {
+	return Settings{
+		Address: config.GetString("ADMIN__ADDRESS", "127.0.0.1:6060"),
+		Timeout: time.Duration(config.MustGetInt("ADMIN__TIMEOUT_SECONDS", 10)) * time.Second,
+	}
+}
@@ -0,0 +1,114 @@
+package bans
+
+import (
+	"auth/internal/handlers/auth"
+	"auth/internal/handlers/auth/repository"
+	"auth/internal/handlers/auth/security"
+	"auth/internal/storage"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/eventBus"
+)
+
+// Worker bans users on requests of moderators of the content service consuming auth.UserBanRequestedTopic.
+// Sessions of the banned user are revoked, access tokens are rejected by validation until they expire.
+type Worker struct {
+	consumer   eventBus.Consumer
+	unitOfWork repository.UnitOfWork
+	logger     *slog.Logger
+}
+
+func NewWorker(consumer eventBus.Consumer, unitOfWork repository.UnitOfWork, logger *slog.Logger) *Worker {
+	return &Worker{
+		consumer:   consumer,
+		unitOfWork: unitOfWork,
+		logger:     logger.With(slog.String("caller", "bans.worker")),
+	}
+}
+
+// Run consumes events until the context is done
+func (w *Worker) Run(ctx context.Context) error {
+	return w.consumer.Consume(ctx, func(data []byte) error {
+		var message auth.UserBanRequestedMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			w.logger.Error("unmarshal error", slog.String("error", err.Error()))
+			return nil
+		}
+
+		return w.handle(ctx, message)
+	})
+}
+
+func (w *Worker) handle(ctx context.Context, message auth.UserBanRequestedMessage) error {
+	// the audit entry is written with the id of the decision, so redelivered events are skipped
+	applied, err := w.unitOfWork.Audit().Exists(ctx, message.DecisionId)
+	if err != nil {
+		w.logger.Error("could not check audit log", slog.String("error", err.Error()), slog.String("decision_id", message.DecisionId))
+		return err
+	}
+
+	if applied || !message.BannedBefore.After(time.Now().UTC()) {
+		return nil
+	}
+
+	allowed, err := w.canBan(ctx, message.ModeratorId, message.UserId)
+	if err != nil {
+		w.logger.Error("could not get user role", slog.String("error", err.Error()), slog.String("decision_id", message.DecisionId))
+		return err
+	}
+
+	if !allowed {
+		w.logger.Warn("ban request rejected", slog.String("moderator_id", message.ModeratorId), slog.String("user_id", message.UserId))
+		return nil
+	}
+
+	err = w.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := w.unitOfWork.Users().Ban(ctx, message.UserId, message.BannedBefore); err != nil {
+			return err
+		}
+
+		if err := w.unitOfWork.Tokens().RevokeByUser(ctx, message.UserId); err != nil {
+			return err
+		}
+
+		return w.unitOfWork.Audit().Write(ctx, &storage.AuditEntry{
+			Id:        message.DecisionId,
+			ActorId:   message.ModeratorId,
+			TargetId:  message.UserId,
+			Action:    auth.AuditUserBan,
+			Details:   message.Reason,
+			CreatedAt: time.Now().UTC(),
+		})
+	})
+
+	if err != nil {
+		w.logger.Error("could not ban user", slog.String("error", err.Error()), slog.String("user_id", message.UserId))
+		return err
+	}
+
+	w.logger.Info("user banned", slog.String("moderator_id", message.ModeratorId), slog.String("user_id", message.UserId))
+
+	return nil
+}
+
+// canBan ensures the moderator role is still granted and is higher than the role of the user
+func (w *Worker) canBan(ctx context.Context, moderatorId string, userId string) (bool, error) {
+	moderatorLevel, err := w.unitOfWork.Users().GetRoleLevel(ctx, moderatorId)
+	if err != nil {
+		return false, err
+	}
+
+	if moderatorLevel < security.RoleLevelModerator {
+		return false, nil
+	}
+
+	userLevel, err := w.unitOfWork.Users().GetRoleLevel(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return userLevel < moderatorLevel, nil
+}
@@ -0,0 +1,43 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+// ServeInProcess serves the server over an in-memory bufconn listener and returns a client connection to it.
+// It is intended for tests of the gRPC API and of its consumers without opening network ports.
+// The returned cleanup function closes the connection and stops the server.
+func ServeInProcess(server *grpc.Server, options ...grpc.DialOption) (*grpc.ClientConn, func(), error) {
+	listener := bufconn.Listen(bufSize)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	options = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, options...)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", options...)
+	if err != nil {
+		server.Stop()
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		_ = conn.Close()
+		server.Stop()
+	}
+
+	return conn, cleanup, nil
+}
@@ -0,0 +1,23 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+)
+
+// ClientCredentials are per-RPC credentials of an internal client (SECURITY__INTERNAL_CLIENTS)
+type ClientCredentials struct {
+	Id     string
+	Secret string
+	// Insecure allows sending credentials without TLS, e.g. inside the docker network or over bufconn
+	Insecure bool
+}
+
+func (c ClientCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	token := base64.StdEncoding.EncodeToString([]byte(c.Id + ":" + c.Secret))
+	return map[string]string{"authorization": "Basic " + token}, nil
+}
+
+func (c ClientCredentials) RequireTransportSecurity() bool {
+	return !c.Insecure
+}
@@ -0,0 +1,84 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// deadlineInterceptor applies the default timeout to calls without a deadline and caps long ones
+func deadlineInterceptor(settings Settings) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		timeout := settings.DefaultTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = min(time.Until(deadline), settings.MaxTimeout)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// clientAuthInterceptor authenticates internal services with "authorization: Basic" metadata,
+// reflection is available without credentials
+func clientAuthInterceptor(clients map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get("authorization")
+		if len(values) == 0 || !validClient(clients, values[0]) {
+			return nil, status.Error(codes.Unauthenticated, "invalid client credentials")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func loggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		started := time.Now()
+		resp, err := handler(ctx, req)
+
+		logger.Info("grpc request",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(started)),
+		)
+
+		return resp, err
+	}
+}
+
+func validClient(clients map[string]string, header string) bool {
+	encoded, ok := strings.CutPrefix(header, "Basic ")
+	if !ok {
+		return false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+
+	id, secret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return false
+	}
+
+	expected, ok := clients[id]
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1
+}
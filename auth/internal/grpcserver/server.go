@@ -0,0 +1,25 @@
+package grpcserver
+
+import (
+	authv1 "auth/api/auth/v1"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer creates the internal gRPC server of the auth service with reflection enabled
+func NewServer(settings Settings, clients map[string]string, logger *slog.Logger, service authv1.AuthServiceServer) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(logger),
+			clientAuthInterceptor(clients),
+			deadlineInterceptor(settings),
+		),
+	)
+
+	authv1.RegisterAuthServiceServer(server, service)
+	reflection.Register(server)
+
+	return server
+}
@@ -0,0 +1,193 @@
+package grpcserver
+
+import (
+	authv1 "auth/api/auth/v1"
+	"auth/internal/handlers/auth/repository"
+	"auth/internal/handlers/auth/security"
+	"auth/internal/handlers/users"
+	"auth/internal/lib/mapper"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type authServer struct {
+	authv1.UnimplementedAuthServiceServer
+	usersService users.Service
+	jwtService   *security.JWTService
+	denylist     repository.DenylistRepository
+	logger       *slog.Logger
+}
+
+func NewAuthServer(
+	usersService users.Service,
+	jwtService *security.JWTService,
+	denylist repository.DenylistRepository,
+	logger *slog.Logger,
+) authv1.AuthServiceServer {
+	return &authServer{
+		usersService: usersService,
+		jwtService:   jwtService,
+		denylist:     denylist,
+		logger:       logger.With(slog.String("caller", "grpcserver.authServer")),
+	}
+}
+
+func (s *authServer) ValidateToken(ctx context.Context, request *authv1.ValidateTokenRequest) (*authv1.ValidateTokenResponse, error) {
+	if request.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, err := s.jwtService.ParseAccessToken(request.GetToken())
+	if err != nil {
+		return &authv1.ValidateTokenResponse{Valid: false, Reason: err.Error()}, nil
+	}
+
+	if claims.Jti != "" {
+		denied, err := s.denylist.Contains(ctx, claims.Jti)
+		if err != nil {
+			s.logger.Error("failed to check denylist", slog.String("error", err.Error()))
+			return nil, status.Error(codes.Internal, "failed to validate token")
+		}
+
+		if denied {
+			return &authv1.ValidateTokenResponse{Valid: false, Reason: "token revoked"}, nil
+		}
+	}
+
+	// tokens of deleted and banned users stay valid until they expire, so the user is checked like on introspection
+	reason, err := s.checkUser(ctx, claims.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if reason != "" {
+		return &authv1.ValidateTokenResponse{Valid: false, Reason: reason}, nil
+	}
+
+	return &authv1.ValidateTokenResponse{
+		Valid:     true,
+		UserId:    claims.UserId,
+		ActorId:   claims.ActorId,
+		Jti:       claims.Jti,
+		ExpiresAt: timestamppb.New(claims.ExpiresAt),
+	}, nil
+}
+
+func (s *authServer) GetUser(ctx context.Context, request *authv1.GetUserRequest) (*authv1.User, error) {
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	response := s.usersService.GetById(ctx, request.GetId())
+	if !response.Ok() {
+		return nil, toStatus(response)
+	}
+
+	user, ok := response.Data.(mapper.UserDto)
+	if !ok || user.Id == "" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return mapUser(&user), nil
+}
+
+func (s *authServer) GetUsersBatch(ctx context.Context, request *authv1.GetUsersBatchRequest) (*authv1.GetUsersBatchResponse, error) {
+	response := s.usersService.GetByIds(ctx, request.GetIds())
+	if !response.Ok() {
+		return nil, toStatus(response)
+	}
+
+	list, _ := response.Data.([]*mapper.UserDto)
+
+	found := make(map[string]struct{}, len(list))
+	result := &authv1.GetUsersBatchResponse{
+		Users: make([]*authv1.User, 0, len(list)),
+	}
+
+	for _, user := range list {
+		found[user.Id] = struct{}{}
+		result.Users = append(result.Users, mapUser(user))
+	}
+
+	for _, id := range request.GetIds() {
+		if _, ok := found[id]; !ok {
+			result.MissingIds = append(result.MissingIds, id)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *authServer) CheckPermission(ctx context.Context, request *authv1.CheckPermissionRequest) (*authv1.CheckPermissionResponse, error) {
+	if request.GetUserId() == "" || request.GetPermission() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and permission are required")
+	}
+
+	response := s.usersService.CheckPermission(ctx, request.GetUserId(), request.GetPermission())
+	if !response.Ok() {
+		return nil, toStatus(response)
+	}
+
+	permission, _ := response.Data.(users.PermissionDto)
+
+	return &authv1.CheckPermissionResponse{
+		Allowed: permission.Allowed,
+		Reason:  permission.Reason,
+	}, nil
+}
+
+// checkUser returns the reason why the user may not act or an empty string if the user exists and is not banned
+func (s *authServer) checkUser(ctx context.Context, userId string) (string, error) {
+	response := s.usersService.GetById(ctx, userId)
+	if !response.Ok() {
+		return "", toStatus(response)
+	}
+
+	user, ok := response.Data.(mapper.UserDto)
+	if !ok || user.Id == "" {
+		return "user not found", nil
+	}
+
+	if user.BannedBefore.After(time.Now().UTC()) {
+		return "user is banned", nil
+	}
+
+	return "", nil
+}
+
+func mapUser(user *mapper.UserDto) *authv1.User {
+	result := &authv1.User{
+		Id:       user.Id,
+		Nickname: user.Nickname,
+		Email:    user.Email,
+		RoleId:   user.RoleId,
+	}
+
+	// repositories return 0001-01-01 instead of NULL
+	if user.BannedBefore.Year() > 1 {
+		result.BannedBefore = timestamppb.New(user.BannedBefore)
+	}
+
+	return result
+}
+
+// codesByMessage maps error messages of the users service to status codes, other errors are internal
+var codesByMessage = map[string]codes.Code{
+	users.ErrValidation:  codes.InvalidArgument,
+	users.ErrFailedQuery: codes.Unavailable,
+}
+
+func toStatus(response api.AppResponse) error {
+	code, ok := codesByMessage[response.Message]
+	if !ok {
+		code = codes.Internal
+	}
+
+	return status.Error(code, response.Message)
+}
@@ -0,0 +1,393 @@
+package grpcserver
+
+import (
+	authv1 "auth/api/auth/v1"
+	"auth/internal/handlers/auth/security"
+	"auth/internal/handlers/users"
+	"auth/internal/lib/pagination"
+	"auth/internal/storage"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	clientId     = "content"
+	clientSecret = "content-secret"
+
+	activeUserId = "00000000-0000-0000-0000-000000000001"
+	bannedUserId = "00000000-0000-0000-0000-000000000002"
+	adminUserId  = "00000000-0000-0000-0000-000000000003"
+	// slowUserId blocks the repository until the call is cancelled
+	slowUserId   = "00000000-0000-0000-0000-000000000004"
+	absentUserId = "00000000-0000-0000-0000-000000000005"
+)
+
+type fakeRepository struct {
+	users  map[string]*storage.User
+	levels map[string]int
+
+	mu sync.Mutex
+	// deadlines are the time left until the deadline of calls, zero for calls without one
+	deadlines []time.Duration
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		users: map[string]*storage.User{
+			activeUserId: {Id: activeUserId, Nickname: "active", Email: "active@example.com"},
+			bannedUserId: {Id: bannedUserId, Nickname: "banned", BannedBefore: time.Now().UTC().Add(time.Hour)},
+			adminUserId:  {Id: adminUserId, Nickname: "admin"},
+		},
+		levels: map[string]int{adminUserId: security.RoleLevelAdmin},
+	}
+}
+
+func (r *fakeRepository) GetById(ctx context.Context, id string) (*storage.User, error) {
+	r.mu.Lock()
+	var left time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		left = time.Until(deadline)
+	}
+	r.deadlines = append(r.deadlines, left)
+	r.mu.Unlock()
+
+	if id == slowUserId {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return r.users[id], nil
+}
+
+func (r *fakeRepository) Query(context.Context, users.QueryFilter, pagination.Page) ([]*storage.User, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) Update(context.Context, storage.UpdateUser) error {
+	return nil
+}
+
+func (r *fakeRepository) GetByIds(_ context.Context, ids []string) ([]*storage.User, error) {
+	var result []*storage.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			result = append(result, user)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeRepository) GetRoleLevel(_ context.Context, id string) (int, error) {
+	return r.levels[id], nil
+}
+
+type fakeDenylist struct {
+	denied map[string]bool
+}
+
+func (d *fakeDenylist) Add(_ context.Context, jti string, _ string, _ time.Time) error {
+	d.denied[jti] = true
+	return nil
+}
+
+func (d *fakeDenylist) Contains(_ context.Context, jti string) (bool, error) {
+	return d.denied[jti], nil
+}
+
+type testEnv struct {
+	client     authv1.AuthServiceClient
+	repository *fakeRepository
+	denylist   *fakeDenylist
+	jwtService *security.JWTService
+}
+
+func newTestEnv(t *testing.T, settings Settings, options ...grpc.DialOption) *testEnv {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	env := &testEnv{
+		repository: newFakeRepository(),
+		denylist:   &fakeDenylist{denied: make(map[string]bool)},
+		jwtService: security.NewJWTService(security.Settings{AccessSecret: "test-secret", AccessTTL: 10, RefreshTTL: 1}),
+	}
+
+	usersService := users.NewService(env.repository, nil, logger)
+	server := NewServer(settings, map[string]string{clientId: clientSecret}, logger,
+		NewAuthServer(usersService, env.jwtService, env.denylist, logger))
+
+	conn, cleanup, err := ServeInProcess(server, options...)
+	if err != nil {
+		t.Fatalf("serve in process: %v", err)
+	}
+	t.Cleanup(cleanup)
+
+	env.client = authv1.NewAuthServiceClient(conn)
+
+	return env
+}
+
+func newAuthorizedEnv(t *testing.T) *testEnv {
+	return newTestEnv(t, testSettings(),
+		grpc.WithPerRPCCredentials(ClientCredentials{Id: clientId, Secret: clientSecret, Insecure: true}))
+}
+
+func testSettings() Settings {
+	return Settings{DefaultTimeout: time.Second, MaxTimeout: 5 * time.Second}
+}
+
+func (e *testEnv) accessToken(t *testing.T, userId string) string {
+	t.Helper()
+
+	tokens, err := e.jwtService.GenerateTokens(userId)
+	if err != nil {
+		t.Fatalf("generate tokens: %v", err)
+	}
+
+	return tokens.AccessToken
+}
+
+func TestValidateToken(t *testing.T) {
+	env := newAuthorizedEnv(t)
+
+	revoked := env.accessToken(t, activeUserId)
+	claims, err := env.jwtService.ParseAccessToken(revoked)
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	env.denylist.denied[claims.Jti] = true
+
+	tests := []struct {
+		name   string
+		token  string
+		valid  bool
+		reason string
+	}{
+		{name: "active user", token: env.accessToken(t, activeUserId), valid: true},
+		{name: "revoked token", token: revoked, reason: "token revoked"},
+		{name: "banned user", token: env.accessToken(t, bannedUserId), reason: "user is banned"},
+		{name: "deleted user", token: env.accessToken(t, absentUserId), reason: "user not found"},
+		{name: "malformed token", token: "not-a-token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := env.client.ValidateToken(context.Background(), &authv1.ValidateTokenRequest{Token: test.token})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if response.GetValid() != test.valid {
+				t.Fatalf("valid = %v, want %v (reason %q)", response.GetValid(), test.valid, response.GetReason())
+			}
+
+			if test.reason != "" && response.GetReason() != test.reason {
+				t.Errorf("reason = %q, want %q", response.GetReason(), test.reason)
+			}
+
+			if test.valid && response.GetUserId() != activeUserId {
+				t.Errorf("user id = %q, want %q", response.GetUserId(), activeUserId)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRequiresToken(t *testing.T) {
+	env := newAuthorizedEnv(t)
+
+	_, err := env.client.ValidateToken(context.Background(), &authv1.ValidateTokenRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	env := newAuthorizedEnv(t)
+
+	tests := []struct {
+		name   string
+		id     string
+		code   codes.Code
+		banned bool
+	}{
+		{name: "found", id: activeUserId, code: codes.OK},
+		{name: "banned", id: bannedUserId, code: codes.OK, banned: true},
+		{name: "not found", id: absentUserId, code: codes.NotFound},
+		{name: "empty id", id: "", code: codes.InvalidArgument},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user, err := env.client.GetUser(context.Background(), &authv1.GetUserRequest{Id: test.id})
+			if status.Code(err) != test.code {
+				t.Fatalf("code = %v, want %v", status.Code(err), test.code)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if user.GetId() != test.id {
+				t.Errorf("id = %q, want %q", user.GetId(), test.id)
+			}
+
+			if (user.GetBannedBefore() != nil) != test.banned {
+				t.Errorf("banned before = %v, want set %v", user.GetBannedBefore(), test.banned)
+			}
+		})
+	}
+}
+
+func TestGetUsersBatch(t *testing.T) {
+	env := newAuthorizedEnv(t)
+
+	response, err := env.client.GetUsersBatch(context.Background(), &authv1.GetUsersBatchRequest{
+		Ids: []string{activeUserId, absentUserId, adminUserId},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.GetUsers()) != 2 {
+		t.Fatalf("users = %d, want 2", len(response.GetUsers()))
+	}
+
+	if missing := response.GetMissingIds(); len(missing) != 1 || missing[0] != absentUserId {
+		t.Errorf("missing ids = %v, want [%s]", missing, absentUserId)
+	}
+
+	_, err = env.client.GetUsersBatch(context.Background(), &authv1.GetUsersBatchRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty batch code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	env := newAuthorizedEnv(t)
+
+	tests := []struct {
+		name       string
+		userId     string
+		permission string
+		allowed    bool
+		reason     string
+	}{
+		{name: "admin bans", userId: adminUserId, permission: security.PermissionUsersBan, allowed: true},
+		{name: "user reads", userId: activeUserId, permission: security.PermissionUsersRead, allowed: true},
+		{name: "insufficient role", userId: activeUserId, permission: security.PermissionUsersBan, reason: "insufficient role"},
+		{name: "banned user", userId: bannedUserId, permission: security.PermissionUsersRead, reason: "user is banned"},
+		{name: "absent user", userId: absentUserId, permission: security.PermissionUsersRead, reason: "user not found"},
+		{name: "unknown permission", userId: adminUserId, permission: "users.unknown", reason: "unknown permission"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := env.client.CheckPermission(context.Background(), &authv1.CheckPermissionRequest{
+				UserId:     test.userId,
+				Permission: test.permission,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if response.GetAllowed() != test.allowed || response.GetReason() != test.reason {
+				t.Errorf("got (%v, %q), want (%v, %q)", response.GetAllowed(), response.GetReason(), test.allowed, test.reason)
+			}
+		})
+	}
+
+	_, err := env.client.CheckPermission(context.Background(), &authv1.CheckPermissionRequest{UserId: activeUserId})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("missing permission code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestUnauthenticatedClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []grpc.DialOption
+	}{
+		{name: "no credentials"},
+		{name: "wrong secret", options: []grpc.DialOption{
+			grpc.WithPerRPCCredentials(ClientCredentials{Id: clientId, Secret: "wrong", Insecure: true}),
+		}},
+		{name: "unknown client", options: []grpc.DialOption{
+			grpc.WithPerRPCCredentials(ClientCredentials{Id: "unknown", Secret: clientSecret, Insecure: true}),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(t, testSettings(), test.options...)
+
+			_, err := env.client.GetUser(context.Background(), &authv1.GetUserRequest{Id: activeUserId})
+			if status.Code(err) != codes.Unauthenticated {
+				t.Fatalf("code = %v, want %v", status.Code(err), codes.Unauthenticated)
+			}
+
+			if len(env.repository.deadlines) != 0 {
+				t.Errorf("repository was called by an unauthenticated client")
+			}
+		})
+	}
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	env := newAuthorizedEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := env.client.GetUser(ctx, &authv1.GetUserRequest{Id: slowUserId})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("code = %v, want %v", status.Code(err), codes.DeadlineExceeded)
+	}
+}
+
+func TestDeadlines(t *testing.T) {
+	settings := Settings{DefaultTimeout: 200 * time.Millisecond, MaxTimeout: 500 * time.Millisecond}
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		max     time.Duration
+	}{
+		{name: "default timeout without client deadline", max: settings.DefaultTimeout},
+		{name: "client deadline is capped", timeout: time.Minute, max: settings.MaxTimeout},
+		{name: "shorter client deadline is kept", timeout: 100 * time.Millisecond, max: 100 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(t, settings,
+				grpc.WithPerRPCCredentials(ClientCredentials{Id: clientId, Secret: clientSecret, Insecure: true}))
+
+			ctx := context.Background()
+			if test.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.timeout)
+				defer cancel()
+			}
+
+			if _, err := env.client.GetUser(ctx, &authv1.GetUserRequest{Id: activeUserId}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(env.repository.deadlines) != 1 {
+				t.Fatalf("repository calls = %d, want 1", len(env.repository.deadlines))
+			}
+
+			if left := env.repository.deadlines[0]; left <= 0 || left > test.max {
+				t.Errorf("time left = %v, want in (0, %v]", left, test.max)
+			}
+		})
+	}
+}
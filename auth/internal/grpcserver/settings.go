@@ -0,0 +1,22 @@
+package grpcserver
+
+import (
+	"auth/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	Address string
+	// DefaultTimeout is applied to calls without a client deadline
+	DefaultTimeout time.Duration
+	// MaxTimeout caps client deadlines
+	MaxTimeout time.Duration
+}
+
+func MustLoadSettings() Settings {
+	return Settings{
+		Address:        config.GetString("GRPC__ADDRESS", ":9081"),
+		DefaultTimeout: time.Duration(config.MustGetInt("GRPC__DEFAULT_TIMEOUT_MS", 2000)) * time.Millisecond,
+		MaxTimeout:     time.Duration(config.MustGetInt("GRPC__MAX_TIMEOUT_MS", 10000)) * time.Millisecond,
+	}
+}
@@ -9,17 +9,29 @@ import (
 	"github.com/flores666/profileshare-lib/api"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
 )
 
 const BaseRoutePath = "/api/auth"
 
+// statuses maps error messages of the service to response codes
+var statuses = map[string]int{
+	ErrValidation:       http.StatusBadRequest,
+	ErrNotImpersonation: http.StatusBadRequest,
+	ErrUnauthorized:     http.StatusUnauthorized,
+	ErrForbidden:        http.StatusForbidden,
+	ErrUserNotFound:     http.StatusNotFound,
+}
+
 type Handler struct {
-	service Service
+	service     Service
+	clientsAuth func(http.Handler) http.Handler
 }
 
-func NewAuthHandler(service Service) *Handler {
+func NewAuthHandler(service Service, clientsAuth func(http.Handler) http.Handler) *Handler {
 	return &Handler{
-		service: service,
+		service:     service,
+		clientsAuth: clientsAuth,
 	}
 }
 
@@ -29,6 +41,14 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Post(BaseRoutePath+"/logout", h.logout)
 	r.Post(BaseRoutePath+"/refresh", h.refresh)
 	r.Post(BaseRoutePath+"/confirm", h.confirm)
+	r.Post(BaseRoutePath+"/impersonate", h.impersonate)
+	r.Post(BaseRoutePath+"/impersonate/stop", h.stopImpersonation)
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.clientsAuth)
+		r.Post(BaseRoutePath+"/introspect", h.introspect)
+		r.Post(BaseRoutePath+"/revoke", h.revoke)
+	})
 }
 
 func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
@@ -141,6 +161,71 @@ func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
 	handlers.Respond(w, r, http.StatusOK, result)
 }
 
+func (h *Handler) impersonate(w http.ResponseWriter, r *http.Request) {
+	var request ImpersonateRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(err.Error(), nil))
+		return
+	}
+
+	request.AccessToken = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	request.Ip = r.RemoteAddr
+
+	// refresh token cookie is intentionally not set: impersonation sessions cannot be extended
+	handlers.WriteResponse(w, r, h.service.Impersonate(r.Context(), request), statuses)
+}
+
+func (h *Handler) stopImpersonation(w http.ResponseWriter, r *http.Request) {
+	request := StopImpersonationRequest{
+		AccessToken: strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		Ip:          r.RemoteAddr,
+	}
+
+	handlers.WriteResponse(w, r, h.service.StopImpersonation(r.Context(), request), statuses)
+}
+
+func (h *Handler) introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("token") == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	result := h.service.Introspect(r.Context(), IntrospectRequest{
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+	})
+
+	if !result.Ok() {
+		handlers.Respond(w, r, http.StatusInternalServerError, result)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, result.Data)
+}
+
+func (h *Handler) revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("token") == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	result := h.service.Revoke(r.Context(), RevokeRequest{
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+	})
+
+	if !result.Ok() {
+		handlers.Respond(w, r, http.StatusServiceUnavailable, result)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func createTokenCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "rt",
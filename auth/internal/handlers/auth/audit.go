@@ -0,0 +1,7 @@
+package auth
+
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationStop  = "impersonation.stop"
+	AuditUserBan            = "user.ban"
+)
@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 type RegisterUserRequest struct {
 	Nickname  string `json:"nickname" validate:"required"`
 	Email     string `json:"email" validate:"required,email"`
@@ -25,3 +27,54 @@ type LogoutRequest struct {
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refreshToken" validate:"required"`
 }
+
+type ImpersonateRequest struct {
+	UserId      string `json:"userId" validate:"required"`
+	Reason      string `json:"reason" validate:"required"`
+	AccessToken string `json:"-"`
+	Ip          string `json:"-"`
+}
+
+type StopImpersonationRequest struct {
+	AccessToken string `json:"-"`
+	Ip          string `json:"-"`
+}
+
+type ImpersonationDto struct {
+	AccessToken string    `json:"accessToken"`
+	UserId      string    `json:"userId"`
+	ActorId     string    `json:"actorId"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// IntrospectRequest is an RFC 7662 introspection request
+type IntrospectRequest struct {
+	Token         string
+	TokenTypeHint string
+}
+
+// RevokeRequest is an RFC 7009 revocation request
+type RevokeRequest struct {
+	Token         string
+	TokenTypeHint string
+}
+
+// IntrospectionDto is an RFC 7662 introspection response
+type IntrospectionDto struct {
+	Active    bool      `json:"active"`
+	Sub       string    `json:"sub,omitempty"`
+	TokenType string    `json:"token_type,omitempty"`
+	Jti       string    `json:"jti,omitempty"`
+	Exp       int64     `json:"exp,omitempty"`
+	Iat       int64     `json:"iat,omitempty"`
+	Act       *ActorDto `json:"act,omitempty"`
+}
+
+type ActorDto struct {
+	Sub string `json:"sub"`
+}
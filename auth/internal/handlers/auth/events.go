@@ -1,7 +1,10 @@
 package auth
 
+import "time"
+
 const (
-	UserCreatedTopic = "users.registered"
+	UserCreatedTopic      = "users.registered"
+	UserBanRequestedTopic = "users.ban_requested"
 )
 
 type UserRegisteredMessage struct {
@@ -10,3 +13,13 @@ type UserRegisteredMessage struct {
 	ReturnUrl      string `json:"returnUrl"`
 	IdempotencyKey string `json:"idempotencyKey"`
 }
+
+// UserBanRequestedMessage is published by the content service when a moderator escalates a report to a ban
+type UserBanRequestedMessage struct {
+	DecisionId   string    `json:"decisionId"`
+	UserId       string    `json:"userId"`
+	ModeratorId  string    `json:"moderatorId"`
+	Reason       string    `json:"reason"`
+	BannedBefore time.Time `json:"bannedBefore"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
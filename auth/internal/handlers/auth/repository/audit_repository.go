@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"auth/internal/storage"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditRepository interface {
+	Write(ctx context.Context, entry *storage.AuditEntry) error
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+type auditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (a *auditRepository) Write(ctx context.Context, entry *storage.AuditEntry) error {
+	executor := getExecutor(ctx, a.db)
+
+	query := `
+		INSERT INTO authorization_service.audit_log (
+			id,
+			actor_id,
+			target_id,
+			action,
+			details,
+			ip,
+			created_at
+		) VALUES (
+			:id,
+			:actor_id,
+			CAST(NULLIF(:target_id, '') AS uuid),
+			:action,
+			:details,
+			:ip,
+			:created_at
+		)
+	`
+
+	_, err := executor.NamedExecContext(ctx, query, entry)
+	return err
+}
+
+func (a *auditRepository) Exists(ctx context.Context, id string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM authorization_service.audit_log WHERE id = $1)`
+
+	var exists bool
+	err := a.db.GetContext(ctx, &exists, query, id)
+	return exists, err
+}
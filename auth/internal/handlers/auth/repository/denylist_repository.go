@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DenylistRepository stores ids (jti) of access tokens revoked before their expiration
+type DenylistRepository interface {
+	Add(ctx context.Context, jti string, userId string, expiresAt time.Time) error
+	Contains(ctx context.Context, jti string) (bool, error)
+}
+
+type denylistRepository struct {
+	db *sqlx.DB
+}
+
+func NewDenylistRepository(db *sqlx.DB) DenylistRepository {
+	return &denylistRepository{db: db}
+}
+
+func (d *denylistRepository) Add(ctx context.Context, jti string, userId string, expiresAt time.Time) error {
+	executor := getExecutor(ctx, d.db)
+
+	query := `
+		INSERT INTO authorization_service.access_token_denylist (jti, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	_, err := executor.ExecContext(ctx, query, jti, userId, expiresAt, time.Now().UTC())
+	return err
+}
+
+func (d *denylistRepository) Contains(ctx context.Context, jti string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM authorization_service.access_token_denylist
+			WHERE jti = $1 AND expires_at > $2
+		)
+	`
+
+	var exists bool
+	err := d.db.GetContext(ctx, &exists, query, jti, time.Now().UTC())
+	return exists, err
+}
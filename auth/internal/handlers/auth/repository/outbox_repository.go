@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"auth/internal/lib/sealing"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxRepository saves events in the transaction of the state change, they are published by outbox.Relay.
+// Events with the same key are published in the order they were added. Payloads are saved encrypted,
+// so confirmation codes are not readable in the table until the events are published and deleted.
+type OutboxRepository interface {
+	Add(ctx context.Context, topic string, key string, payload any) error
+}
+
+type outboxRepository struct {
+	db     *sqlx.DB
+	sealer *sealing.Sealer
+}
+
+func NewOutboxRepository(db *sqlx.DB, sealer *sealing.Sealer) OutboxRepository {
+	return &outboxRepository{db: db, sealer: sealer}
+}
+
+func (o *outboxRepository) Add(ctx context.Context, topic string, key string, payload any) error {
+	executor := getExecutor(ctx, o.db)
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// the sealed payload is saved as a json string
+	data, err = json.Marshal(o.sealer.Seal(data))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO authorization_service.outbox (aggregate_id, topic, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, CAST($3 AS jsonb), $4, $4)
+	`
+
+	_, err = executor.ExecContext(ctx, query, key, topic, string(data), time.Now().UTC())
+	return err
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"auth/internal/lib/digest"
 	"auth/internal/storage"
 	"context"
 	"database/sql"
@@ -15,21 +16,29 @@ import (
 type TokensRepository interface {
 	SaveToken(ctx context.Context, token *storage.Token) error
 	GetByToken(ctx context.Context, token string) (*storage.Token, error)
-	Revoke(ctx context.Context, token string) error
-	RevokeAndReplace(ctx context.Context, oldToken string, newTokenId string) error
+	Revoke(ctx context.Context, id string) error
+	RevokeAndReplace(ctx context.Context, oldTokenId string, newTokenId string) error
+	// RevokeByUser revokes all active refresh tokens of the user
+	RevokeByUser(ctx context.Context, userId string) error
 }
 
+// tokensRepository stores only keyed hashes of refresh tokens,
+// storage.Token.Token returned from the repository contains the hash
 type tokensRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	hasher *digest.Hasher
 }
 
-func NewTokensRepository(db *sqlx.DB) TokensRepository {
-	return &tokensRepository{db: db}
+func NewTokensRepository(db *sqlx.DB, hasher *digest.Hasher) TokensRepository {
+	return &tokensRepository{db: db, hasher: hasher}
 }
 
 func (t *tokensRepository) SaveToken(ctx context.Context, token *storage.Token) error {
 	executor := getExecutor(ctx, t.db)
 
+	hashed := *token
+	hashed.Token = t.hasher.Hash(token.Token)
+
 	columns := []string{
 		"id",
 		"user_id",
@@ -68,7 +77,7 @@ func (t *tokensRepository) SaveToken(ctx context.Context, token *storage.Token)
 		VALUES (%s)
 	`, strings.Join(columns, ", "), strings.Join(values, ", "))
 
-	_, err := executor.NamedExecContext(ctx, query, token)
+	_, err := executor.NamedExecContext(ctx, query, &hashed)
 	return err
 }
 
@@ -81,11 +90,12 @@ func (t *tokensRepository) GetByToken(ctx context.Context, token string) (*stora
 		t.expires_at,
 		t.created_at,
 		COALESCE(t.replaced_by_token, '00000000-0000-0000-0000-000000000000') as replaced_by_token,
-		COALESCE(t.revoked_by_ip, '') as revoked_by_ip
+		COALESCE(t.revoked_by_ip, '') as revoked_by_ip,
+		COALESCE(t.revoked_at, make_timestamptz(1,1,1,0,0,0)) as revoked_at
 FROM authorization_service.tokens t WHERE t.token = $1`
 
 	var item storage.Token
-	err := t.db.GetContext(ctx, &item, query, token)
+	err := t.db.GetContext(ctx, &item, query, t.hasher.Hash(token))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -98,23 +108,35 @@ FROM authorization_service.tokens t WHERE t.token = $1`
 	return &item, err
 }
 
-func (t *tokensRepository) Revoke(ctx context.Context, token string) error {
+func (t *tokensRepository) Revoke(ctx context.Context, id string) error {
 	executor := getExecutor(ctx, t.db)
 	query := `
-		UPDATE authorization_service.tokens t
+		UPDATE authorization_service.tokens
 		SET
-			t.revoked_at = $1,
-			t.expires_at = $2
-		WHERE t.token = $3 AND t.revoked_at IS NULL
+			revoked_at = $1,
+			expires_at = $2
+		WHERE id = $3 AND revoked_at IS NULL
 	`
 
 	now := time.Now().UTC()
 
-	_, err := executor.ExecContext(ctx, query, token, now, now)
+	_, err := executor.ExecContext(ctx, query, now, now, id)
+	return err
+}
+
+func (t *tokensRepository) RevokeByUser(ctx context.Context, userId string) error {
+	executor := getExecutor(ctx, t.db)
+	query := `
+		UPDATE authorization_service.tokens
+		SET revoked_at = $1
+		WHERE user_id = $2 AND revoked_at IS NULL
+	`
+
+	_, err := executor.ExecContext(ctx, query, time.Now().UTC(), userId)
 	return err
 }
 
-func (t *tokensRepository) RevokeAndReplace(ctx context.Context, oldToken string, newTokenId string) error {
+func (t *tokensRepository) RevokeAndReplace(ctx context.Context, oldTokenId string, newTokenId string) error {
 	executor := getExecutor(ctx, t.db)
 
 	query := `
@@ -123,12 +145,12 @@ func (t *tokensRepository) RevokeAndReplace(ctx context.Context, oldToken string
 			revoked_at = :revoked_at,
 			replaced_by_token = :replaced_by_token
 		WHERE
-			token = :token
+			id = :id
 			AND revoked_at IS NULL
 	`
 
 	params := map[string]any{
-		"token":             oldToken,
+		"id":                oldTokenId,
 		"replaced_by_token": newTokenId,
 		"revoked_at":        time.Now().UTC(),
 	}
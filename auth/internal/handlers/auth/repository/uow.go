@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"auth/internal/lib/digest"
+	"auth/internal/lib/sealing"
 	"context"
 	"database/sql"
 
@@ -10,6 +12,9 @@ import (
 type UnitOfWork interface {
 	Users() UsersRepository
 	Tokens() TokensRepository
+	Audit() AuditRepository
+	Denylist() DenylistRepository
+	Outbox() OutboxRepository
 	Do(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
@@ -22,21 +27,31 @@ type sqlTxKey struct{}
 
 type unitOfWork struct {
 	db               *sqlx.DB
+	hasher           *digest.Hasher
+	sealer           *sealing.Sealer
 	usersRepository  UsersRepository
 	tokensRepository TokensRepository
+	auditRepository  AuditRepository
+	denylist         DenylistRepository
+	outbox           OutboxRepository
 }
 
-func NewUnitOfWork(db *sqlx.DB) UnitOfWork {
+func NewUnitOfWork(db *sqlx.DB, hasher *digest.Hasher, sealer *sealing.Sealer) UnitOfWork {
 	return &unitOfWork{
 		db:               db,
-		usersRepository:  NewUsersRepository(db),
-		tokensRepository: NewTokensRepository(db),
+		hasher:           hasher,
+		sealer:           sealer,
+		usersRepository:  NewUsersRepository(db, hasher),
+		tokensRepository: NewTokensRepository(db, hasher),
+		auditRepository:  NewAuditRepository(db),
+		denylist:         NewDenylistRepository(db),
+		outbox:           NewOutboxRepository(db, sealer),
 	}
 }
 
 func (u *unitOfWork) Users() UsersRepository {
 	if u.usersRepository == nil {
-		u.usersRepository = NewUsersRepository(u.db)
+		u.usersRepository = NewUsersRepository(u.db, u.hasher)
 	}
 
 	return u.usersRepository
@@ -44,12 +59,36 @@ func (u *unitOfWork) Users() UsersRepository {
 
 func (u *unitOfWork) Tokens() TokensRepository {
 	if u.tokensRepository == nil {
-		u.tokensRepository = NewTokensRepository(u.db)
+		u.tokensRepository = NewTokensRepository(u.db, u.hasher)
 	}
 
 	return u.tokensRepository
 }
 
+func (u *unitOfWork) Audit() AuditRepository {
+	if u.auditRepository == nil {
+		u.auditRepository = NewAuditRepository(u.db)
+	}
+
+	return u.auditRepository
+}
+
+func (u *unitOfWork) Denylist() DenylistRepository {
+	if u.denylist == nil {
+		u.denylist = NewDenylistRepository(u.db)
+	}
+
+	return u.denylist
+}
+
+func (u *unitOfWork) Outbox() OutboxRepository {
+	if u.outbox == nil {
+		u.outbox = NewOutboxRepository(u.db, u.sealer)
+	}
+
+	return u.outbox
+}
+
 func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
 	tx, err := u.db.BeginTxx(context.Background(), nil)
 	if err != nil {
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"auth/internal/lib/digest"
 	"auth/internal/storage"
 	"context"
 	"database/sql"
@@ -15,17 +16,25 @@ type UsersRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*storage.User, error)
 	GetUserById(ctx context.Context, id string) (*storage.User, error)
 	Update(ctx context.Context, userId string, code string, codeRequestedAt time.Time, isConfirmed bool) error
+	GetRoleLevel(ctx context.Context, userId string) (int, error)
+	// Ban forbids the user to sign in until bannedBefore, a longer existing ban is kept
+	Ban(ctx context.Context, userId string, bannedBefore time.Time) error
 }
 
+// usersRepository stores only keyed hashes of confirmation codes,
+// storage.User.Code returned from the repository contains the hash
 type usersRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	hasher *digest.Hasher
 }
 
-func NewUsersRepository(db *sqlx.DB) UsersRepository {
-	return &usersRepository{db: db}
+func NewUsersRepository(db *sqlx.DB, hasher *digest.Hasher) UsersRepository {
+	return &usersRepository{db: db, hasher: hasher}
 }
 
 func (r *usersRepository) CreateUser(ctx context.Context, user *storage.User) error {
+	executor := getExecutor(ctx, r.db)
+
 	query := `
 		INSERT INTO authorization_service.users (
 			id,
@@ -46,7 +55,10 @@ func (r *usersRepository) CreateUser(ctx context.Context, user *storage.User) er
 		)
 	`
 
-	_, err := r.db.NamedExecContext(ctx, query, user)
+	hashed := *user
+	hashed.Code = r.hasher.Hash(user.Code)
+
+	_, err := executor.NamedExecContext(ctx, query, &hashed)
 	return err
 }
 
@@ -57,7 +69,7 @@ func (r *usersRepository) GetUserByEmail(ctx context.Context, email string) (*st
 		       email, 
 		       password_hash, 
 		       is_confirmed,
-		       code, 
+		       COALESCE(code, '') AS code, 
 		       COALESCE(code_requested_at, make_timestamptz(1,1,1,0,0,0)) AS code_requested_at
 		FROM authorization_service.users
 		WHERE LOWER(email) = LOWER($1)
@@ -82,8 +94,9 @@ func (r *usersRepository) GetUserById(ctx context.Context, id string) (*storage.
 		       email, 
 		       password_hash, 
 		       is_confirmed,
-		       code, 
-		       COALESCE(code_requested_at, make_timestamptz(1,1,1,0,0,0)) AS code_requested_at
+		       COALESCE(code, '') AS code, 
+		       COALESCE(code_requested_at, make_timestamptz(1,1,1,0,0,0)) AS code_requested_at,
+		       COALESCE(banned_before, make_timestamptz(1,1,1,0,0,0)) AS banned_before
 		FROM authorization_service.users
 		WHERE id = $1
 	`
@@ -110,6 +123,39 @@ func (r *usersRepository) Update(ctx context.Context, userId string, code string
 		updateTime = nil
 	}
 
-	_, err := executor.ExecContext(ctx, query, code, updateTime, isConfirmed, userId)
+	_, err := executor.ExecContext(ctx, query, r.hasher.Hash(code), updateTime, isConfirmed, userId)
+	return err
+}
+
+func (r *usersRepository) GetRoleLevel(ctx context.Context, userId string) (int, error) {
+	query := `
+		SELECT COALESCE(r.level, 0)
+		FROM authorization_service.users u
+		LEFT JOIN authorization_service.roles r ON r.id = u.role_id
+		WHERE u.id = $1
+	`
+
+	var level int
+	err := r.db.GetContext(ctx, &level, query, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return level, nil
+}
+
+func (r *usersRepository) Ban(ctx context.Context, userId string, bannedBefore time.Time) error {
+	executor := getExecutor(ctx, r.db)
+
+	query := `
+		UPDATE authorization_service.users
+		SET banned_before = GREATEST(COALESCE(banned_before, $1), $1)
+		WHERE id = $2
+	`
+
+	_, err := executor.ExecContext(ctx, query, bannedBefore, userId)
 	return err
 }
@@ -0,0 +1,36 @@
+package security
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// ClientAuthMiddleware authenticates internal services with HTTP Basic credentials
+// as required for RFC 7662 introspection and RFC 7009 revocation endpoints.
+func ClientAuthMiddleware(clients map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, secret, ok := r.BasicAuth()
+			if !ok || !validClient(clients, id, secret) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="auth"`)
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "invalid_client"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validClient(clients map[string]string, id, secret string) bool {
+	expected, ok := clients[id]
+	if !ok {
+		// keep timing the same for unknown clients
+		expected = secret + "-"
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1 && ok
+}
@@ -0,0 +1,26 @@
+package security
+
+const (
+	// RoleLevelAdmin is the minimal authorization_service.roles.level treated as administrator
+	RoleLevelAdmin = 100
+	// RoleLevelModerator is the minimal authorization_service.roles.level treated as moderator
+	RoleLevelModerator = 50
+)
+
+const (
+	PermissionUsersRead        = "users.read"
+	PermissionUsersBan         = "users.ban"
+	PermissionUsersImpersonate = "users.impersonate"
+	PermissionContentModerate  = "content.moderate"
+	// PermissionContentTypesManage allows creating, changing and deleting content types of the content service
+	PermissionContentTypesManage = "content.types.manage"
+)
+
+// Permissions maps a permission name to the minimal role level granting it
+var Permissions = map[string]int{
+	PermissionUsersRead:          0,
+	PermissionUsersBan:           RoleLevelAdmin,
+	PermissionUsersImpersonate:   RoleLevelAdmin,
+	PermissionContentModerate:    RoleLevelModerator,
+	PermissionContentTypesManage: RoleLevelAdmin,
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type TokenPair struct {
@@ -15,24 +16,38 @@ type TokenPair struct {
 	RefreshToken string
 }
 
+// AccessClaims are the claims of a verified access token
+type AccessClaims struct {
+	UserId    string
+	ActorId   string
+	Jti       string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
 type JWTService struct {
-	accessSecret []byte
-	AccessTTL    time.Duration
-	RefreshTTL   time.Duration
+	accessSecret     []byte
+	AccessTTL        time.Duration
+	RefreshTTL       time.Duration
+	ImpersonationTTL time.Duration
 }
 
 func NewJWTService(settings Settings) *JWTService {
 	return &JWTService{
-		accessSecret: []byte(settings.AccessSecret),
-		AccessTTL:    time.Duration(settings.AccessTTL) * time.Minute,
-		RefreshTTL:   time.Duration(settings.RefreshTTL) * 24 * time.Hour,
+		accessSecret:     []byte(settings.AccessSecret),
+		AccessTTL:        time.Duration(settings.AccessTTL) * time.Minute,
+		RefreshTTL:       time.Duration(settings.RefreshTTL) * 24 * time.Hour,
+		ImpersonationTTL: time.Duration(settings.ImpersonationTTL) * time.Minute,
 	}
 }
 
 func (s *JWTService) GenerateTokens(userId string) (*TokenPair, error) {
+	now := time.Now()
 	accessClaims := jwt.MapClaims{
 		"user_id": userId,
-		"exp":     time.Now().Add(s.AccessTTL).Unix(),
+		"jti":     uuid.NewString(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(s.AccessTTL).Unix(),
 		"type":    "access",
 	}
 
@@ -49,7 +64,101 @@ func (s *JWTService) GenerateTokens(userId string) (*TokenPair, error) {
 	}, nil
 }
 
+// GenerateImpersonationToken issues a short-lived access token for userId on behalf of actorId.
+// The actor is stored in the "act" claim (RFC 8693), no refresh token is issued.
+func (s *JWTService) GenerateImpersonationToken(userId, actorId string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ImpersonationTTL)
+
+	claims := jwt.MapClaims{
+		"user_id": userId,
+		"jti":     uuid.NewString(),
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+		"type":    "access",
+		"act": map[string]any{
+			"sub": actorId,
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.accessSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
 func (s *JWTService) GetValue(tokenStr, key string) (string, error) {
+	claims, err := s.parseAccessToken(tokenStr)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := claims[key].(string)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("invalid %s", key))
+	}
+
+	return value, nil
+}
+
+// GetActor returns the id of the user acting on behalf of the token subject
+// or an empty string when the token is not an impersonation token.
+func (s *JWTService) GetActor(tokenStr string) (string, error) {
+	claims, err := s.parseAccessToken(tokenStr)
+	if err != nil {
+		return "", err
+	}
+
+	act, ok := claims["act"].(map[string]any)
+	if !ok {
+		return "", nil
+	}
+
+	actorId, ok := act["sub"].(string)
+	if !ok {
+		return "", errors.New("invalid act")
+	}
+
+	return actorId, nil
+}
+
+// ParseAccessToken verifies the signature and expiration of an access token and returns its claims.
+// Tokens issued before jti was introduced have an empty Jti.
+func (s *JWTService) ParseAccessToken(tokenStr string) (*AccessClaims, error) {
+	claims, err := s.parseAccessToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, errors.New("invalid user_id")
+	}
+
+	result := &AccessClaims{
+		UserId: userId,
+	}
+
+	result.Jti, _ = claims["jti"].(string)
+
+	if exp, expErr := claims.GetExpirationTime(); expErr == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+
+	if iat, iatErr := claims.GetIssuedAt(); iatErr == nil && iat != nil {
+		result.IssuedAt = iat.Time
+	}
+
+	if act, isMap := claims["act"].(map[string]any); isMap {
+		result.ActorId, _ = act["sub"].(string)
+	}
+
+	return result, nil
+}
+
+func (s *JWTService) parseAccessToken(tokenStr string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -58,20 +167,15 @@ func (s *JWTService) GetValue(tokenStr, key string) (string, error) {
 	})
 
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || claims["type"] != "access" {
-		return "", errors.New("invalid claims")
+		return nil, errors.New("invalid claims")
 	}
 
-	value, ok := claims[key].(string)
-	if !ok {
-		return "", errors.New(fmt.Sprintf("invalid %s", key))
-	}
-
-	return value, nil
+	return claims, nil
 }
 
 func generateSecureToken(length int) string {
@@ -3,14 +3,24 @@ package security
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Settings struct {
-	AccessSecret string
-	AccessTTL    int
-	RefreshTTL   int
+	AccessSecret     string
+	AccessTTL        int
+	RefreshTTL       int
+	ImpersonationTTL int
+	// TokenPepper is the HMAC key for refresh tokens and confirmation codes stored in the database
+	TokenPepper string
+	// OutboxKey encrypts outbox payloads, they carry confirmation codes until the messages are published
+	OutboxKey string
+	// InternalClients maps client id to secret for services allowed to call introspection and revocation
+	InternalClients map[string]string
 }
 
+const defaultImpersonationTTL = 15
+
 func MustLoadSettings() Settings {
 	attl, err := strconv.Atoi(os.Getenv("SECURITY__ACCESS_LIFETIME_MINUTES"))
 	if err != nil {
@@ -22,9 +32,47 @@ func MustLoadSettings() Settings {
 		panic(err)
 	}
 
+	ittl := defaultImpersonationTTL
+	if value := os.Getenv("SECURITY__IMPERSONATION_LIFETIME_MINUTES"); value != "" {
+		ittl, err = strconv.Atoi(value)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	pepper := os.Getenv("SECURITY__TOKEN_PEPPER")
+	if pepper == "" {
+		panic("SECURITY__TOKEN_PEPPER is required")
+	}
+
+	outboxKey := os.Getenv("SECURITY__OUTBOX_KEY")
+	if outboxKey == "" {
+		panic("SECURITY__OUTBOX_KEY is required")
+	}
+
 	return Settings{
-		AccessSecret: os.Getenv("SECURITY__ACCESS_SECRET"),
-		AccessTTL:    attl,
-		RefreshTTL:   rttl,
+		AccessSecret:     os.Getenv("SECURITY__ACCESS_SECRET"),
+		AccessTTL:        attl,
+		RefreshTTL:       rttl,
+		ImpersonationTTL: ittl,
+		TokenPepper:      pepper,
+		OutboxKey:        outboxKey,
+		InternalClients:  parseClients(os.Getenv("SECURITY__INTERNAL_CLIENTS")),
 	}
 }
+
+// parseClients parses "id1:secret1,id2:secret2"
+func parseClients(value string) map[string]string {
+	clients := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+
+		clients[id] = secret
+	}
+
+	return clients
+}
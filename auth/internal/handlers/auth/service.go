@@ -3,6 +3,7 @@ package auth
 import (
 	"auth/internal/handlers/auth/repository"
 	"auth/internal/handlers/auth/security"
+	"auth/internal/lib/digest"
 	"auth/internal/lib/mapper"
 	"auth/internal/lib/masking"
 	"auth/internal/lib/password"
@@ -14,7 +15,6 @@ import (
 	"time"
 
 	"github.com/flores666/profileshare-lib/api"
-	"github.com/flores666/profileshare-lib/eventBus"
 	"github.com/flores666/profileshare-lib/utils"
 	"github.com/google/uuid"
 )
@@ -25,6 +25,10 @@ type Service interface {
 	Login(ctx context.Context, request LoginUserRequest) api.AppResponse
 	Logout(ctx context.Context, request LogoutRequest) api.AppResponse
 	RefreshTokens(ctx context.Context, request RefreshTokenRequest) api.AppResponse
+	Impersonate(ctx context.Context, request ImpersonateRequest) api.AppResponse
+	StopImpersonation(ctx context.Context, request StopImpersonationRequest) api.AppResponse
+	Introspect(ctx context.Context, request IntrospectRequest) api.AppResponse
+	Revoke(ctx context.Context, request RevokeRequest) api.AppResponse
 }
 
 const (
@@ -33,6 +37,11 @@ const (
 	ErrCodeRequestTimeout = "Повторите попытку через 5 минут"
 	ErrInternal           = "Внутрення ошибка"
 	ErrInvalidCredentials = "Неверные логин или пароль"
+	ErrUnauthorized       = "Требуется авторизация"
+	ErrForbidden          = "Недостаточно прав"
+	ErrUserNotFound       = "Пользователь не найден"
+	ErrValidation         = "Ошибка проверки данных"
+	ErrNotImpersonation   = "Токен не является токеном имперсонации"
 	CodeSent              = "Сообщение с новым кодом подтверждения отправлено на вашу почту"
 	CodeRequestTimeout    = time.Minute * 2
 	AccConfirmTimeout     = time.Minute * 10
@@ -42,27 +51,27 @@ const (
 type service struct {
 	unitOfWork repository.UnitOfWork
 	logger     *slog.Logger
-	producer   eventBus.Producer
 	jwtService *security.JWTService
+	hasher     *digest.Hasher
 }
 
 func NewService(
 	unitOfWork repository.UnitOfWork,
 	jwtService *security.JWTService,
+	hasher *digest.Hasher,
 	logger *slog.Logger,
-	producer eventBus.Producer,
 ) Service {
 	return &service{
 		logger:     logger,
-		producer:   producer,
 		jwtService: jwtService,
+		hasher:     hasher,
 		unitOfWork: unitOfWork,
 	}
 }
 
 func (s *service) Register(ctx context.Context, request RegisterUserRequest) api.AppResponse {
 	if err := validateRegister(request); err != nil {
-		return api.NewError("Ошибка проверки данных", err)
+		return api.NewError(ErrValidation, err)
 	}
 
 	existingUser, err := s.unitOfWork.Users().GetUserByEmail(ctx, request.Email)
@@ -80,7 +89,7 @@ func (s *service) Register(ctx context.Context, request RegisterUserRequest) api
 
 func (s *service) Login(ctx context.Context, request LoginUserRequest) api.AppResponse {
 	if err := validateLogin(request); err != nil {
-		return api.NewError("Ошибка проверки данных", err)
+		return api.NewError(ErrValidation, err)
 	}
 
 	user, err := s.unitOfWork.Users().GetUserByEmail(ctx, request.Email)
@@ -113,12 +122,14 @@ func (s *service) Logout(ctx context.Context, request LogoutRequest) api.AppResp
 		return api.NewError("refresh token обязателен", nil)
 	}
 
+	var accessClaims *security.AccessClaims
 	if request.AccessToken != "" {
-		userId, err := s.jwtService.GetValue(request.AccessToken, "user_id")
+		claims, err := s.jwtService.ParseAccessToken(request.AccessToken)
 		if err != nil {
 			s.logger.Warn("invalid access token on logout", slog.String("error", err.Error()))
 		} else {
-			ctx = context.WithValue(ctx, "user_id", userId)
+			accessClaims = claims
+			ctx = context.WithValue(ctx, "user_id", claims.UserId)
 		}
 	}
 
@@ -127,7 +138,7 @@ func (s *service) Logout(ctx context.Context, request LogoutRequest) api.AppResp
 		return api.NewError(ErrInternal, nil)
 	}
 
-	if rt == nil || !rt.RevokedAt.IsZero() || !rt.ExpiresAt.IsZero() {
+	if rt == nil || !rt.RevokedAt.IsZero() || rt.ExpiresAt.Before(time.Now().UTC()) {
 		return api.NewOk(Success, nil)
 	}
 
@@ -143,6 +154,12 @@ func (s *service) Logout(ctx context.Context, request LogoutRequest) api.AppResp
 		return api.NewError(ErrInternal, nil)
 	}
 
+	if accessClaims != nil {
+		if err = s.denyAccessToken(ctx, accessClaims); err != nil {
+			s.logger.Warn("failed to deny access token on logout", slog.String("error", err.Error()))
+		}
+	}
+
 	return api.NewOk(Success, nil)
 }
 
@@ -176,7 +193,7 @@ func (s *service) RefreshTokens(ctx context.Context, request RefreshTokenRequest
 			return err
 		}
 
-		err = s.unitOfWork.Tokens().RevokeAndReplace(ctx, rt.Token, replacedBy)
+		err = s.unitOfWork.Tokens().RevokeAndReplace(ctx, rt.Id, replacedBy)
 		if err != nil {
 			return err
 		}
@@ -208,7 +225,7 @@ func (s *service) Confirm(ctx context.Context, request ConfirmUserRequest) api.A
 		return api.NewError(ErrAlreadyRegistered, nil)
 	}
 
-	if user.Code != request.Code {
+	if !s.hasher.Equal(request.Code, user.Code) {
 		return api.NewError("Неверный код подтверждения", nil)
 	}
 
@@ -242,6 +259,268 @@ func (s *service) Confirm(ctx context.Context, request ConfirmUserRequest) api.A
 	return response
 }
 
+func (s *service) Impersonate(ctx context.Context, request ImpersonateRequest) api.AppResponse {
+	if err := validateImpersonate(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	actorId, err := s.jwtService.GetValue(request.AccessToken, "user_id")
+	if err != nil {
+		return api.NewError(ErrUnauthorized, nil)
+	}
+
+	// nested impersonation would hide the real actor
+	if currentActor, actErr := s.jwtService.GetActor(request.AccessToken); actErr != nil || currentActor != "" {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	if actorId == request.UserId {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	actorLevel, err := s.unitOfWork.Users().GetRoleLevel(ctx, actorId)
+	if err != nil {
+		s.logger.Error("failed to get actor role", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	if actorLevel < security.RoleLevelAdmin {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	target, err := s.unitOfWork.Users().GetUserById(ctx, request.UserId)
+	if err != nil {
+		s.logger.Error("failed to get user", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	if target == nil {
+		return api.NewError(ErrUserNotFound, nil)
+	}
+
+	targetLevel, err := s.unitOfWork.Users().GetRoleLevel(ctx, target.Id)
+	if err != nil {
+		s.logger.Error("failed to get user role", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	if targetLevel >= actorLevel {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	token, expiresAt, err := s.jwtService.GenerateImpersonationToken(target.Id, actorId)
+	if err != nil {
+		s.logger.Error("failed to issue impersonation token", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	err = s.unitOfWork.Audit().Write(ctx, &storage.AuditEntry{
+		Id:        uuid.NewString(),
+		ActorId:   actorId,
+		TargetId:  target.Id,
+		Action:    AuditImpersonationStart,
+		Details:   request.Reason,
+		Ip:        request.Ip,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	if err != nil {
+		s.logger.Error("failed to write audit log", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	s.logger.Info("impersonation started", slog.String("actor_id", actorId), slog.String("user_id", target.Id))
+
+	return api.NewOk(Success, &ImpersonationDto{
+		AccessToken: token,
+		UserId:      target.Id,
+		ActorId:     actorId,
+		ExpiresAt:   expiresAt.UTC(),
+	})
+}
+
+func (s *service) StopImpersonation(ctx context.Context, request StopImpersonationRequest) api.AppResponse {
+	claims, err := s.jwtService.ParseAccessToken(request.AccessToken)
+	if err != nil {
+		return api.NewError(ErrUnauthorized, nil)
+	}
+
+	if claims.ActorId == "" {
+		return api.NewError(ErrNotImpersonation, nil)
+	}
+
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if uowError := s.denyAccessToken(ctx, claims); uowError != nil {
+			return uowError
+		}
+
+		return s.unitOfWork.Audit().Write(ctx, &storage.AuditEntry{
+			Id:        uuid.NewString(),
+			ActorId:   claims.ActorId,
+			TargetId:  claims.UserId,
+			Action:    AuditImpersonationStop,
+			Ip:        request.Ip,
+			CreatedAt: time.Now().UTC(),
+		})
+	})
+
+	if err != nil {
+		s.logger.Error("failed to stop impersonation", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	s.logger.Info("impersonation stopped", slog.String("actor_id", claims.ActorId), slog.String("user_id", claims.UserId))
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) Introspect(ctx context.Context, request IntrospectRequest) api.AppResponse {
+	inactive := api.NewOk(Success, &IntrospectionDto{Active: false})
+
+	if request.Token == "" {
+		return inactive
+	}
+
+	// the hint only defines the lookup order, other token types are checked as well
+	lookups := []func(context.Context, string) (*IntrospectionDto, error){s.introspectAccess, s.introspectRefresh}
+	if request.TokenTypeHint == TokenTypeRefresh {
+		lookups = []func(context.Context, string) (*IntrospectionDto, error){s.introspectRefresh, s.introspectAccess}
+	}
+
+	for _, lookup := range lookups {
+		result, err := lookup(ctx, request.Token)
+		if err != nil {
+			s.logger.Error("failed to introspect token", slog.String("error", err.Error()))
+			return api.NewError(ErrInternal, nil)
+		}
+
+		if result != nil {
+			return api.NewOk(Success, result)
+		}
+	}
+
+	return inactive
+}
+
+func (s *service) Revoke(ctx context.Context, request RevokeRequest) api.AppResponse {
+	if request.Token == "" {
+		return api.NewOk(Success, nil)
+	}
+
+	if request.TokenTypeHint != TokenTypeRefresh {
+		if claims, err := s.jwtService.ParseAccessToken(request.Token); err == nil {
+			if err = s.denyAccessToken(ctx, claims); err != nil {
+				s.logger.Error("failed to revoke access token", slog.String("error", err.Error()))
+				return api.NewError(ErrInternal, nil)
+			}
+
+			return api.NewOk(Success, nil)
+		}
+	}
+
+	rt, err := s.unitOfWork.Tokens().GetByToken(ctx, request.Token)
+	if err != nil {
+		s.logger.Error("failed to get refresh token", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	// invalid tokens do not cause an error response (RFC 7009, section 2.2)
+	if rt == nil || !rt.RevokedAt.IsZero() {
+		return api.NewOk(Success, nil)
+	}
+
+	if err = s.unitOfWork.Tokens().Revoke(ctx, rt.Id); err != nil {
+		s.logger.Error("failed to revoke refresh token", slog.String("error", err.Error()))
+		return api.NewError(ErrInternal, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) introspectAccess(ctx context.Context, token string) (*IntrospectionDto, error) {
+	claims, err := s.jwtService.ParseAccessToken(token)
+	if err != nil {
+		return nil, nil
+	}
+
+	if claims.Jti != "" {
+		denied, err := s.unitOfWork.Denylist().Contains(ctx, claims.Jti)
+		if err != nil {
+			return nil, err
+		}
+
+		if denied {
+			return &IntrospectionDto{Active: false}, nil
+		}
+	}
+
+	active, err := s.isUserActive(ctx, claims.UserId)
+	if err != nil || !active {
+		return &IntrospectionDto{Active: false}, err
+	}
+
+	result := &IntrospectionDto{
+		Active:    true,
+		Sub:       claims.UserId,
+		TokenType: TokenTypeAccess,
+		Jti:       claims.Jti,
+		Exp:       claims.ExpiresAt.Unix(),
+	}
+
+	if !claims.IssuedAt.IsZero() {
+		result.Iat = claims.IssuedAt.Unix()
+	}
+
+	if claims.ActorId != "" {
+		result.Act = &ActorDto{Sub: claims.ActorId}
+	}
+
+	return result, nil
+}
+
+func (s *service) introspectRefresh(ctx context.Context, token string) (*IntrospectionDto, error) {
+	rt, err := s.unitOfWork.Tokens().GetByToken(ctx, token)
+	if err != nil || rt == nil {
+		return nil, err
+	}
+
+	if !rt.RevokedAt.IsZero() || rt.ExpiresAt.Before(time.Now().UTC()) {
+		return &IntrospectionDto{Active: false}, nil
+	}
+
+	active, err := s.isUserActive(ctx, rt.UserId)
+	if err != nil || !active {
+		return &IntrospectionDto{Active: false}, err
+	}
+
+	return &IntrospectionDto{
+		Active:    true,
+		Sub:       rt.UserId,
+		TokenType: TokenTypeRefresh,
+		Exp:       rt.ExpiresAt.Unix(),
+		Iat:       rt.CreatedAt.Unix(),
+	}, nil
+}
+
+// isUserActive reports whether the user exists and is not banned
+func (s *service) isUserActive(ctx context.Context, userId string) (bool, error) {
+	user, err := s.unitOfWork.Users().GetUserById(ctx, userId)
+	if err != nil || user == nil {
+		return false, err
+	}
+
+	return user.BannedBefore.Before(time.Now().UTC()), nil
+}
+
+// denyAccessToken puts the access token into the denylist until it expires
+func (s *service) denyAccessToken(ctx context.Context, claims *security.AccessClaims) error {
+	if claims.Jti == "" {
+		return nil
+	}
+
+	return s.unitOfWork.Denylist().Add(ctx, claims.Jti, claims.UserId, claims.ExpiresAt.UTC())
+}
+
 func (s *service) issueTokens(ctx context.Context, userId string, newTokenId *string) (*security.TokenPair, error) {
 	tokens, err := s.jwtService.GenerateTokens(userId)
 	if err != nil {
@@ -263,7 +542,9 @@ func (s *service) issueTokens(ctx context.Context, userId string, newTokenId *st
 		return nil, err
 	}
 
-	*newTokenId = id
+	if newTokenId != nil {
+		*newTokenId = id
+	}
 
 	return tokens, nil
 }
@@ -280,13 +561,19 @@ func (s *service) handleExistingUser(ctx context.Context, user *storage.User, re
 	user.Code = masking.RandStringBytesMask(10)
 	user.CodeRequestedAt = time.Now().UTC()
 
-	if err := s.unitOfWork.Users().Update(ctx, user.Id, user.Code, user.CodeRequestedAt, false); err != nil {
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.unitOfWork.Users().Update(ctx, user.Id, user.Code, user.CodeRequestedAt, false); err != nil {
+			return err
+		}
+
+		return s.unitOfWork.Outbox().Add(ctx, UserCreatedTopic, user.Id, s.registeredMessage(user, redirectUrl))
+	})
+
+	if err != nil {
 		s.logger.Error("could not update user code", slog.String("error", err.Error()))
 		return api.NewError(ErrFailedSave, nil)
 	}
 
-	go s.publishUser(user, redirectUrl)
-
 	return api.NewOk(CodeSent, mapper.MapUserToDto(user))
 }
 
@@ -304,32 +591,35 @@ func (s *service) createUser(ctx context.Context, request RegisterUserRequest) a
 		CreatedAt:       now,
 	}
 
-	if err := s.unitOfWork.Users().CreateUser(ctx, model); err != nil {
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.unitOfWork.Users().CreateUser(ctx, model); err != nil {
+			return err
+		}
+
+		return s.unitOfWork.Outbox().Add(ctx, UserCreatedTopic, model.Id, s.registeredMessage(model, request.ReturnUrl))
+	})
+
+	if err != nil {
 		s.logger.Error("could not create user", slog.String("error", err.Error()))
 		return api.NewError(ErrFailedSave, nil)
 	}
 
-	go s.publishUser(model, request.ReturnUrl)
-
 	return api.NewOk(CodeSent, mapper.MapUserToDto(model))
 }
 
-func (s *service) publishUser(user *storage.User, redirectUrl string) {
+// registeredMessage asks the mailer to send the confirmation code, it is saved to the outbox with the code
+func (s *service) registeredMessage(user *storage.User, redirectUrl string) *UserRegisteredMessage {
 	r, err := addQueryParam(redirectUrl, "code", user.Code)
 	if err != nil {
 		s.logger.Error("could not add query param", slog.String("error", err.Error()))
 		r = redirectUrl
 	}
 
-	event := &UserRegisteredMessage{
+	return &UserRegisteredMessage{
 		UserId:         user.Id,
 		Email:          user.Email,
 		ReturnUrl:      r,
-		IdempotencyKey: user.Id + ";" + user.Code,
-	}
-
-	if err := s.producer.Produce(context.Background(), UserCreatedTopic, event); err != nil {
-		s.logger.Error("failed to produce event", slog.String("error", err.Error()))
+		IdempotencyKey: user.Id + ";" + s.hasher.Hash(user.Code),
 	}
 }
 
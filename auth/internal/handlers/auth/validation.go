@@ -47,3 +47,21 @@ func validateLogin(request LoginUserRequest) *api.ValidationErrors {
 
 	return errs
 }
+
+func validateImpersonate(request ImpersonateRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if request.UserId == "" {
+		errs.Add("userId", "is required")
+	}
+
+	if len([]rune(request.Reason)) < 5 {
+		errs.Add("reason", "must contain at least 5 characters")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
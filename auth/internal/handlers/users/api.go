@@ -3,6 +3,7 @@ package users
 import (
 	"auth/internal/lib/handlers"
 	"net/http"
+	"strconv"
 
 	"github.com/flores666/profileshare-lib/api"
 
@@ -36,7 +37,12 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
 
 	result := h.service.Update(r.Context(), request)
 	if !result.Ok() {
-		handlers.Respond(w, r, http.StatusInternalServerError, result)
+		status := http.StatusInternalServerError
+		if result.Message == ErrImpersonate {
+			status = http.StatusForbidden
+		}
+
+		handlers.Respond(w, r, status, result)
 		return
 	}
 
@@ -61,9 +67,27 @@ func (h *Handler) getById(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) getByFilter(w http.ResponseWriter, r *http.Request) {
 	filter := getFilter(r)
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			errs := &api.ValidationErrors{}
+			errs.Add("limit", "должно быть числом")
+			handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, errs))
+			return
+		}
+
+		filter.Limit = value
+	}
+
 	response := h.service.GetByFilter(r.Context(), filter)
 	if !response.Ok() {
-		handlers.Respond(w, r, http.StatusInternalServerError, response)
+		status := http.StatusInternalServerError
+		if response.Message == ErrValidation {
+			status = http.StatusBadRequest
+		}
+
+		handlers.Respond(w, r, status, response)
 		return
 	}
 
@@ -73,5 +97,6 @@ func (h *Handler) getByFilter(w http.ResponseWriter, r *http.Request) {
 func getFilter(r *http.Request) QueryFilter {
 	return QueryFilter{
 		Search: r.URL.Query().Get("search"),
+		Cursor: r.URL.Query().Get("cursor"),
 	}
 }
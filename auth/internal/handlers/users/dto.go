@@ -4,6 +4,9 @@ import "time"
 
 type QueryFilter struct {
 	Search string `json:"search"`
+	// Cursor is an opaque position returned as nextCursor or prevCursor of the previous page
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
 }
 
 type UpdateUserRequest struct {
@@ -13,3 +16,8 @@ type UpdateUserRequest struct {
 	RoleId       *string    `json:"roleId"`
 	BannedBefore *time.Time `json:"bannedBefore"`
 }
+
+type PermissionDto struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
@@ -1,6 +1,7 @@
 package users
 
 import (
+	"auth/internal/lib/pagination"
 	"auth/internal/storage"
 	"context"
 	"database/sql"
@@ -12,8 +13,10 @@ import (
 
 type Repository interface {
 	GetById(ctx context.Context, id string) (*storage.User, error)
-	Query(ctx context.Context, filter QueryFilter) ([]*storage.User, error)
+	Query(ctx context.Context, filter QueryFilter, page pagination.Page) ([]*storage.User, error)
 	Update(ctx context.Context, model storage.UpdateUser) error
+	GetByIds(ctx context.Context, ids []string) ([]*storage.User, error)
+	GetRoleLevel(ctx context.Context, id string) (int, error)
 }
 
 type repository struct {
@@ -51,7 +54,7 @@ func (r *repository) GetById(ctx context.Context, id string) (*storage.User, err
 	return &user, nil
 }
 
-func (r *repository) Query(ctx context.Context, filter QueryFilter) ([]*storage.User, error) {
+func (r *repository) Query(ctx context.Context, filter QueryFilter, page pagination.Page) ([]*storage.User, error) {
 	query := `
 		SELECT
 			id,
@@ -66,15 +69,23 @@ func (r *repository) Query(ctx context.Context, filter QueryFilter) ([]*storage.
 
 	params := map[string]any{}
 
+	var conditions []string
+
 	if filter.Search != "" {
-		query += `
-			WHERE nickname ILIKE :search
-			   OR email ILIKE :search
-		`
+		conditions = append(conditions, "(nickname ILIKE :search OR email ILIKE :search)")
 		params["search"] = "%" + filter.Search + "%"
 	}
 
-	query += " ORDER BY created_at DESC LIMIT 20"
+	if where := page.Where("created_at", "id"); where != "" {
+		conditions = append(conditions, where)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += page.OrderBy("created_at", "id")
+	page.AddParams(params)
 
 	query, args, err := sqlx.Named(query, params)
 	if err != nil {
@@ -120,3 +131,55 @@ func (r *repository) Update(ctx context.Context, model storage.UpdateUser) error
 	_, err := r.db.NamedExecContext(ctx, query, params)
 	return err
 }
+
+func (r *repository) GetByIds(ctx context.Context, ids []string) ([]*storage.User, error) {
+	if len(ids) == 0 {
+		return make([]*storage.User, 0), nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT
+			id,
+			nickname,
+			email,
+			COALESCE(role_id, '00000000-0000-0000-0000-000000000000') AS role_id,
+			is_confirmed,
+			COALESCE(banned_before, make_timestamptz(1,1,1,0,0,0)) AS banned_before,
+			created_at
+		FROM authorization_service.users
+		WHERE id IN (?)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	query = sqlx.Rebind(sqlx.DOLLAR, query)
+
+	var users []*storage.User
+	err = r.db.SelectContext(ctx, &users, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *repository) GetRoleLevel(ctx context.Context, id string) (int, error) {
+	query := `
+		SELECT COALESCE(r.level, 0)
+		FROM authorization_service.users u
+		LEFT JOIN authorization_service.roles r ON r.id = u.role_id
+		WHERE u.id = $1
+	`
+
+	var level int
+	err := r.db.GetContext(ctx, &level, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return level, nil
+}
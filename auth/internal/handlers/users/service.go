@@ -1,10 +1,13 @@
 package users
 
 import (
+	"auth/internal/handlers/auth/security"
 	"auth/internal/lib/mapper"
+	"auth/internal/lib/pagination"
 	"auth/internal/storage"
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/flores666/profileshare-lib/api"
 )
@@ -13,28 +16,34 @@ type Service interface {
 	GetById(ctx context.Context, id string) api.AppResponse
 	GetByFilter(ctx context.Context, filter QueryFilter) api.AppResponse
 	Update(ctx context.Context, request UpdateUserRequest) api.AppResponse
+	GetByIds(ctx context.Context, ids []string) api.AppResponse
+	CheckPermission(ctx context.Context, userId string, permission string) api.AppResponse
 }
 
 const (
 	ErrFailedQuery = "Не удалось выполнить запрос"
 	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrImpersonate = "Операция недоступна в режиме имперсонации"
+	ErrValidation  = "Ошибка проверки данных"
 )
 
 type service struct {
 	repository Repository
+	paginator  *pagination.Paginator
 	logger     *slog.Logger
 }
 
-func NewService(repository Repository, logger *slog.Logger) Service {
+func NewService(repository Repository, paginator *pagination.Paginator, logger *slog.Logger) Service {
 	return &service{
 		repository: repository,
+		paginator:  paginator,
 		logger:     logger,
 	}
 }
 
 func (s *service) GetById(ctx context.Context, id string) api.AppResponse {
 	if err := validateId(id); err != nil {
-		return api.NewError("Ошибка проверки данных", err)
+		return api.NewError(ErrValidation, err)
 	}
 
 	model, err := s.repository.GetById(ctx, id)
@@ -48,22 +57,39 @@ func (s *service) GetById(ctx context.Context, id string) api.AppResponse {
 
 func (s *service) GetByFilter(ctx context.Context, filter QueryFilter) api.AppResponse {
 	if err := validateFilter(filter); err != nil {
-		return api.NewError("Ошибка проверки данных", err)
+		return api.NewError(ErrValidation, err)
 	}
 
-	list, err := s.repository.Query(ctx, filter)
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	list, err := s.repository.Query(ctx, filter, page)
 
 	if err != nil {
-		s.logger.Error("could not get users by filter, error = ", err, "filter = ", filter)
+		s.logger.Error("could not get users by filter", slog.String("error", err.Error()), slog.Any("filter", filter))
 		return api.NewError(ErrFailedQuery, nil)
 	}
 
-	return api.NewOk("Успешно", mapper.MapUserSliceToDto(list))
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, userKey)
+
+	return api.NewOk("Успешно", pagination.PageDto[*mapper.UserDto]{
+		Items:      mapper.MapUserSliceToDto(list),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
 }
 
 func (s *service) Update(ctx context.Context, request UpdateUserRequest) api.AppResponse {
 	if err := validateUpdate(request); err != nil {
-		return api.NewError("Ошибка проверки данных", err)
+		return api.NewError(ErrValidation, err)
+	}
+
+	if _, impersonating := security.ActorFromContext(ctx); impersonating && request.Email != nil {
+		return api.NewError(ErrImpersonate, nil)
 	}
 
 	model := storage.UpdateUser{
@@ -88,3 +114,58 @@ func (s *service) Update(ctx context.Context, request UpdateUserRequest) api.App
 	response.Data = mapper.MapUserToDto(user)
 	return response
 }
+
+func (s *service) GetByIds(ctx context.Context, ids []string) api.AppResponse {
+	if err := validateIds(ids); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	list, err := s.repository.GetByIds(ctx, ids)
+	if err != nil {
+		s.logger.Error("could not get users by ids", slog.String("error", err.Error()))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk("Успешно", mapper.MapUserSliceToDto(list))
+}
+
+func (s *service) CheckPermission(ctx context.Context, userId string, permission string) api.AppResponse {
+	if err := validateId(userId); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	requiredLevel, ok := security.Permissions[permission]
+	if !ok {
+		return api.NewOk("Успешно", PermissionDto{Allowed: false, Reason: "unknown permission"})
+	}
+
+	user, err := s.repository.GetById(ctx, userId)
+	if err != nil {
+		s.logger.Error("could not get user", slog.String("error", err.Error()), slog.String("id", userId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if user == nil {
+		return api.NewOk("Успешно", PermissionDto{Allowed: false, Reason: "user not found"})
+	}
+
+	if user.BannedBefore.After(time.Now().UTC()) {
+		return api.NewOk("Успешно", PermissionDto{Allowed: false, Reason: "user is banned"})
+	}
+
+	level, err := s.repository.GetRoleLevel(ctx, userId)
+	if err != nil {
+		s.logger.Error("could not get user role", slog.String("error", err.Error()), slog.String("id", userId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if level < requiredLevel {
+		return api.NewOk("Успешно", PermissionDto{Allowed: false, Reason: "insufficient role"})
+	}
+
+	return api.NewOk("Успешно", PermissionDto{Allowed: true})
+}
+
+func userKey(user *storage.User) pagination.Key {
+	return pagination.Key{CreatedAt: user.CreatedAt, Id: user.Id}
+}
@@ -26,6 +26,10 @@ func validateFilter(filter QueryFilter) *api.ValidationErrors {
 		errs.Add("search", "must contain at least 2 characters")
 	}
 
+	if filter.Limit < 0 {
+		errs.Add("limit", "must be positive")
+	}
+
 	if errs.Ok() {
 		return nil
 	}
@@ -58,3 +62,23 @@ func validateUpdate(request UpdateUserRequest) *api.ValidationErrors {
 
 	return errs
 }
+
+const maxBatchSize = 100
+
+func validateIds(ids []string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if len(ids) == 0 {
+		errs.Add("ids", "is required")
+	}
+
+	if len(ids) > maxBatchSize {
+		errs.Add("ids", "must contain at most 100 items")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
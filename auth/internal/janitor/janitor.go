@@ -0,0 +1,127 @@
+package janitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Janitor periodically purges expired refresh tokens, denied access token ids and unconfirmed accounts.
+// All replicas run the loop, but a run is performed only by the one holding the Postgres advisory lock.
+type Janitor struct {
+	db         *sqlx.DB
+	repository *repository
+	settings   Settings
+	logger     *slog.Logger
+}
+
+func NewJanitor(db *sqlx.DB, settings Settings, logger *slog.Logger) *Janitor {
+	return &Janitor{
+		db:         db,
+		repository: &repository{},
+		settings:   settings,
+		logger:     logger.With(slog.String("caller", "janitor")),
+	}
+}
+
+// Run blocks until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Janitor) runOnce(ctx context.Context) {
+	conn, err := j.db.Connx(ctx)
+	if err != nil {
+		metrics.Add(metricErrors, 1)
+		j.logger.Error("failed to get connection", slog.String("error", err.Error()))
+		return
+	}
+
+	defer func(conn *sqlx.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	locked, err := j.repository.tryLock(ctx, conn)
+	if err != nil {
+		metrics.Add(metricErrors, 1)
+		j.logger.Error("failed to take advisory lock", slog.String("error", err.Error()))
+		return
+	}
+
+	if !locked {
+		metrics.Add(metricSkipped, 1)
+		j.logger.Debug("cleanup is running on another replica")
+		return
+	}
+
+	defer func() {
+		if unlockErr := j.repository.unlock(context.Background(), conn); unlockErr != nil {
+			j.logger.Warn("failed to release advisory lock", slog.String("error", unlockErr.Error()))
+		}
+	}()
+
+	started := time.Now()
+	now := started.UTC()
+
+	tokens := j.purge(ctx, "tokens", func(limit int) (int64, error) {
+		return j.repository.deleteTokens(ctx, conn, now.Add(-j.settings.TokenRetention), limit)
+	})
+	metrics.Add(metricTokensDeleted, tokens)
+
+	denylist := j.purge(ctx, "denylist", func(limit int) (int64, error) {
+		return j.repository.deleteDenylist(ctx, conn, now, limit)
+	})
+	metrics.Add(metricDenylistDeleted, denylist)
+
+	users := j.purge(ctx, "unconfirmed users", func(limit int) (int64, error) {
+		return j.repository.deleteUnconfirmedUsers(ctx, conn, now.Add(-j.settings.UnconfirmedMaxAge), limit)
+	})
+	metrics.Add(metricUnconfirmedDeleted, users)
+
+	duration := time.Since(started)
+
+	metrics.Add(metricRuns, 1)
+	metrics.Set(metricLastRunUnixSeconds, intVar(now.Unix()))
+	metrics.Set(metricLastRunDurationMsec, intVar(duration.Milliseconds()))
+
+	j.logger.Info("cleanup finished",
+		slog.Int64("tokens_deleted", tokens),
+		slog.Int64("denylist_deleted", denylist),
+		slog.Int64("unconfirmed_users_deleted", users),
+		slog.Duration("duration", duration),
+	)
+}
+
+// purge deletes in batches until a batch is not full, so long transactions and locks are avoided
+func (j *Janitor) purge(ctx context.Context, name string, deleteBatch func(limit int) (int64, error)) int64 {
+	var total int64
+
+	for ctx.Err() == nil {
+		deleted, err := deleteBatch(j.settings.BatchSize)
+		if err != nil {
+			metrics.Add(metricErrors, 1)
+			j.logger.Error("failed to purge "+name, slog.String("error", err.Error()))
+			break
+		}
+
+		total += deleted
+		if deleted < int64(j.settings.BatchSize) {
+			break
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,23 @@
+package janitor
+
+import "expvar"
+
+// metrics are published on /debug/vars under the "janitor" key
+var metrics = expvar.NewMap("janitor")
+
+const (
+	metricRuns                = "runs"
+	metricSkipped             = "skipped_not_leader"
+	metricErrors              = "errors"
+	metricTokensDeleted       = "tokens_deleted"
+	metricDenylistDeleted     = "denylist_deleted"
+	metricUnconfirmedDeleted  = "unconfirmed_users_deleted"
+	metricLastRunUnixSeconds  = "last_run_unix"
+	metricLastRunDurationMsec = "last_run_duration_ms"
+)
+
+func intVar(value int64) *expvar.Int {
+	result := new(expvar.Int)
+	result.Set(value)
+	return result
+}
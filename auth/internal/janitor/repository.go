@@ -0,0 +1,76 @@
+package janitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type repository struct{}
+
+// tryLock takes a session level advisory lock, only the replica holding it runs the cleanup
+func (r *repository) tryLock(ctx context.Context, conn *sqlx.Conn) (bool, error) {
+	var locked bool
+	err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock(hashtext('authorization_service.janitor'))`)
+	return locked, err
+}
+
+func (r *repository) unlock(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext('authorization_service.janitor'))`)
+	return err
+}
+
+// deleteTokens removes a batch of refresh tokens expired or revoked before the threshold.
+// Older tokens of the same chain reference newer ones via replaced_by_token and are removed by cascade.
+func (r *repository) deleteTokens(ctx context.Context, conn *sqlx.Conn, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM authorization_service.tokens
+		WHERE id IN (
+			SELECT id
+			FROM authorization_service.tokens
+			WHERE expires_at < $1 OR revoked_at < $1
+			LIMIT $2
+		)
+	`
+
+	return execCount(ctx, conn, query, before, limit)
+}
+
+func (r *repository) deleteDenylist(ctx context.Context, conn *sqlx.Conn, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM authorization_service.access_token_denylist
+		WHERE jti IN (
+			SELECT jti
+			FROM authorization_service.access_token_denylist
+			WHERE expires_at < $1
+			LIMIT $2
+		)
+	`
+
+	return execCount(ctx, conn, query, before, limit)
+}
+
+// deleteUnconfirmedUsers removes accounts never confirmed since creation, their tokens are removed by cascade
+func (r *repository) deleteUnconfirmedUsers(ctx context.Context, conn *sqlx.Conn, createdBefore time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM authorization_service.users
+		WHERE id IN (
+			SELECT id
+			FROM authorization_service.users
+			WHERE is_confirmed = false AND created_at < $1
+			LIMIT $2
+		)
+	`
+
+	return execCount(ctx, conn, query, createdBefore, limit)
+}
+
+func execCount(ctx context.Context, conn *sqlx.Conn, query string, args ...any) (int64, error) {
+	result, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,29 @@
+package janitor
+
+import (
+	"auth/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	Interval          time.Duration
+	TokenRetention    time.Duration
+	UnconfirmedMaxAge time.Duration
+	BatchSize         int
+}
+
+const (
+	defaultIntervalMinutes      = 60
+	defaultTokenRetentionDays   = 30
+	defaultUnconfirmedMaxAgeDay = 7
+	defaultBatchSize            = 1000
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		Interval:          time.Duration(config.MustGetPositiveInt("JANITOR__INTERVAL_MINUTES", defaultIntervalMinutes)) * time.Minute,
+		TokenRetention:    time.Duration(config.MustGetInt("JANITOR__TOKEN_RETENTION_DAYS", defaultTokenRetentionDays)) * 24 * time.Hour,
+		UnconfirmedMaxAge: time.Duration(config.MustGetInt("JANITOR__UNCONFIRMED_MAX_AGE_DAYS", defaultUnconfirmedMaxAgeDay)) * 24 * time.Hour,
+		BatchSize:         config.MustGetPositiveInt("JANITOR__BATCH_SIZE", defaultBatchSize),
+	}
+}
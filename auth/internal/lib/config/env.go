@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// MustGetInt returns the integer value of the environment variable or the default one if it is not set,
+// invalid values panic, so misconfigured services do not start
+func MustGetInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		panic("invalid " + key + ": " + value)
+	}
+
+	return result
+}
+
+// MustGetPositiveInt is MustGetInt for values that must be greater than zero, such as intervals of tickers
+func MustGetPositiveInt(key string, defaultValue int) int {
+	result := MustGetInt(key, defaultValue)
+	if result <= 0 {
+		panic(key + " must be greater than zero")
+	}
+
+	return result
+}
+
+// MustGetBool returns the boolean value of the environment variable or the default one if it is not set
+func MustGetBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		panic("invalid " + key + ": " + value)
+	}
+
+	return result
+}
+
+// GetString returns the value of the environment variable or the default one if it is not set
+func GetString(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
@@ -0,0 +1,39 @@
+package digest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// Hasher produces keyed hashes (HMAC-SHA256 with a server pepper) of secrets stored in the database,
+// so that a database read leak does not reveal usable tokens or codes.
+type Hasher struct {
+	pepper []byte
+}
+
+func NewHasher(pepper string) *Hasher {
+	return &Hasher{pepper: []byte(pepper)}
+}
+
+// Hash returns hex encoded HMAC of the value. Empty values stay empty.
+func (h *Hasher) Hash(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, h.pepper)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Equal compares the value with the stored hash in constant time
+func (h *Hasher) Equal(value, hash string) bool {
+	if value == "" || hash == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(h.Hash(value)), []byte(hash)) == 1
+}
@@ -12,3 +12,19 @@ func Respond(w http.ResponseWriter, r *http.Request, status int, response api.Ap
 	render.Status(r, status)
 	render.JSON(w, r, response)
 }
+
+// WriteResponse responds with 200 for successful responses,
+// otherwise with the status mapped from the response message or 500
+func WriteResponse(w http.ResponseWriter, r *http.Request, response api.AppResponse, statuses map[string]int) {
+	if response.Ok() {
+		Respond(w, r, http.StatusOK, response)
+		return
+	}
+
+	status, ok := statuses[response.Message]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	Respond(w, r, status, response)
+}
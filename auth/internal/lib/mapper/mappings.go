@@ -34,7 +34,7 @@ func MapUserSliceToDto(users []*storage.User) []*UserDto {
 		return make([]*UserDto, 0)
 	}
 
-	result := make([]*UserDto, len(users))
+	result := make([]*UserDto, 0, len(users))
 	for _, item := range users {
 		result = append(result, &UserDto{
 			Id:              item.Id,
@@ -0,0 +1,95 @@
+package pagination
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Page is a request of a page of items ordered by (created_at, id) descending
+type Page struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// PageDto is the response envelope of paginated lists
+type PageDto[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+func (p Page) backward() bool {
+	return p.Cursor != nil && p.Cursor.Direction == Prev
+}
+
+// Where returns the keyset condition with :cursor_created_at and :cursor_id named parameters
+// or an empty string for the first page
+func (p Page) Where(createdAtColumn, idColumn string) string {
+	if p.Cursor == nil {
+		return ""
+	}
+
+	operator := "<"
+	if p.backward() {
+		operator = ">"
+	}
+
+	return fmt.Sprintf("(%s, %s) %s (:cursor_created_at, CAST(:cursor_id AS uuid))", createdAtColumn, idColumn, operator)
+}
+
+// OrderBy returns ORDER BY and LIMIT clauses, one extra row is requested to detect the next page
+func (p Page) OrderBy(createdAtColumn, idColumn string) string {
+	direction := "DESC"
+	if p.backward() {
+		direction = "ASC"
+	}
+
+	return fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT :page_limit", createdAtColumn, direction, idColumn, direction)
+}
+
+// AddParams adds named parameters used by Where and OrderBy
+func (p Page) AddParams(params map[string]any) {
+	params["page_limit"] = p.Limit + 1
+
+	if p.Cursor != nil {
+		params["cursor_created_at"] = p.Cursor.CreatedAt
+		params["cursor_id"] = p.Cursor.Id
+	}
+}
+
+// Finish cuts the extra row, restores descending order of backward pages and builds cursors
+func Finish[T any](codec *Codec, page Page, items []T, key func(T) Key) ([]T, string, string) {
+	hasMore := len(items) > page.Limit
+	if hasMore {
+		items = items[:page.Limit]
+	}
+
+	if page.backward() {
+		slices.Reverse(items)
+	}
+
+	if len(items) == 0 {
+		return items, "", ""
+	}
+
+	var next, prev string
+
+	first := Cursor{Key: key(items[0]), Direction: Prev}
+	last := Cursor{Key: key(items[len(items)-1]), Direction: Next}
+
+	if page.backward() {
+		next = codec.Encode(last)
+		if hasMore {
+			prev = codec.Encode(first)
+		}
+	} else {
+		if hasMore {
+			next = codec.Encode(last)
+		}
+		if page.Cursor != nil {
+			prev = codec.Encode(first)
+		}
+	}
+
+	return items, next, prev
+}
@@ -0,0 +1,56 @@
+package sealing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var errShortValue = errors.New("sealed value is too short")
+
+// Sealer encrypts (AES-256-GCM with a key derived from a server secret) values that are stored in the database
+// only until they are delivered, such as outbox payloads carrying confirmation codes.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+func NewSealer(secret string) *Sealer {
+	key := sha256.Sum256([]byte(secret))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Sealer{aead: aead}
+}
+
+// Seal returns base64 encoded nonce and ciphertext of the value
+func (s *Sealer) Seal(value []byte) string {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(value)+s.aead.Overhead())
+	_, _ = rand.Read(nonce)
+
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, value, nil))
+}
+
+// Open decrypts the value returned by Seal
+func (s *Sealer) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < s.aead.NonceSize() {
+		return nil, errShortValue
+	}
+
+	return s.aead.Open(nil, data[:s.aead.NonceSize()], data[s.aead.NonceSize():], nil)
+}
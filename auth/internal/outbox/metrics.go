@@ -0,0 +1,12 @@
+package outbox
+
+import "expvar"
+
+// metrics are published on /debug/vars under the "outbox" key
+var metrics = expvar.NewMap("outbox")
+
+const (
+	metricPublished = "published"
+	metricFailed    = "failed"
+	metricErrors    = "errors"
+)
@@ -0,0 +1,39 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Producer publishes a message with the key of its aggregate
+type Producer interface {
+	Produce(ctx context.Context, topic string, key string, value []byte) error
+}
+
+// KafkaProducer sends messages with the same key to the same partition, so consumers read
+// the messages of an aggregate in the order they were published
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaProducer(brokers []string) *KafkaProducer {
+	return &KafkaProducer{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Balancer: &kafka.Hash{},
+			// the relay publishes messages one by one and waits for each of them
+			BatchSize:              1,
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+func (p *KafkaProducer) Produce(ctx context.Context, topic string, key string, value []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{Topic: topic, Key: []byte(key), Value: value})
+}
+
+func (p *KafkaProducer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"auth/internal/lib/sealing"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Relay publishes messages saved by repository.OutboxRepository to Kafka. Messages are claimed for Settings.Lease
+// by a short statement and published within the lease without holding a transaction or row locks.
+// A message is deleted only after it is published, so delivery is at least once and consumers must tolerate
+// duplicates. Messages of a relay stopped while publishing are claimed by other replicas when the lease expires.
+// Failed messages are retried with an exponential delay and hold back later messages of the same aggregate.
+// Messages are published with the aggregate id as the key, so the messages of an aggregate share a partition
+// and keep their order, only a message published again after an expired lease may follow later messages.
+// Payloads are decrypted right before publishing.
+type Relay struct {
+	repository *repository
+	producer   Producer
+	sealer     *sealing.Sealer
+	settings   Settings
+	logger     *slog.Logger
+}
+
+func NewRelay(db *sqlx.DB, producer Producer, sealer *sealing.Sealer, settings Settings, logger *slog.Logger) *Relay {
+	return &Relay{
+		repository: &repository{db: db},
+		producer:   producer,
+		sealer:     sealer,
+		settings:   settings,
+		logger:     logger.With(slog.String("caller", "outbox.relay")),
+	}
+}
+
+// Run blocks until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.settings.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// full batches mean more messages are waiting
+		for ctx.Err() == nil && r.publishBatch(ctx) == r.settings.BatchSize {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishBatch returns the number of claimed messages
+func (r *Relay) publishBatch(ctx context.Context) int {
+	now := time.Now().UTC()
+	// the lease identifies the claim, it is kept with the precision of postgres timestamps
+	lockedUntil := now.Add(r.settings.Lease).Truncate(time.Microsecond)
+
+	batch, err := r.repository.claimBatch(ctx, now, lockedUntil, r.settings.BatchSize)
+	if err != nil {
+		metrics.Add(metricErrors, 1)
+		r.logger.Error("could not claim outbox messages", slog.String("error", err.Error()))
+		return 0
+	}
+
+	// messages are published only while the lease holds, the rest of the batch is claimed again after it expires
+	leaseCtx, cancel := context.WithDeadline(ctx, lockedUntil)
+	defer cancel()
+
+	for _, item := range batch {
+		if leaseCtx.Err() != nil {
+			break
+		}
+
+		if err = r.publish(ctx, leaseCtx, item, lockedUntil); err != nil {
+			metrics.Add(metricErrors, 1)
+			r.logger.Error("could not save outbox message state",
+				slog.String("error", err.Error()),
+				slog.Int64("id", item.Id))
+		}
+	}
+
+	return len(batch)
+}
+
+// publish sends the message within the lease and saves the result with ctx, so the result of the last attempt is kept
+func (r *Relay) publish(ctx context.Context, leaseCtx context.Context, item *record, lockedUntil time.Time) error {
+	produceCtx, cancel := context.WithTimeout(leaseCtx, r.settings.PublishTimeout)
+	defer cancel()
+
+	payload, produceErr := r.open(item.Payload)
+	if produceErr == nil {
+		produceErr = r.producer.Produce(produceCtx, item.Topic, item.Key, payload)
+	}
+
+	if produceErr == nil {
+		metrics.Add(metricPublished, 1)
+		return r.repository.delete(ctx, item.Id)
+	}
+
+	metrics.Add(metricFailed, 1)
+	r.logger.Warn("could not publish message, retrying later",
+		slog.String("error", produceErr.Error()),
+		slog.String("topic", item.Topic),
+		slog.String("key", item.Key),
+		slog.Int("attempts", item.Attempts+1))
+
+	return r.repository.retryLater(ctx, item.Id, lockedUntil, time.Now().UTC().Add(r.backoff(item.Attempts)), produceErr.Error())
+}
+
+// open decrypts the payload saved as a json string, payloads saved before encryption are json objects and published as is
+func (r *Relay) open(payload string) ([]byte, error) {
+	var sealed string
+	if json.Unmarshal([]byte(payload), &sealed) != nil {
+		return []byte(payload), nil
+	}
+
+	return r.sealer.Open(sealed)
+}
+
+// backoff doubles the delay with every failed attempt up to RetryMax
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.settings.RetryMin
+	for i := 0; i < attempts && delay < r.settings.RetryMax; i++ {
+		delay *= 2
+	}
+
+	return min(delay, r.settings.RetryMax)
+}
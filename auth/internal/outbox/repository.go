@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// record is a saved message not yet published
+type record struct {
+	Id       int64  `db:"id"`
+	Key      string `db:"aggregate_id"`
+	Topic    string `db:"topic"`
+	Payload  string `db:"payload"`
+	Attempts int    `db:"attempts"`
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// claimBatch leases the oldest due message of every aggregate until lockedUntil, later messages of an aggregate
+// wait until the earlier ones are published, messages claimed by other replicas are skipped
+func (r *repository) claimBatch(ctx context.Context, now time.Time, lockedUntil time.Time, limit int) ([]*record, error) {
+	query := `
+		UPDATE authorization_service.outbox
+		SET locked_until = $2
+		WHERE id IN (
+			SELECT o.id
+			FROM authorization_service.outbox o
+			WHERE o.next_attempt_at <= $1
+				AND (o.locked_until IS NULL OR o.locked_until <= $1)
+				AND NOT EXISTS (SELECT 1 FROM authorization_service.outbox p WHERE p.aggregate_id = o.aggregate_id AND p.id < o.id)
+			ORDER BY o.id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, aggregate_id, topic, CAST(payload AS text) AS payload, attempts`
+
+	var result []*record
+
+	err := r.db.SelectContext(ctx, &result, query, now, lockedUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(result, func(a, b *record) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return result, nil
+}
+
+// delete removes the published message, so payloads are not kept after delivery
+func (r *repository) delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM authorization_service.outbox WHERE id = $1`, id)
+	return err
+}
+
+// retryLater releases the claim, the message is published again after nextAttemptAt.
+// A message claimed again by another replica after the lease expired is left to that replica
+func (r *repository) retryLater(ctx context.Context, id int64, lockedUntil time.Time, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE authorization_service.outbox
+		SET attempts = attempts + 1, next_attempt_at = $3, last_error = $4, locked_until = NULL
+		WHERE id = $1 AND locked_until = $2`
+
+	_, err := r.db.ExecContext(ctx, query, id, lockedUntil, nextAttemptAt, lastError)
+	return err
+}
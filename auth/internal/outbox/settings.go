@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"auth/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// RetryMin and RetryMax bound the exponential delay before the next attempt to publish a failed message
+	RetryMin time.Duration
+	RetryMax time.Duration
+	// Lease is how long claimed messages are not published by other replicas, a batch is published within the lease
+	Lease time.Duration
+	// PublishTimeout limits publishing of a message, it is shorter than Lease
+	PublishTimeout time.Duration
+}
+
+const (
+	defaultPollIntervalMs    = 500
+	defaultBatchSize         = 100
+	defaultRetryMinSeconds   = 1
+	defaultRetryMaxSeconds   = 300
+	defaultLeaseSeconds      = 60
+	defaultPublishTimeoutSec = 10
+)
+
+func MustLoadSettings() Settings {
+	settings := Settings{
+		PollInterval:   time.Duration(config.MustGetPositiveInt("OUTBOX__POLL_INTERVAL_MS", defaultPollIntervalMs)) * time.Millisecond,
+		BatchSize:      config.MustGetPositiveInt("OUTBOX__BATCH_SIZE", defaultBatchSize),
+		RetryMin:       time.Duration(config.MustGetInt("OUTBOX__RETRY_MIN_SECONDS", defaultRetryMinSeconds)) * time.Second,
+		RetryMax:       time.Duration(config.MustGetInt("OUTBOX__RETRY_MAX_SECONDS", defaultRetryMaxSeconds)) * time.Second,
+		Lease:          time.Duration(config.MustGetPositiveInt("OUTBOX__LEASE_SECONDS", defaultLeaseSeconds)) * time.Second,
+		PublishTimeout: time.Duration(config.MustGetPositiveInt("OUTBOX__PUBLISH_TIMEOUT_SECONDS", defaultPublishTimeoutSec)) * time.Second,
+	}
+
+	if settings.PublishTimeout >= settings.Lease {
+		panic("OUTBOX__PUBLISH_TIMEOUT_SECONDS must be less than OUTBOX__LEASE_SECONDS")
+	}
+
+	return settings
+}
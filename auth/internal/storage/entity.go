@@ -32,3 +32,13 @@ type Token struct {
 	RevokedByIp     string    `db:"revoked_by_ip"`
 	RevokedAt       time.Time `db:"revoked_at"`
 }
+
+type AuditEntry struct {
+	Id        string    `db:"id"`
+	ActorId   string    `db:"actor_id"`
+	TargetId  string    `db:"target_id"`
+	Action    string    `db:"action"`
+	Details   string    `db:"details"`
+	Ip        string    `db:"ip"`
+	CreatedAt time.Time `db:"created_at"`
+}
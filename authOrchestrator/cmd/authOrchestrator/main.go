@@ -1,6 +1,7 @@
 package main
 
 import (
+	"authOrchestrator/internal/orchestrators/comments"
 	"authOrchestrator/internal/orchestrators/registration"
 	"authOrchestrator/internal/storage/postgresql"
 	"context"
@@ -64,6 +65,19 @@ func main() {
 		}
 	}()
 
+	commentsOrch := comments.NewOrchestrator(
+		eventBus.NewConsumer(cfg.Consumer.Brokers, "content.commented", "authOrchestrator"),
+		eventBus.NewProducer(cfg.Producer.Brokers),
+		comments.NewRepository(storage),
+		logger,
+	)
+
+	go func() {
+		if runErr := commentsOrch.Run(ctx); runErr != nil {
+			logger.Error("comments orchestrator error", plog.Error(runErr))
+		}
+	}()
+
 	<-ctx.Done()
 	logger.Info("shutting down gracefully")
 }
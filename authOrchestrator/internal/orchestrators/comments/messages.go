@@ -0,0 +1,22 @@
+package comments
+
+import "time"
+
+type ContentCommentedMessage struct {
+	CommentId    string    `json:"commentId"`
+	ContentId    string    `json:"contentId"`
+	ContentName  string    `json:"contentName"`
+	OwnerId      string    `json:"ownerId"`
+	UserId       string    `json:"userId"`
+	ParentId     string    `json:"parentId"`
+	ParentUserId string    `json:"parentUserId"`
+	Text         string    `json:"text"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type EmailMessage struct {
+	To             string `json:"to"`
+	Message        string `json:"message"`
+	Title          string `json:"title"`
+	IdempotencyKey string `json:"idempotencyKey"`
+}
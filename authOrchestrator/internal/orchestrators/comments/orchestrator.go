@@ -0,0 +1,152 @@
+package comments
+
+import (
+	"authOrchestrator/internal/orchestrators"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+
+	"github.com/flores666/profileshare-lib/eventBus"
+)
+
+type commentsOrchestrator struct {
+	logger     *slog.Logger
+	producer   eventBus.Producer
+	consumer   eventBus.Consumer
+	repository Repository
+}
+
+// NewOrchestrator notifies the content owner and the author of the parent comment about new comments by email
+func NewOrchestrator(
+	consumer eventBus.Consumer,
+	producer eventBus.Producer,
+	repository Repository,
+	logger *slog.Logger,
+) orchestrators.Orchestrator {
+	return &commentsOrchestrator{
+		logger:     logger,
+		producer:   producer,
+		consumer:   consumer,
+		repository: repository,
+	}
+}
+
+func (o *commentsOrchestrator) Run(ctx context.Context) error {
+	return o.consumer.Consume(ctx, func(data []byte) error {
+		var message ContentCommentedMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			o.logger.Error("unmarshal error", slog.String("error", err.Error()))
+			return err
+		}
+
+		ids := getRecipientIds(message)
+		if len(ids) == 0 {
+			return nil
+		}
+
+		recipients, err := o.repository.GetRecipients(ctx, ids)
+		if err != nil {
+			o.logger.Error("could not get recipients", slog.String("error", err.Error()), slog.String("commentId", message.CommentId))
+			return err
+		}
+
+		author, err := o.repository.GetNickname(ctx, message.UserId)
+		if err != nil {
+			o.logger.Error("could not get comment author", slog.String("error", err.Error()), slog.String("commentId", message.CommentId))
+			return err
+		}
+
+		for _, recipient := range recipients {
+			if err = o.producer.Produce(ctx, "emails.send", getEmailMessage(message, recipient, author)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// getRecipientIds returns the content owner and the author of the parent comment except the author of the comment
+func getRecipientIds(message ContentCommentedMessage) []string {
+	var ids []string
+
+	if message.OwnerId != message.UserId {
+		ids = append(ids, message.OwnerId)
+	}
+
+	if message.ParentUserId != "" && message.ParentUserId != message.UserId && message.ParentUserId != message.OwnerId {
+		ids = append(ids, message.ParentUserId)
+	}
+
+	return ids
+}
+
+func getEmailMessage(msg ContentCommentedMessage, recipient *Recipient, author string) EmailMessage {
+	title := "Новый комментарий"
+	action := fmt.Sprintf("оставил комментарий к записи <strong>%s</strong>", html.EscapeString(msg.ContentName))
+
+	if recipient.Id == msg.ParentUserId {
+		title = "Ответ на ваш комментарий"
+		action = fmt.Sprintf("ответил на ваш комментарий к записи <strong>%s</strong>", html.EscapeString(msg.ContentName))
+	}
+
+	htmlMessage := fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="ru">
+<head>
+  <meta charset="UTF-8">
+  <title>%s — Lumo</title>
+  <style>
+    body {
+      font-family: Arial, sans-serif;
+      background-color: #f5f6fa;
+      margin: 0;
+      padding: 0;
+    }
+    .container {
+      max-width: 600px;
+      margin: 40px auto;
+      background-color: #ffffff;
+      border-radius: 8px;
+      box-shadow: 0 2px 8px rgba(0,0,0,0.05);
+      padding: 20px 40px 40px 40px;
+    }
+    .comment {
+      padding: 14px 18px;
+      background-color: #f5f6fa;
+      border-radius: 6px;
+      white-space: pre-wrap;
+    }
+    .footer {
+      margin-top: 30px;
+      font-size: 12px;
+      color: #888888;
+      text-align: center;
+    }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <h2>%s</h2>
+    <p>Здравствуйте, %s!</p>
+    <p><strong>%s</strong> %s:</p>
+
+    <p class="comment">%s</p>
+
+    <div class="footer">
+      &copy; 2025 Lumo. Все права защищены.
+    </div>
+  </div>
+</body>
+</html>
+`, title, title, html.EscapeString(recipient.Nickname), html.EscapeString(author), action, html.EscapeString(msg.Text))
+
+	return EmailMessage{
+		To:             recipient.Email,
+		Message:        htmlMessage,
+		Title:          title,
+		IdempotencyKey: msg.CommentId + ":" + recipient.Id,
+	}
+}
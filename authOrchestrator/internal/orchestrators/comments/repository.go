@@ -0,0 +1,55 @@
+package comments
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Recipient struct {
+	Id       string `db:"id"`
+	Nickname string `db:"nickname"`
+	Email    string `db:"email"`
+}
+
+type Repository interface {
+	// GetRecipients returns confirmed users with the ids
+	GetRecipients(ctx context.Context, ids []string) ([]*Recipient, error)
+	GetNickname(ctx context.Context, id string) (string, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) GetRecipients(ctx context.Context, ids []string) ([]*Recipient, error) {
+	query, args, err := sqlx.In(`
+		SELECT CAST(id AS text) AS id, nickname, email
+		FROM authorization_service.users
+		WHERE id IN (?) AND is_confirmed`, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Recipient
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) GetNickname(ctx context.Context, id string) (string, error) {
+	query := `SELECT COALESCE((SELECT nickname FROM authorization_service.users WHERE id = $1), '')`
+
+	var nickname string
+	err := r.db.GetContext(ctx, &nickname, query, id)
+
+	return nickname, err
+}
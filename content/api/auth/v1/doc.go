@@ -0,0 +1,4 @@
+// Package authv1 is the client of the internal gRPC API of the auth service.
+// The generated files are copied from auth/api/auth/v1, since services are built from their own directories,
+// regenerate them from auth/api/auth/v1/auth.proto after changing the contract.
+package authv1
@@ -1,14 +1,38 @@
 package main
 
 import (
+	"content/internal/admin"
+	"content/internal/authclient"
+	"content/internal/feed"
+	"content/internal/handlers/comments"
 	"content/internal/handlers/content"
+	"content/internal/handlers/folders"
+	"content/internal/handlers/follows"
+	"content/internal/handlers/media"
+	"content/internal/handlers/moderation"
+	"content/internal/handlers/shares"
+	"content/internal/handlers/tags"
+	"content/internal/handlers/types"
+	"content/internal/images"
+	"content/internal/lib/impersonation"
+	"content/internal/lib/pagination"
+	"content/internal/lib/visibility"
+	"content/internal/outbox"
+	"content/internal/storage/blob"
 	"content/internal/storage/postgresql"
+	"content/internal/trash"
+	"content/internal/unfurl"
+	"context"
+	"errors"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/flores666/profileshare-lib/config"
+	"github.com/flores666/profileshare-lib/eventBus"
 	libmiddleware "github.com/flores666/profileshare-lib/middleware"
 
 	plog "github.com/flores666/profileshare-lib/logger"
@@ -48,17 +72,105 @@ func main() {
 		}
 	}(storage)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	blobStore, err := blob.NewBlobStore(ctx, blob.MustLoadSettings())
+	if err != nil {
+		logger.Error("failed to init blob store", plog.Error(err))
+		os.Exit(1)
+	}
+
+	imagesConsumer := eventBus.NewConsumer(cfg.Consumer.Brokers, media.MediaUploadedTopic, "content_images")
+	imagesWorker := images.NewWorker(imagesConsumer, storage, blobStore, images.MustLoadSettings(), logger)
+
+	go func() {
+		if consumeErr := imagesWorker.Run(ctx); consumeErr != nil {
+			logger.Error("consume error", slog.String("error", consumeErr.Error()))
+		}
+	}()
+
+	contentSettings := content.MustLoadSettings()
+	if contentSettings.FeedInboxEnabled {
+		// items are delivered when they are created public or become public by an update or a restore
+		feedConsumers := []eventBus.Consumer{
+			eventBus.NewConsumer(cfg.Consumer.Brokers, content.ContentCreatedTopic, "content_feed"),
+			eventBus.NewConsumer(cfg.Consumer.Brokers, content.ContentUpdatedTopic, "content_feed_updated"),
+			eventBus.NewConsumer(cfg.Consumer.Brokers, content.ContentRestoredTopic, "content_feed_restored"),
+		}
+		feedWorker := feed.NewWorker(feedConsumers, storage, feed.MustLoadSettings(), logger)
+
+		go func() {
+			if consumeErr := feedWorker.Run(ctx); consumeErr != nil {
+				logger.Error("consume error", slog.String("error", consumeErr.Error()))
+			}
+		}()
+	}
+
+	unfurlConsumer := eventBus.NewConsumer(cfg.Consumer.Brokers, content.ContentCreatedTopic, "content_unfurl")
+	unfurlWorker := unfurl.NewWorker(unfurlConsumer, storage, unfurl.MustLoadSettings(), logger)
+
+	go func() {
+		if consumeErr := unfurlWorker.Run(ctx); consumeErr != nil {
+			logger.Error("consume error", slog.String("error", consumeErr.Error()))
+		}
+	}()
+
+	// events are saved to the outbox with the state changes and published by the relay with the aggregate id as the key
+	producer := outbox.NewKafkaProducer(cfg.Producer.Brokers)
+	defer func() {
+		_ = producer.Close()
+	}()
+
+	go outbox.NewRelay(storage, producer, outbox.MustLoadSettings(), logger).Run(ctx)
+
+	go trash.NewPurger(storage, content.NewRepository(storage), media.NewRepository(storage), blobStore, trash.MustLoadSettings(), logger).Run(ctx)
+
+	// permissions of users are checked by the auth service
+	authSettings := authclient.MustLoadSettings()
+	authConn, err := authclient.Dial(authSettings)
+	if err != nil {
+		logger.Error("failed to init auth client", plog.Error(err))
+		os.Exit(1)
+	}
+
+	defer func() {
+		_ = authConn.Close()
+	}()
+
+	authClient := authclient.NewClient(authConn, authSettings)
+
 	server := &http.Server{
 		Addr:         cfg.HttpServer.Address,
-		Handler:      buildHandler(logger, storage),
+		Handler:      buildHandler(logger, storage, blobStore, authClient, contentSettings),
 		ReadTimeout:  cfg.HttpServer.Timeout,
 		WriteTimeout: cfg.HttpServer.Timeout,
 		IdleTimeout:  cfg.HttpServer.IddleTimeout,
 	}
 
+	// diagnostics are served on a separate listener, so they are not exposed with the public api
+	adminServer := admin.NewServer(admin.MustLoadSettings())
+
+	go func() {
+		logger.Info("starting admin server", slog.String("address", adminServer.Addr))
+		if serveErr := adminServer.ListenAndServe(); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			logger.Error("failed to start admin server", plog.Error(serveErr))
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("shutting down gracefully")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HttpServer.Timeout)
+		defer cancel()
+		_ = adminServer.Shutdown(shutdownCtx)
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
 	logger.Info("starting application", slog.String("address", cfg.HttpServer.Address))
 
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("failed to start http server", plog.Error(err))
 	}
 
@@ -80,16 +192,43 @@ func setupLogger(env string) *slog.Logger {
 	return logger
 }
 
-func buildHandler(logger *slog.Logger, storage *sqlx.DB) http.Handler {
+func buildHandler(logger *slog.Logger, storage *sqlx.DB, blobStore blob.BlobStore, authClient *authclient.Client, contentSettings content.Settings) http.Handler {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
 	router.Use(plog.NewRequestLogMiddleware(logger))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
-	authMiddleware := libmiddleware.AuthMiddleware([]byte(os.Getenv("SECURITY__ACCESS_SECRET")))
-
-	content.NewContentHandler(content.NewService(content.NewRepository(storage), logger)).RegisterRoutes(router, authMiddleware)
+	accessSecret := []byte(os.Getenv("SECURITY__ACCESS_SECRET"))
+	// the admin of impersonation tokens is kept in the context for events and irreversible actions
+	authMiddleware := impersonation.Middleware(libmiddleware.AuthMiddleware(accessSecret), accessSecret)
+
+	foldersRepository := folders.NewRepository(storage)
+	mediaRepository := media.NewRepository(storage)
+	mediaSettings := media.MustLoadSettings()
+	signer := media.NewSigner(mediaSettings)
+	paginator := pagination.NewPaginator(pagination.MustLoadSettings())
+	followsRepository := follows.NewRepository(storage)
+	sharesRepository := shares.NewRepository(storage)
+	policy := visibility.NewPolicy(followsRepository)
+	access := shares.NewAccess(sharesRepository, shares.MustLoadSettings(), logger)
+
+	typesRepository := types.NewRepository(storage)
+	typesSettings := types.MustLoadSettings()
+	registry := types.NewRegistry(typesRepository, typesSettings)
+
+	contentRepository := content.NewRepository(storage)
+	contentService := content.NewService(contentRepository, foldersRepository, followsRepository, mediaRepository, signer, registry, paginator, policy, access, contentSettings, logger)
+
+	content.NewContentHandler(contentService).RegisterRoutes(router, authMiddleware)
+	comments.NewCommentsHandler(comments.NewService(comments.NewRepository(storage), contentService, paginator, comments.MustLoadSettings(), logger)).RegisterRoutes(router, authMiddleware)
+	moderation.NewModerationHandler(moderation.NewService(moderation.NewRepository(storage), contentService, contentRepository, authClient, paginator, moderation.MustLoadSettings(), logger)).RegisterRoutes(router, authMiddleware)
+	folders.NewFoldersHandler(folders.NewService(foldersRepository, signer, policy, access, logger)).RegisterRoutes(router, authMiddleware)
+	follows.NewFollowsHandler(follows.NewService(followsRepository, logger)).RegisterRoutes(router, authMiddleware)
+	shares.NewSharesHandler(shares.NewService(sharesRepository, logger)).RegisterRoutes(router, authMiddleware)
+	tags.NewTagsHandler(tags.NewService(tags.NewRepository(storage), logger)).RegisterRoutes(router, authMiddleware)
+	media.NewMediaHandler(media.NewService(mediaRepository, blobStore, signer, registry, mediaSettings, logger), mediaSettings).RegisterRoutes(router, authMiddleware)
+	types.NewTypesHandler(types.NewService(typesRepository, registry, authClient, typesSettings, logger)).RegisterRoutes(router, authMiddleware)
 
 	return router
 }
@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// NewServer serves diagnostics apart from the public router,
+// the address should be reachable only from the host or the internal network
+func NewServer(settings Settings) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return &http.Server{
+		Addr:              settings.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: settings.Timeout,
+		WriteTimeout:      settings.Timeout,
+	}
+}
@@ -0,0 +1,59 @@
+package authclient
+
+import (
+	authv1 "content/api/auth/v1"
+	"context"
+	"encoding/base64"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client checks permissions of users with the auth service, so roles stay the concern of the auth service
+type Client struct {
+	client   authv1.AuthServiceClient
+	settings Settings
+}
+
+// Dial creates the connection to the auth service, it is established lazily on the first call
+func Dial(settings Settings) (*grpc.ClientConn, error) {
+	return grpc.NewClient(settings.Address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(credentials{id: settings.ClientId, secret: settings.ClientSecret}),
+	)
+}
+
+func NewClient(conn grpc.ClientConnInterface, settings Settings) *Client {
+	return &Client{
+		client:   authv1.NewAuthServiceClient(conn),
+		settings: settings,
+	}
+}
+
+// HasPermission reports whether the user exists, is not banned and has a role granting the permission
+func (c *Client) HasPermission(ctx context.Context, userId string, permission string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.settings.Timeout)
+	defer cancel()
+
+	response, err := c.client.CheckPermission(ctx, &authv1.CheckPermissionRequest{UserId: userId, Permission: permission})
+	if err != nil {
+		return false, err
+	}
+
+	return response.GetAllowed(), nil
+}
+
+// credentials are sent as "authorization: Basic" metadata, calls stay inside the docker network
+type credentials struct {
+	id     string
+	secret string
+}
+
+func (c credentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	token := base64.StdEncoding.EncodeToString([]byte(c.id + ":" + c.secret))
+	return map[string]string{"authorization": "Basic " + token}, nil
+}
+
+func (c credentials) RequireTransportSecurity() bool {
+	return false
+}
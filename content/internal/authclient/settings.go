@@ -0,0 +1,30 @@
+package authclient
+
+import (
+	"content/internal/lib/config"
+	"os"
+	"time"
+)
+
+type Settings struct {
+	// Address of the gRPC server of the auth service
+	Address string
+	// ClientId and ClientSecret are credentials of the content service in SECURITY__INTERNAL_CLIENTS of the auth service
+	ClientId     string
+	ClientSecret string
+	Timeout      time.Duration
+}
+
+func MustLoadSettings() Settings {
+	secret := os.Getenv("AUTH__CLIENT_SECRET")
+	if secret == "" {
+		panic("AUTH__CLIENT_SECRET is required")
+	}
+
+	return Settings{
+		Address:      config.GetString("AUTH__GRPC_ADDRESS", "auth:9081"),
+		ClientId:     config.GetString("AUTH__CLIENT_ID", "content"),
+		ClientSecret: secret,
+		Timeout:      time.Duration(config.MustGetPositiveInt("AUTH__TIMEOUT_MS", 2000)) * time.Millisecond,
+	}
+}
@@ -0,0 +1,45 @@
+package feed
+
+import (
+	"content/internal/lib/visibility"
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func newRepository(db *sqlx.DB) *repository {
+	return &repository{db: db}
+}
+
+// Deliver adds the item to inboxes of all followers of the author if it is still public
+func (r *repository) Deliver(ctx context.Context, contentId string) (int64, error) {
+	query := `
+		INSERT INTO content.feed_inbox (user_id, content_id, author_id, created_at)
+		SELECT f.follower_id, c.id, c.user_id, c.created_at
+		FROM content.content c
+		JOIN content.follows f ON f.followee_id = c.user_id
+		WHERE c.id = $1 AND c.visibility = '` + visibility.Public + `' AND c.deleted_at IS NULL
+		ON CONFLICT (user_id, content_id) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, contentId)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Trim removes inbox entries of items created before the time
+func (r *repository) Trim(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM content.feed_inbox WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
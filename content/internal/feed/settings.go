@@ -0,0 +1,19 @@
+package feed
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// Retention is the age after which inbox entries are removed
+	Retention    time.Duration
+	TrimInterval time.Duration
+}
+
+func MustLoadSettings() Settings {
+	return Settings{
+		Retention:    time.Duration(config.MustGetInt("FEED__INBOX_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		TrimInterval: time.Duration(config.MustGetPositiveInt("FEED__TRIM_INTERVAL_MINUTES", 60)) * time.Minute,
+	}
+}
@@ -0,0 +1,107 @@
+package feed
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/lib/visibility"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/eventBus"
+	"github.com/jmoiron/sqlx"
+)
+
+// Worker fills feed inboxes of followers (fan-out on write) consuming content.ContentCreatedTopic,
+// content.ContentRestoredTopic and content.ContentUpdatedTopic, so items are delivered when they become public
+// and feeds of users following many accounts are read from a single index
+type Worker struct {
+	consumers  []eventBus.Consumer
+	repository *repository
+	settings   Settings
+	logger     *slog.Logger
+}
+
+func NewWorker(consumers []eventBus.Consumer, db *sqlx.DB, settings Settings, logger *slog.Logger) *Worker {
+	return &Worker{
+		consumers:  consumers,
+		repository: newRepository(db),
+		settings:   settings,
+		logger:     logger.With(slog.String("caller", "feed.worker")),
+	}
+}
+
+// Run trims old inbox entries in the background and consumes events until the context is done
+func (w *Worker) Run(ctx context.Context) error {
+	go w.trim(ctx)
+
+	errs := make(chan error, len(w.consumers))
+	for _, consumer := range w.consumers {
+		go func(consumer eventBus.Consumer) {
+			errs <- consumer.Consume(ctx, func(data []byte) error {
+				return w.handle(ctx, data)
+			})
+		}(consumer)
+	}
+
+	var result error
+	for range w.consumers {
+		result = errors.Join(result, <-errs)
+	}
+
+	return result
+}
+
+func (w *Worker) handle(ctx context.Context, data []byte) error {
+	var event content.ContentEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		w.logger.Error("unmarshal error", slog.String("error", err.Error()))
+		return nil
+	}
+
+	if !becamePublic(&event) {
+		return nil
+	}
+
+	// events are delivered at least once, delivered entries are skipped
+	delivered, err := w.repository.Deliver(ctx, event.ContentId)
+	if err != nil {
+		w.logger.Error("could not deliver content to inboxes", slog.String("error", err.Error()), slog.String("id", event.ContentId))
+		return err
+	}
+
+	w.logger.Debug("content delivered to inboxes", slog.String("id", event.ContentId), slog.Int64("inboxes", delivered))
+
+	return nil
+}
+
+// becamePublic reports whether the item is public after the change and was not visible to followers before it,
+// created and restored items have no state before the change
+func becamePublic(event *content.ContentEvent) bool {
+	if event.After == nil || event.After.Visibility != visibility.Public || event.After.Blocked {
+		return false
+	}
+
+	return event.Before == nil || event.Before.Visibility != visibility.Public || event.Before.Blocked
+}
+
+func (w *Worker) trim(ctx context.Context) {
+	ticker := time.NewTicker(w.settings.TrimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := w.repository.Trim(ctx, time.Now().UTC().Add(-w.settings.Retention))
+			if err != nil {
+				w.logger.Error("could not trim inboxes", slog.String("error", err.Error()))
+				continue
+			}
+
+			w.logger.Info("inboxes trimmed", slog.Int64("deleted", deleted))
+		}
+	}
+}
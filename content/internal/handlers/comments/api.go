@@ -0,0 +1,141 @@
+package comments
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/handlers/shares"
+	"content/internal/lib/handlers"
+	"net/http"
+	"strconv"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath        = "/api/comments"
+	contentBasePath = "/api/content/{id}/comments"
+)
+
+var statuses = map[string]int{
+	ErrValidation:         http.StatusBadRequest,
+	ErrForbidden:          http.StatusForbidden,
+	ErrNotFound:           http.StatusNotFound,
+	ErrDisabled:           http.StatusForbidden,
+	ErrRateLimit:          http.StatusTooManyRequests,
+	content.ErrNotFound:   http.StatusNotFound,
+	shares.ErrLinkInvalid: http.StatusNotFound,
+	shares.ErrPassword:    http.StatusUnauthorized,
+	shares.ErrAttempts:    http.StatusTooManyRequests,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewCommentsHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Group(func(r chi.Router) {
+		r.Use(handlers.OptionalAuth(authMiddleware))
+		r.Get(contentBasePath, h.getByContent)
+		r.Get(basePath+"/{id}/replies", h.getReplies)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Post(contentBasePath, h.create)
+		r.Put(contentBasePath+"/settings", h.setSettings)
+		r.Put(basePath+"/{id}", h.update)
+		r.Delete(basePath+"/{id}", h.delete)
+		r.Put(basePath+"/{id}/hidden", h.hide)
+	})
+}
+
+func (h *Handler) getByContent(w http.ResponseWriter, r *http.Request) {
+	filter, ok := getFilter(w, r)
+	if !ok {
+		return
+	}
+
+	filter.ContentId = chi.URLParam(r, "id")
+	handlers.WriteResponse(w, r, h.service.GetByFilter(r.Context(), filter, handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) getReplies(w http.ResponseWriter, r *http.Request) {
+	filter, ok := getFilter(w, r)
+	if !ok {
+		return
+	}
+
+	filter.ParentId = chi.URLParam(r, "id")
+	handlers.WriteResponse(w, r, h.service.GetByFilter(r.Context(), filter, handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var request CreateCommentRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Create(r.Context(), chi.URLParam(r, "id"), request, handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
+	var request UpdateCommentRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Update(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.Delete(r.Context(), chi.URLParam(r, "id"), handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) hide(w http.ResponseWriter, r *http.Request) {
+	var request HideCommentRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Hide(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) setSettings(w http.ResponseWriter, r *http.Request) {
+	var request CommentSettingsRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.SetSettings(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func getFilter(w http.ResponseWriter, r *http.Request) (ListFilter, bool) {
+	filter := ListFilter{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			errs := &api.ValidationErrors{}
+			errs.Add("limit", "должно быть числом")
+			handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, errs))
+			return filter, false
+		}
+
+		filter.Limit = value
+	}
+
+	return filter, true
+}
@@ -0,0 +1,89 @@
+package comments
+
+import "time"
+
+type CreateCommentRequest struct {
+	Text string `json:"text" validate:"required"`
+	// ParentId is the top level comment the reply is written to
+	ParentId string `json:"parentId,omitempty"`
+}
+
+type UpdateCommentRequest struct {
+	Text string `json:"text" validate:"required"`
+}
+
+type HideCommentRequest struct {
+	Hidden bool `json:"hidden"`
+}
+
+type CommentSettingsRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ListFilter selects a page of top level comments of the item or replies to the comment
+type ListFilter struct {
+	ContentId string
+	ParentId  string
+	Cursor    string
+	Limit     int
+	// ViewerId and Moderator are set by the service, the moderator sees hidden comments
+	ViewerId  string
+	Moderator bool
+}
+
+type CommentDto struct {
+	Id        string `json:"id"`
+	ContentId string `json:"contentId"`
+	UserId    string `json:"userId,omitempty"`
+	ParentId  string `json:"parentId,omitempty"`
+	// Text is empty for deleted comments kept because of their replies
+	Text         string     `json:"text"`
+	Hidden       bool       `json:"hidden,omitempty"`
+	Blocked      bool       `json:"blocked,omitempty"`
+	Deleted      bool       `json:"deleted,omitempty"`
+	RepliesCount int        `json:"repliesCount,omitempty"`
+	EditedAt     *time.Time `json:"editedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func MapCommentToDto(model *Comment) CommentDto {
+	if model == nil {
+		return CommentDto{}
+	}
+
+	dto := CommentDto{
+		Id:           model.Id,
+		ContentId:    model.ContentId,
+		UserId:       model.UserId,
+		ParentId:     model.ParentId,
+		Text:         model.Text,
+		Hidden:       model.Hidden,
+		Blocked:      model.Blocked,
+		RepliesCount: model.RepliesCount,
+		CreatedAt:    model.CreatedAt,
+	}
+
+	if !model.EditedAt.IsZero() {
+		editedAt := model.EditedAt
+		dto.EditedAt = &editedAt
+	}
+
+	// the author of a deleted comment is not disclosed
+	if !model.DeletedAt.IsZero() {
+		dto.Deleted = true
+		dto.Text = ""
+		dto.UserId = ""
+	}
+
+	return dto
+}
+
+func MapCommentSliceToDto(list []*Comment) []*CommentDto {
+	result := make([]*CommentDto, 0, len(list))
+	for _, model := range list {
+		dto := MapCommentToDto(model)
+		result = append(result, &dto)
+	}
+
+	return result
+}
@@ -0,0 +1,21 @@
+package comments
+
+import "time"
+
+// Comment represents a comment to content, replies reference a top level comment by ParentId
+type Comment struct {
+	Id        string `db:"id"`
+	ContentId string `db:"content_id"`
+	UserId    string `db:"user_id"`
+	ParentId  string `db:"parent_id"`
+	Text      string `db:"text"`
+	// Hidden comments are hidden by the content owner and shown only to the owner and the author
+	Hidden bool `db:"hidden"`
+	// Blocked comments are hidden by moderators and shown only to the author
+	Blocked   bool      `db:"blocked"`
+	EditedAt  time.Time `db:"edited_at"`
+	DeletedAt time.Time `db:"deleted_at"`
+	CreatedAt time.Time `db:"created_at"`
+	// RepliesCount is selected for top level comments
+	RepliesCount int `db:"replies_count"`
+}
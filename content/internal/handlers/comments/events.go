@@ -0,0 +1,20 @@
+package comments
+
+import "time"
+
+const ContentCommentedTopic = "content.commented"
+
+// ContentCommentedEvent is published when a comment is written, so the content owner
+// and the author of the parent comment can be notified
+type ContentCommentedEvent struct {
+	CommentId   string `json:"commentId"`
+	ContentId   string `json:"contentId"`
+	ContentName string `json:"contentName"`
+	OwnerId     string `json:"ownerId"`
+	UserId      string `json:"userId"`
+	// ParentId and ParentUserId are set for replies
+	ParentId     string    `json:"parentId,omitempty"`
+	ParentUserId string    `json:"parentUserId,omitempty"`
+	Text         string    `json:"text"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
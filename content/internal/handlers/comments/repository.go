@@ -0,0 +1,168 @@
+package comments
+
+import (
+	"content/internal/lib/pagination"
+	"content/internal/outbox"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// errRateLimited means the user wrote too many comments recently
+var errRateLimited = errors.New("comment rate limit exceeded")
+
+type Repository interface {
+	// Create saves the comment unless the user has written limit comments since the time
+	Create(ctx context.Context, comment Comment, limit int, since time.Time, messages ...outbox.Message) error
+	GetById(ctx context.Context, id string) (*Comment, error)
+	Query(ctx context.Context, filter ListFilter, page pagination.Page) ([]*Comment, error)
+	Update(ctx context.Context, id string, text string, editedAt time.Time) error
+	Delete(ctx context.Context, id string, deletedAt time.Time) error
+	SetHidden(ctx context.Context, id string, hidden bool) error
+	// SetEnabled allows or forbids new comments to the content item
+	SetEnabled(ctx context.Context, contentId string, enabled bool) error
+}
+
+const selectComment = `
+	SELECT cm.id, cm.content_id, cm.user_id, COALESCE(CAST(cm.parent_id AS text), '') AS parent_id, cm.text, cm.hidden,
+		cm.blocked_at IS NOT NULL AS blocked,
+		COALESCE(cm.edited_at, make_timestamptz(1,1,1,0,0,0)) AS edited_at,
+		COALESCE(cm.deleted_at, make_timestamptz(1,1,1,0,0,0)) AS deleted_at,
+		cm.created_at`
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) Create(ctx context.Context, comment Comment, limit int, since time.Time, messages ...outbox.Message) error {
+	return postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		// the lock serializes comments of the user, so concurrent requests cannot exceed the limit
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "content.comments:"+comment.UserId); err != nil {
+			return err
+		}
+
+		var written int
+		query := `SELECT COUNT(*) FROM content.comments WHERE user_id = $1 AND created_at > $2`
+
+		if err := tx.GetContext(ctx, &written, query, comment.UserId, since); err != nil {
+			return err
+		}
+
+		if written >= limit {
+			return errRateLimited
+		}
+
+		query = `
+			INSERT INTO content.comments (id, content_id, user_id, parent_id, text, created_at)
+			VALUES ($1, $2, $3, CAST(NULLIF($4, '') AS uuid), $5, $6)`
+
+		if _, err := tx.ExecContext(ctx, query, comment.Id, comment.ContentId, comment.UserId, comment.ParentId, comment.Text, comment.CreatedAt); err != nil {
+			return err
+		}
+
+		return outbox.Write(tx.Exec, messages...)
+	})
+}
+
+func (r *repository) GetById(ctx context.Context, id string) (*Comment, error) {
+	var comment Comment
+
+	err := r.db.GetContext(ctx, &comment, selectComment+` FROM content.comments cm WHERE cm.id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// Query returns a page of top level comments of the item or replies to the comment,
+// deleted top level comments are kept while they have replies
+func (r *repository) Query(ctx context.Context, filter ListFilter, page pagination.Page) ([]*Comment, error) {
+	query := selectComment + `,
+		(
+			SELECT COUNT(*) FROM content.comments rp
+			WHERE rp.parent_id = cm.id AND rp.deleted_at IS NULL AND ` + visibleCondition("rp") + `
+		) AS replies_count
+		FROM content.comments cm
+		WHERE ` + visibleCondition("cm")
+
+	params := map[string]any{
+		"viewer_id": filter.ViewerId,
+		"moderator": filter.Moderator,
+	}
+
+	if filter.ParentId != "" {
+		query += " AND cm.parent_id = :parent_id AND cm.deleted_at IS NULL"
+		params["parent_id"] = filter.ParentId
+	} else {
+		query += ` AND cm.content_id = :content_id AND cm.parent_id IS NULL
+			AND (cm.deleted_at IS NULL OR EXISTS (
+				SELECT 1 FROM content.comments rp WHERE rp.parent_id = cm.id AND rp.deleted_at IS NULL
+			))`
+		params["content_id"] = filter.ContentId
+	}
+
+	if where := page.Where("cm.created_at", "cm.id"); where != "" {
+		query += " AND " + where
+	}
+
+	query += page.OrderBy("cm.created_at", "cm.id")
+	page.AddParams(params)
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Comment
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// visibleCondition selects comments shown to the viewer, hidden ones are shown to the moderator and the author,
+// blocked ones only to the author
+func visibleCondition(alias string) string {
+	return `((NOT ` + alias + `.hidden OR :moderator) AND ` + alias + `.blocked_at IS NULL
+		OR ` + alias + `.user_id = CAST(NULLIF(:viewer_id, '') AS uuid))`
+}
+
+func (r *repository) Update(ctx context.Context, id string, text string, editedAt time.Time) error {
+	query := `UPDATE content.comments SET text = $2, edited_at = $3 WHERE id = $1 AND deleted_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, id, text, editedAt)
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, id string, deletedAt time.Time) error {
+	query := `UPDATE content.comments SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, id, deletedAt)
+	return err
+}
+
+func (r *repository) SetHidden(ctx context.Context, id string, hidden bool) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE content.comments SET hidden = $2 WHERE id = $1`, id, hidden)
+	return err
+}
+
+func (r *repository) SetEnabled(ctx context.Context, contentId string, enabled bool) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE content.content SET comments_enabled = $2 WHERE id = $1`, contentId, enabled)
+	return err
+}
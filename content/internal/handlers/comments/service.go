@@ -0,0 +1,335 @@
+package comments
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/lib/pagination"
+	"content/internal/lib/visibility"
+	"content/internal/outbox"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+	"github.com/flores666/profileshare-lib/utils"
+)
+
+type Service interface {
+	Create(ctx context.Context, contentId string, request CreateCommentRequest, viewer visibility.Viewer) api.AppResponse
+	GetByFilter(ctx context.Context, filter ListFilter, viewer visibility.Viewer) api.AppResponse
+	Update(ctx context.Context, id string, request UpdateCommentRequest, userId string) api.AppResponse
+	// Delete removes the comment of the author, the content owner may delete any comment to the item
+	Delete(ctx context.Context, id string, userId string) api.AppResponse
+	Hide(ctx context.Context, id string, request HideCommentRequest, userId string) api.AppResponse
+	SetSettings(ctx context.Context, contentId string, request CommentSettingsRequest, userId string) api.AppResponse
+}
+
+// Contents returns the content item if the viewer may read it
+type Contents interface {
+	GetById(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
+}
+
+type service struct {
+	repository Repository
+	contents   Contents
+	paginator  *pagination.Paginator
+	settings   Settings
+	logger     *slog.Logger
+}
+
+const (
+	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	ErrForbidden   = "Комментарий вам не принадлежит"
+	ErrNotFound    = "Комментарий не найден"
+	ErrDisabled    = "Комментарии к записи отключены"
+	ErrRateLimit   = "Слишком много комментариев, попробуйте позже"
+	Success        = "Успешно"
+)
+
+func NewService(
+	repository Repository,
+	contents Contents,
+	paginator *pagination.Paginator,
+	settings Settings,
+	logger *slog.Logger,
+) Service {
+	srv := &service{
+		repository: repository,
+		contents:   contents,
+		paginator:  paginator,
+		settings:   settings,
+		logger:     logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.comments.service"))
+
+	return srv
+}
+
+func (s *service) Create(ctx context.Context, contentId string, request CreateCommentRequest, viewer visibility.Viewer) api.AppResponse {
+	if err := validateText(request.Text, s.settings.MaxLength); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	item, response := s.getContent(ctx, contentId, viewer)
+	if item == nil {
+		return response
+	}
+
+	if !item.CommentsEnabled {
+		return api.NewError(ErrDisabled, nil)
+	}
+
+	var parent *Comment
+	if request.ParentId != "" {
+		if parent, response = s.getParent(ctx, request.ParentId, contentId); parent == nil {
+			return response
+		}
+	}
+
+	comment := Comment{
+		Id:        utils.NewGuid(),
+		ContentId: contentId,
+		UserId:    viewer.UserId,
+		ParentId:  request.ParentId,
+		Text:      request.Text,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := s.repository.Create(ctx, comment, s.settings.RateLimit, comment.CreatedAt.Add(-s.settings.RateWindow), commentedMessage(item, &comment, parent))
+	if errors.Is(err, errRateLimited) {
+		return api.NewError(ErrRateLimit, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not create comment", slog.String("error", err.Error()), slog.String("contentId", contentId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapCommentToDto(&comment))
+}
+
+func (s *service) GetByFilter(ctx context.Context, filter ListFilter, viewer visibility.Viewer) api.AppResponse {
+	if err := validateFilter(filter); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	if filter.ParentId != "" {
+		// replies of a deleted comment are still listed
+		parent, err := s.repository.GetById(ctx, filter.ParentId)
+		if err != nil {
+			s.logger.Error("could not get comment", slog.String("error", err.Error()), slog.String("id", filter.ParentId))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		if parent == nil {
+			return api.NewError(ErrNotFound, nil)
+		}
+
+		filter.ContentId = parent.ContentId
+	}
+
+	item, response := s.getContent(ctx, filter.ContentId, viewer)
+	if item == nil {
+		return response
+	}
+
+	filter.ViewerId = viewer.UserId
+	filter.Moderator = viewer.UserId != "" && viewer.UserId == item.UserId
+
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	list, err := s.repository.Query(ctx, filter, page)
+	if err != nil {
+		s.logger.Error("could not get comments", slog.String("error", err.Error()), slog.String("contentId", filter.ContentId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, commentKey)
+
+	return api.NewOk(Success, pagination.PageDto[*CommentDto]{
+		Items:      MapCommentSliceToDto(list),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
+
+func (s *service) Update(ctx context.Context, id string, request UpdateCommentRequest, userId string) api.AppResponse {
+	if err := validateText(request.Text, s.settings.MaxLength); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	comment, response := s.getComment(ctx, id)
+	if comment == nil {
+		return response
+	}
+
+	if comment.UserId != userId {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	comment.Text = request.Text
+	comment.EditedAt = time.Now().UTC()
+
+	if err := s.repository.Update(ctx, id, comment.Text, comment.EditedAt); err != nil {
+		s.logger.Error("could not update comment", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapCommentToDto(comment))
+}
+
+func (s *service) Delete(ctx context.Context, id string, userId string) api.AppResponse {
+	comment, response := s.getComment(ctx, id)
+	if comment == nil {
+		return response
+	}
+
+	if comment.UserId != userId {
+		if response, ok := s.checkModerator(ctx, comment.ContentId, userId); !ok {
+			return response
+		}
+	}
+
+	if err := s.repository.Delete(ctx, id, time.Now().UTC()); err != nil {
+		s.logger.Error("could not delete comment", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) Hide(ctx context.Context, id string, request HideCommentRequest, userId string) api.AppResponse {
+	comment, response := s.getComment(ctx, id)
+	if comment == nil {
+		return response
+	}
+
+	if response, ok := s.checkModerator(ctx, comment.ContentId, userId); !ok {
+		return response
+	}
+
+	if err := s.repository.SetHidden(ctx, id, request.Hidden); err != nil {
+		s.logger.Error("could not hide comment", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	comment.Hidden = request.Hidden
+
+	return api.NewOk(Success, MapCommentToDto(comment))
+}
+
+func (s *service) SetSettings(ctx context.Context, contentId string, request CommentSettingsRequest, userId string) api.AppResponse {
+	if response, ok := s.checkModerator(ctx, contentId, userId); !ok {
+		return response
+	}
+
+	if err := s.repository.SetEnabled(ctx, contentId, request.Enabled); err != nil {
+		s.logger.Error("could not change comment settings", slog.String("error", err.Error()), slog.String("contentId", contentId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+// commentedMessage notifies the content owner and the author of the parent comment, it is saved with the comment
+func commentedMessage(item *content.ContentDto, comment *Comment, parent *Comment) outbox.Message {
+	event := ContentCommentedEvent{
+		CommentId:   comment.Id,
+		ContentId:   item.Id,
+		ContentName: item.DisplayName,
+		OwnerId:     item.UserId,
+		UserId:      comment.UserId,
+		Text:        comment.Text,
+		CreatedAt:   comment.CreatedAt,
+	}
+
+	if parent != nil {
+		event.ParentId = parent.Id
+		event.ParentUserId = parent.UserId
+	}
+
+	return outbox.Message{Topic: ContentCommentedTopic, Key: item.Id, Payload: event}
+}
+
+// getContent returns the content item the viewer may read, otherwise nil and the error response of the content service
+func (s *service) getContent(ctx context.Context, id string, viewer visibility.Viewer) (*content.ContentDto, api.AppResponse) {
+	response := s.contents.GetById(ctx, id, viewer)
+	if !response.Ok() {
+		return nil, response
+	}
+
+	item, ok := response.Data.(content.ContentDto)
+	if !ok {
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	return &item, response
+}
+
+// getComment returns the not deleted comment, otherwise nil and an error response
+func (s *service) getComment(ctx context.Context, id string) (*Comment, api.AppResponse) {
+	if err := validateId(id); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	comment, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get comment", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if comment == nil || !comment.DeletedAt.IsZero() {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	return comment, api.AppResponse{}
+}
+
+// getParent returns the top level comment of the item the reply is written to
+func (s *service) getParent(ctx context.Context, id string, contentId string) (*Comment, api.AppResponse) {
+	parent, response := s.getComment(ctx, id)
+	if parent == nil {
+		return nil, response
+	}
+
+	errs := &api.ValidationErrors{}
+
+	switch {
+	case parent.ContentId != contentId:
+		errs.Add("parentId", "comment not found")
+	case parent.ParentId != "":
+		errs.Add("parentId", "replies to replies are not allowed")
+	}
+
+	if !errs.Ok() {
+		return nil, api.NewError(ErrValidation, errs)
+	}
+
+	return parent, api.AppResponse{}
+}
+
+// checkModerator ensures the user owns the content item, so the user may moderate its comments
+func (s *service) checkModerator(ctx context.Context, contentId string, userId string) (api.AppResponse, bool) {
+	item, response := s.getContent(ctx, contentId, visibility.Viewer{UserId: userId})
+	if item == nil {
+		return response, false
+	}
+
+	if item.UserId != userId {
+		return api.NewError(ErrForbidden, nil), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+func commentKey(item *Comment) pagination.Key {
+	return pagination.Key{CreatedAt: item.CreatedAt, Id: item.Id}
+}
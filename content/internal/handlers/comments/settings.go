@@ -0,0 +1,28 @@
+package comments
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// MaxLength is the maximum length of a comment in characters
+	MaxLength int
+	// RateLimit is the number of comments a user may write within RateWindow
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+const (
+	defaultMaxLength         = 2000
+	defaultRateLimit         = 10
+	defaultRateWindowSeconds = 60
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		MaxLength:  config.MustGetInt("COMMENTS__MAX_LENGTH", defaultMaxLength),
+		RateLimit:  config.MustGetInt("COMMENTS__RATE_LIMIT", defaultRateLimit),
+		RateWindow: time.Duration(config.MustGetInt("COMMENTS__RATE_WINDOW_SECONDS", defaultRateWindowSeconds)) * time.Second,
+	}
+}
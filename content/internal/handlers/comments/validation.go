@@ -0,0 +1,56 @@
+package comments
+
+import (
+	"strings"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+func validateText(text string, maxLength int) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if strings.TrimSpace(text) == "" {
+		errs.Add("text", "is required")
+	}
+
+	if len([]rune(text)) > maxLength {
+		errs.Add("text", "is too long")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateFilter(filter ListFilter) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if filter.ContentId == "" && filter.ParentId == "" {
+		errs.Add("contentId", "is required")
+	}
+
+	if filter.Limit < 0 {
+		errs.Add("limit", "must be positive")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateId(id string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+	if id == "" {
+		errs.Add("id", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
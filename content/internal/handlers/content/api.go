@@ -1,7 +1,14 @@
 package content
 
 import (
+	"content/internal/handlers/media"
+	"content/internal/handlers/shares"
+	"content/internal/lib/handlers"
+	"encoding/json"
+	"hash/fnv"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/flores666/profileshare-lib/api"
 
@@ -13,6 +20,8 @@ const (
 	basePath      = "/api/content"
 	errValidation = "Ошибка проверки данных"
 	errMissingId  = "Отсутствует id"
+	// anyVersion is sent as If-Match: *, the change is based on the current version of the item
+	anyVersion = -1
 )
 
 type Handler struct {
@@ -28,14 +37,26 @@ func NewContentHandler(service Service) *Handler {
 }
 
 func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
-	r.Get(basePath+"/{id}", h.getById)
-	r.Get(basePath, h.getByFilter)
+	r.Group(func(r chi.Router) {
+		r.Use(handlers.OptionalAuth(authMiddleware))
+		r.Get(basePath+"/{id}", h.getById)
+		r.Get(basePath, h.getByFilter)
+	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware)
 		r.Post(basePath, h.create)
 		r.Put(basePath, h.update)
 		r.Delete(basePath+"/{id}", h.delete)
+		r.Get(basePath+"/trash", h.getTrash)
+		r.Get(basePath+"/feed", h.getFeed)
+		r.Delete(basePath+"/trash", h.emptyTrash)
+		r.Delete(basePath+"/trash/{id}", h.deletePermanently)
+		r.Post(basePath+"/{id}/restore", h.restore)
+		r.Get(basePath+"/{id}/revisions", h.getRevisions)
+		r.Post(basePath+"/{id}/revisions/{number}/restore", h.restoreRevision)
+		r.Put(basePath+"/{id}/reaction", h.react)
+		r.Delete(basePath+"/{id}/reaction", h.unreact)
 	})
 }
 
@@ -46,7 +67,18 @@ func (h *Handler) getById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := h.service.GetById(r.Context(), id)
+	response := h.service.GetById(r.Context(), id, handlers.GetViewer(r))
+
+	if item, ok := response.Data.(ContentDto); ok && response.Ok() {
+		tag := etag(item)
+		w.Header().Set("ETag", tag)
+
+		if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && matchesETag(noneMatch, tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	writeResponse(w, r, response)
 }
 
@@ -60,12 +92,19 @@ func (h *Handler) getByFilter(w http.ResponseWriter, r *http.Request) {
 	if filter.UserId == "" {
 		err.Add("userId", "поле обязательное")
 	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if value, convErr := strconv.Atoi(limit); convErr != nil {
+			err.Add("limit", "должно быть числом")
+		} else {
+			filter.Limit = value
+		}
+	}
 	if !err.Ok() {
 		respond(w, r, http.StatusBadRequest, api.NewError(errValidation, err))
 		return
 	}
 
-	response := h.service.GetByFilter(r.Context(), filter)
+	response := h.service.GetByFilter(r.Context(), filter, handlers.GetViewer(r))
 	writeResponse(w, r, response)
 }
 
@@ -87,7 +126,28 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if request.Version != nil && *request.Version <= 0 {
+		errs := &api.ValidationErrors{}
+		errs.Add("version", "должно быть положительным числом")
+		respond(w, r, http.StatusBadRequest, api.NewError(errValidation, errs))
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		version, ok := parseETag(ifMatch)
+		if !ok {
+			respond(w, r, http.StatusPreconditionFailed, api.NewError(ErrVersion, nil))
+			return
+		}
+
+		request.Version = &version
+	}
+
 	response := h.service.Update(r.Context(), request, getUserId(r))
+	if item, ok := response.Data.(ContentDto); ok && response.Ok() {
+		w.Header().Set("ETag", etag(item))
+	}
+
 	writeResponse(w, r, response)
 }
 
@@ -98,18 +158,134 @@ func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := h.service.SafeDelete(r.Context(), id, getUserId(r))
+	var version *int
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		value, ok := parseETag(ifMatch)
+		if !ok {
+			respond(w, r, http.StatusPreconditionFailed, api.NewError(ErrVersion, nil))
+			return
+		}
+
+		version = &value
+	}
+
+	response := h.service.SafeDelete(r.Context(), id, version, getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) getTrash(w http.ResponseWriter, r *http.Request) {
+	filter := TrashFilter{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			errs := &api.ValidationErrors{}
+			errs.Add("limit", "должно быть положительным числом")
+			respond(w, r, http.StatusBadRequest, api.NewError(errValidation, errs))
+			return
+		}
+
+		filter.Limit = value
+	}
+
+	response := h.service.GetTrash(r.Context(), filter, getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) getFeed(w http.ResponseWriter, r *http.Request) {
+	filter := FeedFilter{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			errs := &api.ValidationErrors{}
+			errs.Add("limit", "должно быть положительным числом")
+			respond(w, r, http.StatusBadRequest, api.NewError(errValidation, errs))
+			return
+		}
+
+		filter.Limit = value
+	}
+
+	response := h.service.GetFeed(r.Context(), filter, getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) restore(w http.ResponseWriter, r *http.Request) {
+	response := h.service.Restore(r.Context(), chi.URLParam(r, "id"), getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) deletePermanently(w http.ResponseWriter, r *http.Request) {
+	response := h.service.DeletePermanently(r.Context(), chi.URLParam(r, "id"), getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) emptyTrash(w http.ResponseWriter, r *http.Request) {
+	response := h.service.EmptyTrash(r.Context(), getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) getRevisions(w http.ResponseWriter, r *http.Request) {
+	response := h.service.GetRevisions(r.Context(), chi.URLParam(r, "id"), getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) restoreRevision(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil || number <= 0 {
+		errs := &api.ValidationErrors{}
+		errs.Add("number", "должно быть положительным числом")
+		respond(w, r, http.StatusBadRequest, api.NewError(errValidation, errs))
+		return
+	}
+
+	response := h.service.RestoreRevision(r.Context(), chi.URLParam(r, "id"), number, getUserId(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) react(w http.ResponseWriter, r *http.Request) {
+	var request ReactRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	response := h.service.React(r.Context(), chi.URLParam(r, "id"), request, handlers.GetViewer(r))
+	writeResponse(w, r, response)
+}
+
+func (h *Handler) unreact(w http.ResponseWriter, r *http.Request) {
+	response := h.service.Unreact(r.Context(), chi.URLParam(r, "id"), handlers.GetViewer(r))
 	writeResponse(w, r, response)
 }
 
 func getFilter(r *http.Request) Filter {
 	return Filter{
-		UserId:   r.URL.Query().Get("userId"),
-		Search:   r.URL.Query().Get("search"),
-		FolderId: r.URL.Query().Get("folderId"),
+		UserId:             r.URL.Query().Get("userId"),
+		Search:             r.URL.Query().Get("search"),
+		FolderId:           r.URL.Query().Get("folderId"),
+		IncludeDescendants: r.URL.Query().Get("includeDescendants") == "true",
+		Fuzzy:              r.URL.Query().Get("fuzzy") == "true",
+		Tags:               splitList(r.URL.Query().Get("tags")),
+		AllTags:            splitList(r.URL.Query().Get("allTags")),
+		Cursor:             r.URL.Query().Get("cursor"),
 	}
 }
 
+// splitList splits the comma separated query parameter
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
 func respond(w http.ResponseWriter, r *http.Request, status int, response api.AppResponse) {
 	render.Status(r, status)
 	render.JSON(w, r, response)
@@ -127,13 +303,76 @@ func writeResponse(w http.ResponseWriter, r *http.Request, resp api.AppResponse)
 	}
 
 	switch resp.Message {
-	case ErrForbidden:
+	case ErrForbidden, ErrImpersonate:
 		render.Status(r, http.StatusForbidden)
+	case ErrNotFound, ErrNoRevision, shares.ErrLinkInvalid:
+		render.Status(r, http.StatusNotFound)
+	case shares.ErrPassword:
+		render.Status(r, http.StatusUnauthorized)
+	case shares.ErrAttempts:
+		render.Status(r, http.StatusTooManyRequests)
 	case ErrValidation:
 		render.Status(r, http.StatusBadRequest)
+	case ErrNoVersion:
+		render.Status(r, http.StatusPreconditionRequired)
+	case ErrVersion:
+		render.Status(r, http.StatusPreconditionFailed)
 	default:
 		render.Status(r, http.StatusInternalServerError)
 	}
 
 	render.JSON(w, r, resp)
 }
+
+// etag is the weak entity tag of the item as seen by the viewer: besides the version it covers
+// reaction counts, the reaction of the viewer, fields filled by background workers, which do not change the version,
+// and signed download links, so cached items are not revalidated after their links expire
+func etag(item ContentDto) string {
+	state, _ := json.Marshal(struct {
+		Reactions  map[string]int       `json:"r"`
+		Reaction   string               `json:"v"`
+		Blocked    bool                 `json:"b"`
+		MediaUrl   string               `json:"m"`
+		Blurhash   string               `json:"h"`
+		Thumbnails []media.ThumbnailDto `json:"t"`
+		Preview    *LinkPreview         `json:"p"`
+	}{item.Reactions, item.Reaction, item.Blocked, item.MediaUrl, item.Blurhash, item.Thumbnails, item.Preview})
+
+	hash := fnv.New64a()
+	_, _ = hash.Write(state)
+
+	return `W/"` + strconv.Itoa(item.Version) + "-" + strconv.FormatUint(hash.Sum64(), 36) + `"`
+}
+
+// parseETag reads the version from a single entity tag, weak tags and tags without the state hash are accepted as well,
+// "*" matches any version
+func parseETag(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "*" {
+		return anyVersion, true
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, false
+	}
+
+	version, _, _ := strings.Cut(value[1:len(value)-1], "-")
+
+	result, err := strconv.Atoi(version)
+	return result, err == nil
+}
+
+// matchesETag reports whether If-None-Match contains "*" or the tag, tags are compared weakly
+func matchesETag(header string, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
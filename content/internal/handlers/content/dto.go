@@ -1,24 +1,74 @@
 package content
 
+import (
+	"content/internal/handlers/media"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 type CreateContentRequest struct {
 	DisplayName string `json:"displayName" validate:"required"`
 	Text        string `json:"text,omitempty"`
-	MediaUrl    string `json:"mediaUrl" validate:"required"`
-	Type        string `json:"type" validate:"required"`
-	FolderId    string `json:"folderId" validate:"required"`
+	// Format of the text is plain or markdown, plain by default
+	Format string `json:"format,omitempty"`
+	// MediaId and MediaUrl are allowed or required depending on the content type
+	MediaId  string `json:"mediaId,omitempty"`
+	MediaUrl string `json:"mediaUrl,omitempty"`
+	Type     string `json:"type" validate:"required"`
+	FolderId string `json:"folderId" validate:"required"`
+	// Visibility defaults to the visibility of the folder
+	Visibility string   `json:"visibility,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
 }
 
 type UpdateContentRequest struct {
 	Id          string  `json:"id" validate:"required"`
 	DisplayName *string `json:"displayName,omitempty"`
 	Text        *string `json:"text,omitempty"`
-	MediaUrl    *string `json:"mediaUrl,omitempty"`
+	Format      *string `json:"format,omitempty"`
+	MediaId     *string `json:"mediaId,omitempty"`
+	Visibility  *string `json:"visibility,omitempty"`
+	// Tags replace all tags of the item when set, an empty list removes them
+	Tags *[]string `json:"tags,omitempty"`
+	// Version of the item the change is based on, the If-Match header may be sent instead
+	Version *int `json:"version,omitempty"`
+}
+
+type ReactRequest struct {
+	Reaction string `json:"reaction" validate:"required"`
 }
 
 type Filter struct {
 	UserId   string
 	Search   string
 	FolderId string
+	// IncludeDescendants selects content of FolderId and all its nested folders
+	IncludeDescendants bool
+	// Fuzzy also matches display names similar to Search to tolerate typos
+	Fuzzy bool
+	// Tags selects items marked with any of the tags, AllTags with all of them
+	Tags    []string
+	AllTags []string
+	// Cursor is an opaque position returned as nextCursor or prevCursor of the previous page
+	Cursor string
+	Limit  int
+	// ViewerId is the user reading the list, items hidden from the viewer are skipped
+	ViewerId string
+	// Granted is set when a share link grants access to every item of the folder
+	Granted bool
+}
+
+// FeedFilter selects a page of the feed of the user
+type FeedFilter struct {
+	Cursor string
+	Limit  int
+}
+
+// TrashFilter selects a page of deleted items of the user
+type TrashFilter struct {
+	Cursor string
+	Limit  int
 }
 
 type ContentDto struct {
@@ -26,43 +76,168 @@ type ContentDto struct {
 	UserId      string `json:"userId"`
 	DisplayName string `json:"display_name"`
 	Text        string `json:"text"`
-	MediaUrl    string `json:"media_url"`
-	Type        string `json:"type"`
-	FolderId    string `json:"folder_id"`
+	// Format is the format of the text source, Html is the text rendered to sanitized HTML
+	Format     string    `json:"format"`
+	Html       string    `json:"html"`
+	MediaId    string    `json:"media_id,omitempty"`
+	MediaUrl   string    `json:"media_url"`
+	Type       string    `json:"type"`
+	FolderId   string    `json:"folder_id"`
+	Visibility string    `json:"visibility"`
+	Tags       []string  `json:"tags"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Reactions are counts of reactions, Reaction is the reaction of the caller
+	Reactions       map[string]int `json:"reactions"`
+	Reaction        string         `json:"reaction,omitempty"`
+	CommentsEnabled bool           `json:"commentsEnabled"`
+	// DeletedAt is set for items in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Blocked is set for items hidden by moderators, they are returned only to the owner
+	Blocked bool `json:"blocked,omitempty"`
+	// Width, Height, Blurhash and Thumbnails are set for processed photos
+	Width      int                  `json:"width,omitempty"`
+	Height     int                  `json:"height,omitempty"`
+	Blurhash   string               `json:"blurhash,omitempty"`
+	Thumbnails []media.ThumbnailDto `json:"thumbnails,omitempty"`
+	// Preview is set for link items once the page is unfurled
+	Preview *LinkPreview `json:"preview,omitempty"`
+	// Rank and Highlight are returned only for search results
+	Rank      float64       `json:"rank,omitempty"`
+	Highlight *HighlightDto `json:"highlight,omitempty"`
+}
+
+type ReactionsDto struct {
+	Reactions map[string]int `json:"reactions"`
+	Reaction  string         `json:"reaction,omitempty"`
+}
+
+type PurgeResultDto struct {
+	Deleted int64 `json:"deleted"`
 }
 
-func MapContentToDto(model *Content) ContentDto {
+// HighlightDto contains html escaped fragments with search terms wrapped in <mark> tags
+type HighlightDto struct {
+	DisplayName string `json:"displayName"`
+	Text        string `json:"text,omitempty"`
+}
+
+func MapContentToDto(model *Content, signer *media.Signer) ContentDto {
 	if model == nil {
 		return ContentDto{}
 	}
 
-	return ContentDto{
-		Id:          model.Id,
-		UserId:      model.UserId,
-		DisplayName: model.DisplayName,
-		Text:        model.Text,
-		MediaUrl:    model.MediaUrl,
-		Type:        model.Type,
-		FolderId:    model.FolderId,
+	dto := ContentDto{
+		Id:              model.Id,
+		UserId:          model.UserId,
+		DisplayName:     model.DisplayName,
+		Text:            model.Text,
+		Format:          model.Format,
+		Html:            model.TextHtml,
+		MediaId:         model.MediaId,
+		MediaUrl:        signer.Resolve(model.MediaId, model.MediaUrl),
+		Type:            model.Type,
+		FolderId:        model.FolderId,
+		Visibility:      model.Visibility,
+		Tags:            make([]string, 0),
+		Reactions:       parseReactionCounts(model.ReactionCounts),
+		Reaction:        model.Reaction,
+		CommentsEnabled: model.CommentsEnabled,
+		Version:         model.Version,
+		CreatedAt:       model.CreatedAt,
+		Blocked:         !model.BlockedAt.IsZero(),
+		Width:           model.Width,
+		Height:          model.Height,
+		Blurhash:        model.Blurhash,
+		Thumbnails:      media.MapThumbnailsToDto(model.MediaId, media.ParseDerivatives(model.Thumbnails), signer),
+		Preview:         ParseLinkPreview(model.LinkPreview),
 	}
+
+	if model.Tags != "" {
+		dto.Tags = strings.Split(model.Tags, ",")
+	}
+
+	if !model.DeletedAt.IsZero() {
+		deletedAt := model.DeletedAt
+		dto.DeletedAt = &deletedAt
+	}
+
+	if model.DisplayNameHighlight != "" {
+		dto.Rank = model.Rank
+		dto.Highlight = &HighlightDto{
+			DisplayName: model.DisplayNameHighlight,
+			Text:        model.TextHighlight,
+		}
+	}
+
+	return dto
 }
 
-func MapContentSliceToDto(content []*Content) []*ContentDto {
+func MapContentSliceToDto(content []*Content, signer *media.Signer) []*ContentDto {
 	if content == nil {
 		return make([]*ContentDto, 0)
 	}
 
-	result := make([]*ContentDto, len(content))
+	result := make([]*ContentDto, 0, len(content))
 	for _, model := range content {
-		result = append(result, &ContentDto{
-			Id:          model.Id,
-			UserId:      model.UserId,
-			DisplayName: model.DisplayName,
-			Text:        model.Text,
-			MediaUrl:    model.MediaUrl,
-			Type:        model.Type,
-			FolderId:    model.FolderId,
-		})
+		dto := MapContentToDto(model, signer)
+		result = append(result, &dto)
+	}
+
+	return result
+}
+
+func MapReactionsToDto(counts string, reaction string) ReactionsDto {
+	return ReactionsDto{
+		Reactions: parseReactionCounts(counts),
+		Reaction:  reaction,
+	}
+}
+
+// parseReactionCounts reads counts maintained by the repository, so the value is always a valid json object or empty
+func parseReactionCounts(value string) map[string]int {
+	counts := make(map[string]int)
+	if value != "" {
+		_ = json.Unmarshal([]byte(value), &counts)
+	}
+
+	return counts
+}
+
+type RevisionDto struct {
+	Number       int                    `json:"number"`
+	UserId       string                 `json:"userId"`
+	RestoredFrom int                    `json:"restoredFrom,omitempty"`
+	Changes      map[string]FieldChange `json:"changes"`
+	State        RevisionState          `json:"state"`
+	CreatedAt    time.Time              `json:"createdAt"`
+}
+
+func MapRevisionToDto(model *Revision) RevisionDto {
+	if model == nil {
+		return RevisionDto{}
+	}
+
+	dto := RevisionDto{
+		Number:       model.Number,
+		UserId:       model.UserId,
+		RestoredFrom: model.RestoredFrom,
+		Changes:      make(map[string]FieldChange),
+		CreatedAt:    model.CreatedAt,
+	}
+
+	// revisions are written by the repository, so values are always valid json
+	_ = json.Unmarshal([]byte(model.Changes), &dto.Changes)
+	_ = json.Unmarshal([]byte(model.Snapshot), &dto.State)
+
+	return dto
+}
+
+func MapRevisionSliceToDto(revisions []*Revision) []*RevisionDto {
+	result := make([]*RevisionDto, 0, len(revisions))
+	for _, model := range revisions {
+		dto := MapRevisionToDto(model)
+		result = append(result, &dto)
 	}
 
 	return result
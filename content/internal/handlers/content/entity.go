@@ -1,25 +1,143 @@
 package content
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Content represents content entity in database
 type Content struct {
-	Id          string    `db:"id"`
-	UserId      string    `db:"user_id"`
-	DisplayName string    `db:"display_name"`
-	Text        string    `db:"text"`
-	MediaUrl    string    `db:"media_url"`
-	Type        string    `db:"type"`
-	FolderId    string    `db:"folder_id"`
-	CreatedAt   time.Time `db:"created_at"`
-	DeletedAt   time.Time `db:"deleted_at"`
+	Id          string `db:"id"`
+	UserId      string `db:"user_id"`
+	DisplayName string `db:"display_name"`
+	Text        string `db:"text"`
+	// Format is the format of Text, TextHtml is Text rendered to sanitized HTML
+	Format     string `db:"text_format"`
+	TextHtml   string `db:"text_html"`
+	MediaUrl   string `db:"media_url"`
+	MediaId    string `db:"media_id"`
+	Type       string `db:"type"`
+	FolderId   string `db:"folder_id"`
+	Visibility string `db:"visibility"`
+	// Tags are normalized tag names joined with commas
+	Tags string `db:"tags"`
+	// ReactionCounts is a json object of reactions with their counts,
+	// Reaction is the reaction of the viewer selected along with the item
+	ReactionCounts string `db:"reaction_counts"`
+	Reaction       string `db:"reaction"`
+	// CommentsEnabled is switched off by the owner to forbid new comments
+	CommentsEnabled bool `db:"comments_enabled"`
+	// Version is incremented by every change and used as ETag
+	Version   int       `db:"version"`
+	CreatedAt time.Time `db:"created_at"`
+	DeletedAt time.Time `db:"deleted_at"`
+	// BlockedAt is set when moderators hide the item, blocked items are shown only to the owner
+	BlockedAt time.Time `db:"blocked_at"`
+	// Width, Height, Blurhash and Thumbnails are copied from processed photos
+	Width      int    `db:"width"`
+	Height     int    `db:"height"`
+	Blurhash   string `db:"blurhash"`
+	Thumbnails string `db:"thumbnails"`
+	// LinkPreview is the unfurled page of link items as json, empty until the worker fetches it
+	LinkPreview string `db:"link_preview"`
+	// Rank and highlights are selected only by full text search
+	Rank                 float64 `db:"rank"`
+	DisplayNameHighlight string  `db:"display_name_highlight"`
+	TextHighlight        string  `db:"text_highlight"`
+}
+
+// LinkPreview is the page behind the url of a link item, unfurled by the background worker
+type LinkPreview struct {
+	Url         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+	ImageUrl    string `json:"imageUrl,omitempty"`
+	FaviconUrl  string `json:"faviconUrl,omitempty"`
+}
+
+func ParseLinkPreview(value string) *LinkPreview {
+	if value == "" {
+		return nil
+	}
+
+	var result LinkPreview
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return nil
+	}
+
+	return &result
 }
 
 type UpdateContent struct {
 	Id          string  `db:"id"`
 	DisplayName *string `db:"display_name"`
 	Text        *string `db:"text"`
-	MediaUrl    *string `db:"media_url"`
+	Format      *string `db:"text_format"`
+	// TextHtml is rendered by the service when the text or its format changes
+	TextHtml   *string `db:"text_html"`
+	MediaId    *string `db:"media_id"`
+	Visibility *string `db:"visibility"`
+	// Tags replace all tags of the item, normalized names are joined with commas
+	Tags *string `db:"-"`
+	// Version is the version the change is based on, the update fails if the item was changed since
+	Version int `db:"version"`
+}
+
+// Reaction is the reaction of the user to content, each user has at most one reaction per item
+type Reaction struct {
+	ContentId string    `db:"content_id"`
+	UserId    string    `db:"user_id"`
+	Reaction  string    `db:"reaction"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Revision is the state of content saved by an update with changes against the previous state
+type Revision struct {
+	Id        string `db:"id"`
+	ContentId string `db:"content_id"`
+	Number    int    `db:"number"`
+	// UserId is the author of the change
+	UserId string `db:"user_id"`
+	// Snapshot is RevisionState and Changes is a map of field names to FieldChange, both stored as json
+	Snapshot string `db:"snapshot"`
+	Changes  string `db:"changes"`
+	// RestoredFrom is the number of the revision restored by the change
+	RestoredFrom int       `db:"restored_from"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// RevisionState contains fields of content kept by revisions
+type RevisionState struct {
+	DisplayName string `db:"display_name" json:"displayName"`
+	Text        string `db:"text" json:"text"`
+	Format      string `db:"text_format" json:"format,omitempty"`
+	MediaId     string `db:"media_id" json:"mediaId,omitempty"`
+	MediaUrl    string `db:"media_url" json:"mediaUrl,omitempty"`
+	Visibility  string `db:"visibility" json:"visibility"`
+}
+
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Diff returns changes of the fields between the states
+func (s RevisionState) Diff(next RevisionState) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+
+	add := func(name string, before string, after string) {
+		if before != after {
+			changes[name] = FieldChange{Old: before, New: after}
+		}
+	}
+
+	add("displayName", s.DisplayName, next.DisplayName)
+	add("text", s.Text, next.Text)
+	add("format", s.Format, next.Format)
+	add("mediaId", s.MediaId, next.MediaId)
+	add("mediaUrl", s.MediaUrl, next.MediaUrl)
+	add("visibility", s.Visibility, next.Visibility)
+
+	return changes
 }
@@ -0,0 +1,115 @@
+package content
+
+import (
+	"content/internal/outbox"
+	"strings"
+	"time"
+
+	"github.com/flores666/profileshare-lib/utils"
+)
+
+const (
+	ContentCreatedTopic  = "content.created"
+	ContentUpdatedTopic  = "content.updated"
+	ContentDeletedTopic  = "content.deleted"
+	ContentRestoredTopic = "content.restored"
+	ContentReactedTopic  = "content.reacted"
+)
+
+// ContentEventVersion is incremented on incompatible changes of ContentEvent and ContentSnapshot
+const ContentEventVersion = 1
+
+// ContentEvent is published on every lifecycle change of an item keyed by the item id,
+// so events of the item are delivered in order. Before is empty for created items, After for deleted ones.
+type ContentEvent struct {
+	EventId       string `json:"eventId"`
+	SchemaVersion int    `json:"schemaVersion"`
+	ContentId     string `json:"contentId"`
+	// UserId is the owner of the item, ActorId is the user who made the change, e.g. a moderator
+	UserId  string `json:"userId"`
+	ActorId string `json:"actorId"`
+	// Visibility is the visibility after the change, for deleted items the last one
+	Visibility string           `json:"visibility"`
+	Before     *ContentSnapshot `json:"before,omitempty"`
+	After      *ContentSnapshot `json:"after,omitempty"`
+	// CreatedAt is the time of the change
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ContentSnapshot is the state of the item published with lifecycle events
+type ContentSnapshot struct {
+	DisplayName     string   `json:"displayName"`
+	Text            string   `json:"text"`
+	Format          string   `json:"format"`
+	Type            string   `json:"type"`
+	MediaId         string   `json:"mediaId,omitempty"`
+	MediaUrl        string   `json:"mediaUrl,omitempty"`
+	Visibility      string   `json:"visibility"`
+	Tags            []string `json:"tags"`
+	CommentsEnabled bool     `json:"commentsEnabled"`
+	// Blocked items are hidden by moderators from everyone except the owner
+	Blocked bool `json:"blocked"`
+	Version int  `json:"version"`
+}
+
+// ContentReactedEvent is published when a user reacts to content of another user or changes the reaction
+type ContentReactedEvent struct {
+	ContentId string `json:"contentId"`
+	OwnerId   string `json:"ownerId"`
+	UserId    string `json:"userId"`
+	Reaction  string `json:"reaction"`
+	// PreviousReaction is set when the user changes the reaction
+	PreviousReaction string    `json:"previousReaction,omitempty"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+func newSnapshot(item *Content) *ContentSnapshot {
+	snapshot := &ContentSnapshot{
+		DisplayName:     item.DisplayName,
+		Text:            item.Text,
+		Format:          item.Format,
+		Type:            item.Type,
+		MediaId:         item.MediaId,
+		MediaUrl:        item.MediaUrl,
+		Visibility:      item.Visibility,
+		Tags:            make([]string, 0),
+		CommentsEnabled: item.CommentsEnabled,
+		Blocked:         !item.BlockedAt.IsZero(),
+		Version:         item.Version,
+	}
+
+	if item.Tags != "" {
+		snapshot.Tags = strings.Split(item.Tags, ",")
+	}
+
+	return snapshot
+}
+
+// LifecycleMessage returns the event of the change of the item from before to after for the outbox,
+// either state is nil for created and deleted items
+func LifecycleMessage(topic string, before *Content, after *Content, actorId string, now time.Time) outbox.Message {
+	event := ContentEvent{
+		EventId:       utils.NewGuid(),
+		SchemaVersion: ContentEventVersion,
+		ActorId:       actorId,
+		CreatedAt:     now,
+	}
+
+	for _, item := range []*Content{before, after} {
+		if item != nil {
+			event.ContentId = item.Id
+			event.UserId = item.UserId
+			event.Visibility = item.Visibility
+		}
+	}
+
+	if before != nil {
+		event.Before = newSnapshot(before)
+	}
+
+	if after != nil {
+		event.After = newSnapshot(after)
+	}
+
+	return outbox.Message{Topic: topic, Key: event.ContentId, Payload: event}
+}
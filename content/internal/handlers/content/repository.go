@@ -1,8 +1,14 @@
 package content
 
 import (
+	"content/internal/handlers/media"
+	"content/internal/lib/pagination"
+	"content/internal/lib/visibility"
+	"content/internal/outbox"
+	"content/internal/storage/postgresql"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"strings"
 	"time"
@@ -10,12 +16,71 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// errVersionConflict means the item was changed since the version the change is based on
+var errVersionConflict = errors.New("content version conflict")
+
+// errNotInTrash means the item was restored or purged concurrently
+var errNotInTrash = errors.New("content is not in the trash")
+
 type Repository interface {
-	Create(ctx context.Context, content Content) error
+	Create(ctx context.Context, content Content, messages ...outbox.Message) error
 	GetById(ctx context.Context, id string) (*Content, error)
-	Query(ctx context.Context, filter Filter) ([]*Content, error)
-	Update(ctx context.Context, model UpdateContent) error
-	SafeDelete(ctx context.Context, id string) error
+	Query(ctx context.Context, filter Filter, page pagination.Page) ([]*Content, error)
+	// Update changes the item and saves the new state as the revision, keeping at most maxRevisions latest ones
+	Update(ctx context.Context, model UpdateContent, revision Revision, maxRevisions int, messages ...outbox.Message) error
+	SafeDelete(ctx context.Context, id string, version int, messages ...outbox.Message) error
+	QueryTrash(ctx context.Context, userId string, page pagination.Page) ([]*Content, error)
+	// QueryFeed returns a page of public items of users followed by the user,
+	// either reading them from the followed users or from the materialized inbox of the user
+	QueryFeed(ctx context.Context, userId string, fromInbox bool, page pagination.Page) ([]*Content, error)
+	// Restore takes the item of the version out of the trash, errNotInTrash is returned if it was restored or changed since
+	Restore(ctx context.Context, id string, version int, messages ...outbox.Message) error
+	// DeletePermanently removes the item from the trash, its media is released for purge if no other item uses it
+	DeletePermanently(ctx context.Context, id string) (int64, error)
+	EmptyTrash(ctx context.Context, userId string) (int64, error)
+	// PurgeExpired permanently deletes a batch of items moved to the trash before the time
+	PurgeExpired(ctx context.Context, deletedBefore time.Time, limit int) (int64, error)
+	GetRevisions(ctx context.Context, contentId string) ([]*Revision, error)
+	GetRevision(ctx context.Context, contentId string, number int) (*Revision, error)
+	// React sets the reaction of the user and returns the previous reaction and the new counts of the item,
+	// notify is completed with the previous reaction and saved to the outbox when the reaction changes
+	React(ctx context.Context, reaction Reaction, notify *ContentReactedEvent) (previous string, counts string, err error)
+	// Unreact removes the reaction of the user and returns the new counts of the item
+	Unreact(ctx context.Context, contentId string, userId string) (string, error)
+	GetReaction(ctx context.Context, contentId string, userId string) (string, error)
+}
+
+const (
+	// tagsColumn selects tags of the item c joined with commas
+	tagsColumn = `COALESCE((
+			SELECT string_agg(t.name, ',' ORDER BY t.name)
+			FROM content.content_tags ct JOIN content.tags t ON t.id = ct.tag_id
+			WHERE ct.content_id = c.id
+		), '') AS tags`
+	searchRank = "ts_rank_cd(c.search_vector, s.query)"
+	// fuzzySearchRank lets items matched only by trigram similarity of display name compete with full text matches
+	fuzzySearchRank = "GREATEST(ts_rank_cd(c.search_vector, s.query), similarity(c.display_name, :search))"
+)
+
+// listColumns selects fields of the item c for lists, the reaction is of the user given by the named parameter
+func listColumns(userIdParam string) string {
+	return `
+			c.id, c.user_id, c.display_name, c.text, c.text_format, c.text_html,
+			COALESCE(c.media_url, '') AS media_url, COALESCE(CAST(c.media_id AS text), '') AS media_id, c.type, c.visibility,
+			COALESCE(c.width, 0) AS width, COALESCE(c.height, 0) AS height, COALESCE(c.blurhash, '') AS blurhash,
+			COALESCE(CAST(c.thumbnails AS text), '') AS thumbnails, COALESCE(CAST(c.link_preview AS text), '') AS link_preview,
+			` + tagsColumn + `,
+			CAST(c.reaction_counts AS text) AS reaction_counts, c.comments_enabled, ` + reactionColumn(userIdParam) + `,
+			c.version, COALESCE(c.deleted_at, make_timestamptz(1,1,1,0,0,0)) as deleted_at,
+			COALESCE(c.blocked_at, make_timestamptz(1,1,1,0,0,0)) AS blocked_at, c.created_at`
+}
+
+// reactionColumn selects the reaction of the user given by the named parameter to the item c
+func reactionColumn(userIdParam string) string {
+	return `COALESCE((
+			SELECT r.reaction FROM content.reactions r
+			WHERE r.content_id = c.id AND r.user_id = CAST(NULLIF(` + userIdParam + `, '') AS uuid)
+		), '') AS reaction`
 }
 
 type repository struct {
@@ -26,13 +91,19 @@ func NewRepository(db *sqlx.DB) Repository {
 	return &repository{db}
 }
 
-func (r *repository) Create(ctx context.Context, content Content) (err error) {
-	useTransaction := content.FolderId != ""
+func (r *repository) Create(ctx context.Context, content Content, messages ...outbox.Message) (err error) {
+	useTransaction := content.FolderId != "" || content.Tags != "" || len(messages) > 0
 
 	return r.exec(ctx, useTransaction, func(exec func(query string, args ...any) (sql.Result, error)) error {
-		insertContentQuery := `INSERT INTO content.content (id, user_id, display_name, text, media_url, type, created_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`
-
-		_, err = exec(insertContentQuery, content.Id, content.UserId, content.DisplayName, content.Text, content.MediaUrl, content.Type, content.CreatedAt)
+		// photo may be already processed when content is created
+		insertContentQuery := `
+			INSERT INTO content.content (id, user_id, display_name, text, text_format, text_html, media_id, media_url, type, visibility, created_at, width, height, blurhash, thumbnails)
+			SELECT $1,$2,$3,$4,$10,$11,m.id,NULLIF($9, ''),$6,$7,$8,m.width,m.height,m.blurhash,m.derivatives
+			FROM (SELECT 1) x
+			LEFT JOIN content.media m ON m.id = CAST(NULLIF($5, '') AS uuid)`
+
+		_, err = exec(insertContentQuery, content.Id, content.UserId, content.DisplayName, content.Text, content.MediaId, content.Type, content.Visibility, content.CreatedAt, content.MediaUrl,
+			content.Format, content.TextHtml)
 		if err == nil && content.FolderId != "" {
 			insertLinkQuery := `INSERT INTO content.folders_contents (folder_id, content_id, created_at) VALUES ($1,$2,$3)`
 
@@ -42,6 +113,14 @@ func (r *repository) Create(ctx context.Context, content Content) (err error) {
 				content.CreatedAt)
 		}
 
+		if err == nil && content.Tags != "" {
+			err = saveTags(exec, content.Id, content.Tags, content.CreatedAt)
+		}
+
+		if err == nil {
+			err = outbox.Write(exec, messages...)
+		}
+
 		return err
 	})
 }
@@ -52,11 +131,25 @@ func (r *repository) GetById(ctx context.Context, id string) (*Content, error) {
         user_id,
         display_name,
         text,
-        media_url,
+        text_format,
+        text_html,
+        COALESCE(media_url, '') AS media_url,
+        COALESCE(media_id::text, '') AS media_id,
         type,
+        visibility,
+        COALESCE(width, 0) AS width,
+        COALESCE(height, 0) AS height,
+        COALESCE(blurhash, '') AS blurhash,
+        COALESCE(thumbnails::text, '') AS thumbnails,
+        COALESCE(link_preview::text, '') AS link_preview,
+        ` + tagsColumn + `,
+        CAST(reaction_counts AS text) AS reaction_counts,
+        comments_enabled,
+        version,
         COALESCE(deleted_at, '0001-01-01 00:00:00+00') as deleted_at,
+        COALESCE(blocked_at, '0001-01-01 00:00:00+00') as blocked_at,
         created_at
-    FROM content.content WHERE id = $1`
+    FROM content.content c WHERE id = $1`
 
 	var content Content
 	err := r.db.GetContext(ctx, &content, query, id)
@@ -72,35 +165,109 @@ func (r *repository) GetById(ctx context.Context, id string) (*Content, error) {
 	return &content, nil
 }
 
-func (r *repository) Query(ctx context.Context, filter Filter) ([]*Content, error) {
-	query := `
-		SELECT
-			c.id, c.user_id, c.display_name, c.text,
-			c.media_url, c.type, COALESCE(c.deleted_at, make_timestamptz(1,1,1,0,0,0)) as deleted_at, c.created_at
-		FROM content.content c`
+func (r *repository) Query(ctx context.Context, filter Filter, page pagination.Page) ([]*Content, error) {
+	query := "SELECT" + listColumns(":viewer_id")
 
 	params := map[string]any{
-		"user_id": filter.UserId,
+		"user_id":   filter.UserId,
+		"viewer_id": filter.ViewerId,
 	}
 
-	if filter.FolderId != "" {
+	if filter.Search != "" {
+		var err error
+		rank := searchRank
+
+		if filter.Fuzzy {
+			rank = fuzzySearchRank
+		}
+
+		if page, err = page.RankedBy(rank); err != nil {
+			return nil, err
+		}
+
+		query += `,
+			` + rank + ` AS rank,
+			ts_headline('russian', ` + escapeHtml("c.display_name") + `, s.query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS display_name_highlight,
+			ts_headline('russian', ` + escapeHtml("COALESCE(c.text, '')") + `, s.query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2') AS text_highlight
+		FROM content.content c
+		CROSS JOIN (
+			SELECT websearch_to_tsquery('russian', :search) || websearch_to_tsquery('english', :search) AS query
+		) s`
+		params["search"] = filter.Search
+	} else {
+		query += `
+		FROM content.content c`
+	}
+
+	if filter.FolderId != "" && !filter.IncludeDescendants {
 		query += " JOIN content.folders_contents f on f.content_id = c.id"
 	}
 
-	query += " WHERE c.user_id = :user_id"
+	query += ` WHERE c.user_id = :user_id AND c.deleted_at IS NULL
+		AND (c.blocked_at IS NULL OR c.user_id = CAST(NULLIF(:viewer_id, '') AS uuid))`
 
 	if filter.FolderId != "" {
-		query += " AND f.folder_id = :folder_id"
 		params["folder_id"] = filter.FolderId
+
+		if filter.IncludeDescendants {
+			// item linked to several folders of the subtree must be returned once
+			query += ` AND c.id IN (
+				SELECT fc.content_id
+				FROM content.folders_contents fc
+				WHERE fc.folder_id IN (
+					WITH RECURSIVE subtree AS (
+						SELECT id FROM content.folders WHERE id = :folder_id
+						UNION ALL
+						SELECT fd.id FROM content.folders fd JOIN subtree s ON fd.parent_id = s.id
+					)
+					SELECT id FROM subtree
+				)
+			)`
+		} else {
+			query += " AND f.folder_id = :folder_id"
+		}
+	}
+
+	// tag filters start from the tag name index and the (tag_id, content_id) index of the links
+	if len(filter.Tags) > 0 {
+		query += ` AND c.id IN (
+			SELECT ct.content_id
+			FROM content.content_tags ct JOIN content.tags t ON t.id = ct.tag_id
+			WHERE t.name = ANY(string_to_array(:tags, ','))
+		)`
+		params["tags"] = strings.Join(filter.Tags, ",")
+	}
+
+	if len(filter.AllTags) > 0 {
+		query += ` AND c.id IN (
+			SELECT ct.content_id
+			FROM content.content_tags ct JOIN content.tags t ON t.id = ct.tag_id
+			WHERE t.name = ANY(string_to_array(:all_tags, ','))
+			GROUP BY ct.content_id
+			HAVING COUNT(*) = :all_tags_count
+		)`
+		params["all_tags"] = strings.Join(filter.AllTags, ",")
+		params["all_tags_count"] = len(filter.AllTags)
+	}
+
+	if !filter.Granted {
+		query += " AND " + visibility.ListCondition("c.user_id", "c.visibility")
 	}
 
 	if filter.Search != "" {
-		query += " AND (c.text ILIKE :search OR c.display_name ILIKE :search)"
-		params["search"] = "%" + filter.Search + "%"
+		if filter.Fuzzy {
+			query += " AND (c.search_vector @@ s.query OR c.display_name % :search)"
+		} else {
+			query += " AND c.search_vector @@ s.query"
+		}
+	}
+
+	if where := page.Where("c.created_at", "c.id"); where != "" {
+		query += " AND " + where
 	}
 
-	//todo: cursor pagination
-	query += " ORDER BY c.created_at DESC LIMIT 20"
+	query += page.OrderBy("c.created_at", "c.id")
+	page.AddParams(params)
 
 	query, args, err := sqlx.Named(query, params)
 	if err != nil {
@@ -119,17 +286,18 @@ func (r *repository) Query(ctx context.Context, filter Filter) ([]*Content, erro
 	return result, nil
 }
 
-func (r *repository) Update(ctx context.Context, model UpdateContent) error {
+func (r *repository) Update(ctx context.Context, model UpdateContent, revision Revision, maxRevisions int, messages ...outbox.Message) error {
 	if model.Id == "" {
 		return errors.New("id is required")
 	}
 
 	query := "UPDATE content.content SET "
 	params := map[string]any{
-		"id": model.Id,
+		"id":      model.Id,
+		"version": model.Version,
 	}
 
-	var sets []string
+	sets := []string{"version = version + 1"}
 
 	if model.DisplayName != nil {
 		sets = append(sets, "display_name = :display_name")
@@ -139,57 +307,489 @@ func (r *repository) Update(ctx context.Context, model UpdateContent) error {
 		sets = append(sets, "text = :text")
 		params["text"] = *model.Text
 	}
-	if model.MediaUrl != nil {
-		sets = append(sets, "media_url = :media_url")
-		params["media_url"] = *model.MediaUrl
+	if model.Format != nil {
+		sets = append(sets, "text_format = :text_format")
+		params["text_format"] = *model.Format
+	}
+	if model.TextHtml != nil {
+		sets = append(sets, "text_html = :text_html")
+		params["text_html"] = *model.TextHtml
+	}
+	if model.MediaId != nil {
+		// uploaded media replaces links stored before uploads existed
+		sets = append(sets,
+			"media_id = CAST(:media_id AS uuid)",
+			"media_url = NULL",
+			"(width, height, blurhash, thumbnails) = (SELECT width, height, blurhash, derivatives FROM content.media WHERE id = CAST(:media_id AS uuid))")
+		params["media_id"] = *model.MediaId
+	}
+	if model.Visibility != nil {
+		sets = append(sets, "visibility = :visibility")
+		params["visibility"] = *model.Visibility
 	}
 
-	if len(sets) == 0 {
+	if len(sets) == 1 && model.Tags == nil {
 		return errors.New("nothing to update")
 	}
 
 	query += strings.Join(sets, ", ")
-	query += " WHERE id = :id"
+	query += " WHERE id = :id AND version = :version"
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return err
+	}
+
+	return postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		// the row lock serializes updates, so revision numbers do not collide
+		var before RevisionState
+		if err := tx.GetContext(ctx, &before, selectRevisionState+` FOR UPDATE`, model.Id); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return errVersionConflict
+		}
+
+		if model.Tags != nil {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM content.content_tags WHERE content_id = $1`, model.Id); err != nil {
+				return err
+			}
+
+			if err := saveTags(tx.Exec, model.Id, *model.Tags, time.Now().UTC()); err != nil {
+				return err
+			}
+		}
+
+		var after RevisionState
+		if err := tx.GetContext(ctx, &after, selectRevisionState, model.Id); err != nil {
+			return err
+		}
 
-	_, err := r.db.NamedExecContext(ctx, query, params)
+		if err := outbox.Write(tx.Exec, messages...); err != nil {
+			return err
+		}
+
+		changes := before.Diff(after)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		return saveRevision(ctx, tx, revision, before, after, changes, maxRevisions)
+	})
+}
+
+// saveTags links the item to the tags joined with commas, missing tags are created
+func saveTags(exec func(query string, args ...any) (sql.Result, error), contentId string, tags string, now time.Time) error {
+	if tags == "" {
+		return nil
+	}
+
+	query := `
+		INSERT INTO content.tags (id, name)
+		SELECT gen_random_uuid(), name FROM unnest(string_to_array($1, ',')) AS name
+		ON CONFLICT (name) DO NOTHING`
+
+	if _, err := exec(query, tags); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO content.content_tags (content_id, tag_id, created_at)
+		SELECT $1, t.id, $3
+		FROM content.tags t
+		WHERE t.name = ANY(string_to_array($2, ','))
+		ON CONFLICT (content_id, tag_id) DO NOTHING`
+
+	_, err := exec(query, contentId, tags, now)
 	return err
 }
 
-func (r *repository) SafeDelete(ctx context.Context, id string) error {
-	if id == "" {
-		return errors.New("id is required")
+// selectRevisionState selects fields of the content $1 kept by revisions
+const selectRevisionState = `
+	SELECT display_name, COALESCE(text, '') AS text, text_format, COALESCE(CAST(media_id AS text), '') AS media_id,
+		COALESCE(media_url, '') AS media_url, visibility
+	FROM content.content
+	WHERE id = $1`
+
+// saveRevision appends the revision, the state before the first update is saved as the initial revision of the owner
+func saveRevision(ctx context.Context, tx *sqlx.Tx, revision Revision, before RevisionState, after RevisionState, changes map[string]FieldChange, maxRevisions int) error {
+	query := `
+		INSERT INTO content.content_revisions (id, content_id, number, user_id, snapshot, changes, restored_from, created_at)
+		SELECT gen_random_uuid(), c.id, 1, c.user_id, CAST($1 AS jsonb), '{}', NULL, c.created_at
+		FROM content.content c
+		WHERE c.id = $2 AND NOT EXISTS (SELECT 1 FROM content.content_revisions WHERE content_id = c.id)`
+
+	initial, err := json.Marshal(before)
+	if err != nil {
+		return err
 	}
 
-	now := time.Now().UTC()
+	if _, err = tx.ExecContext(ctx, query, string(initial), revision.ContentId); err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	diff, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO content.content_revisions (id, content_id, number, user_id, snapshot, changes, restored_from, created_at)
+		SELECT $1, $2, COALESCE(MAX(number), 0) + 1, $3, CAST($4 AS jsonb), CAST($5 AS jsonb), NULLIF($6, 0), $7
+		FROM content.content_revisions
+		WHERE content_id = $2`
+
+	_, err = tx.ExecContext(ctx, query,
+		revision.Id,
+		revision.ContentId,
+		revision.UserId,
+		string(snapshot),
+		string(diff),
+		revision.RestoredFrom,
+		revision.CreatedAt)
+	if err != nil {
+		return err
+	}
 
-	query := "UPDATE content.content SET deleted_at = $1 WHERE id = $2"
-	_, err := r.db.ExecContext(ctx, query, now, id)
+	query = `
+		DELETE FROM content.content_revisions
+		WHERE content_id = $1
+			AND number <= (SELECT MAX(number) FROM content.content_revisions WHERE content_id = $1) - $2`
 
+	_, err = tx.ExecContext(ctx, query, revision.ContentId, maxRevisions)
 	return err
 }
 
-func (r *repository) exec(ctx context.Context, useTransaction bool, fn func(exec func(query string, args ...any) (sql.Result, error)) error) (err error) {
-	if useTransaction {
-		tran, tranErr := r.db.BeginTx(ctx, &sql.TxOptions{})
-		if tranErr != nil {
-			return tranErr
+// selectRevisions selects revisions with nullable columns as zero values
+const selectRevisions = `
+	SELECT id, content_id, number, user_id, CAST(snapshot AS text) AS snapshot, CAST(changes AS text) AS changes,
+		COALESCE(restored_from, 0) AS restored_from, created_at
+	FROM content.content_revisions`
+
+// GetRevisions returns kept revisions of the item, the latest first
+func (r *repository) GetRevisions(ctx context.Context, contentId string) ([]*Revision, error) {
+	var result []*Revision
+
+	err := r.db.SelectContext(ctx, &result, selectRevisions+` WHERE content_id = $1 ORDER BY number DESC`, contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) GetRevision(ctx context.Context, contentId string, number int) (*Revision, error) {
+	var revision Revision
+
+	err := r.db.GetContext(ctx, &revision, selectRevisions+` WHERE content_id = $1 AND number = $2`, contentId, number)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+func (r *repository) React(ctx context.Context, reaction Reaction, notify *ContentReactedEvent) (previous string, counts string, err error) {
+	err = postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		// the item row lock serializes reactions to the item, so counts always match the reactions
+		if counts, err = lockReactionCounts(ctx, tx, reaction.ContentId); err != nil {
+			return err
+		}
+
+		query := `SELECT COALESCE((SELECT reaction FROM content.reactions WHERE content_id = $1 AND user_id = $2), '')`
+		if err = tx.GetContext(ctx, &previous, query, reaction.ContentId, reaction.UserId); err != nil {
+			return err
+		}
+
+		if previous == reaction.Reaction {
+			return nil
+		}
+
+		query = `
+			INSERT INTO content.reactions (content_id, user_id, reaction, created_at) VALUES ($1,$2,$3,$4)
+			ON CONFLICT (content_id, user_id) DO UPDATE SET reaction = EXCLUDED.reaction, created_at = EXCLUDED.created_at`
+
+		if _, err = tx.ExecContext(ctx, query, reaction.ContentId, reaction.UserId, reaction.Reaction, reaction.CreatedAt); err != nil {
+			return err
+		}
+
+		if previous != "" {
+			if counts, err = changeReactionCount(ctx, tx, reaction.ContentId, previous, -1); err != nil {
+				return err
+			}
+		}
+
+		if counts, err = changeReactionCount(ctx, tx, reaction.ContentId, reaction.Reaction, 1); err != nil {
+			return err
+		}
+
+		if notify == nil {
+			return nil
+		}
+
+		notify.PreviousReaction = previous
+
+		return outbox.Write(tx.Exec, outbox.Message{Topic: ContentReactedTopic, Key: reaction.ContentId, Payload: notify})
+	})
+
+	return previous, counts, err
+}
+
+func (r *repository) Unreact(ctx context.Context, contentId string, userId string) (counts string, err error) {
+	err = postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		if counts, err = lockReactionCounts(ctx, tx, contentId); err != nil {
+			return err
 		}
 
-		defer func() {
-			if rec := recover(); rec != nil {
-				_ = tran.Rollback()
-				panic(rec)
-			} else if err != nil {
-				_ = tran.Rollback()
-			} else {
-				err = tran.Commit()
+		var previous string
+
+		query := `DELETE FROM content.reactions WHERE content_id = $1 AND user_id = $2 RETURNING reaction`
+		if err = tx.GetContext(ctx, &previous, query, contentId, userId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
 			}
-		}()
 
-		err = fn(tran.Exec)
+			return err
+		}
+
+		counts, err = changeReactionCount(ctx, tx, contentId, previous, -1)
+		return err
+	})
+
+	return counts, err
+}
+
+func (r *repository) GetReaction(ctx context.Context, contentId string, userId string) (string, error) {
+	query := `SELECT COALESCE((SELECT reaction FROM content.reactions WHERE content_id = $1 AND user_id = $2), '')`
+
+	var reaction string
+	err := r.db.GetContext(ctx, &reaction, query, contentId, userId)
+
+	return reaction, err
+}
+
+// lockReactionCounts locks the not deleted item and returns its reaction counts, sql.ErrNoRows if there is no such item
+func lockReactionCounts(ctx context.Context, tx *sqlx.Tx, contentId string) (string, error) {
+	query := `SELECT CAST(reaction_counts AS text) FROM content.content WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	var counts string
+	err := tx.GetContext(ctx, &counts, query, contentId)
+
+	return counts, err
+}
+
+// changeReactionCount adds delta to the count of the reaction, zero counts are removed from the object
+func changeReactionCount(ctx context.Context, tx *sqlx.Tx, contentId string, reaction string, delta int) (string, error) {
+	query := `
+		UPDATE content.content SET reaction_counts = CASE
+			WHEN COALESCE(CAST(reaction_counts->>CAST($2 AS text) AS integer), 0) + $3 <= 0 THEN reaction_counts - CAST($2 AS text)
+			ELSE reaction_counts || jsonb_build_object(CAST($2 AS text), COALESCE(CAST(reaction_counts->>CAST($2 AS text) AS integer), 0) + $3)
+		END
+		WHERE id = $1
+		RETURNING CAST(reaction_counts AS text)`
+
+	var counts string
+	err := tx.GetContext(ctx, &counts, query, contentId, reaction, delta)
+
+	return counts, err
+}
+
+func (r *repository) SafeDelete(ctx context.Context, id string, version int, messages ...outbox.Message) error {
+	if id == "" {
+		return errors.New("id is required")
+	}
+
+	now := time.Now().UTC()
+
+	return r.exec(ctx, len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		query := "UPDATE content.content SET deleted_at = $1, version = version + 1 WHERE id = $2 AND version = $3 AND deleted_at IS NULL"
+		result, err := exec(query, now, id, version)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return errVersionConflict
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+// QueryTrash returns a page of the user's deleted items ordered by deletion time
+func (r *repository) QueryTrash(ctx context.Context, userId string, page pagination.Page) ([]*Content, error) {
+	query := `
+		SELECT` + listColumns(":user_id") + `
+		FROM content.content c
+		WHERE c.user_id = :user_id AND c.deleted_at IS NOT NULL`
+
+	params := map[string]any{
+		"user_id": userId,
+	}
+
+	if where := page.Where("c.deleted_at", "c.id"); where != "" {
+		query += " AND " + where
+	}
+
+	query += page.OrderBy("c.deleted_at", "c.id")
+	page.AddParams(params)
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Content
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) QueryFeed(ctx context.Context, userId string, fromInbox bool, page pagination.Page) ([]*Content, error) {
+	query := "SELECT" + listColumns(":user_id")
+
+	// inbox entries keep created_at of the items, so cursors are valid for both sources
+	createdAtColumn, idColumn := "c.created_at", "c.id"
+
+	if fromInbox {
+		query += `
+		FROM content.feed_inbox i
+		JOIN content.content c ON c.id = i.content_id
+		WHERE i.user_id = :user_id`
+		createdAtColumn, idColumn = "i.created_at", "i.content_id"
 	} else {
-		err = fn(r.db.Exec)
+		// the followed users are few, so every one is read by content_index_0
+		query += `
+		FROM content.content c
+		WHERE c.user_id IN (SELECT followee_id FROM content.follows WHERE follower_id = :user_id)`
+	}
+
+	query += ` AND c.visibility = '` + visibility.Public + `' AND c.deleted_at IS NULL AND c.blocked_at IS NULL`
+
+	params := map[string]any{
+		"user_id": userId,
+	}
+
+	if where := page.Where(createdAtColumn, idColumn); where != "" {
+		query += " AND " + where
+	}
+
+	query += page.OrderBy(createdAtColumn, idColumn)
+	page.AddParams(params)
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return nil, err
 	}
 
-	return
+	var result []*Content
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Restore(ctx context.Context, id string, version int, messages ...outbox.Message) error {
+	return r.exec(ctx, len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		query := `UPDATE content.content SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL AND version = $2`
+
+		result, err := exec(query, id, version)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return errNotInTrash
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+func (r *repository) DeletePermanently(ctx context.Context, id string) (int64, error) {
+	return r.purge(ctx, "c.id = $1", id)
+}
+
+func (r *repository) EmptyTrash(ctx context.Context, userId string) (int64, error) {
+	return r.purge(ctx, "c.user_id = $1", userId)
+}
+
+func (r *repository) PurgeExpired(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	return r.purge(ctx, `c.id IN (
+		SELECT id FROM content.content
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+	)`, deletedBefore, limit)
+}
+
+// purge hard deletes trashed items matching the condition and returns their number.
+// Media left without items is marked deleted, its files are removed by the trash purger.
+func (r *repository) purge(ctx context.Context, condition string, args ...any) (int64, error) {
+	query := `
+		WITH deleted AS (
+			DELETE FROM content.content c
+			WHERE c.deleted_at IS NOT NULL AND ` + condition + `
+			RETURNING c.id, c.media_id
+		), released AS (
+			UPDATE content.media m SET status = '` + media.StatusDeleted + `'
+			WHERE m.id IN (SELECT media_id FROM deleted)
+				AND NOT EXISTS (
+					SELECT 1 FROM content.content c
+					WHERE c.media_id = m.id AND c.id NOT IN (SELECT id FROM deleted)
+				)
+		)
+		SELECT COUNT(*) FROM deleted`
+
+	var deleted int64
+	err := r.db.GetContext(ctx, &deleted, query, args...)
+
+	return deleted, err
+}
+
+func (r *repository) exec(ctx context.Context, useTransaction bool, fn func(exec func(query string, args ...any) (sql.Result, error)) error) error {
+	return postgresql.Exec(ctx, r.db, useTransaction, fn)
+}
+
+// escapeHtml escapes the sql expression before it is passed to ts_headline,
+// so highlights contain only the <mark> tags added by the database
+func escapeHtml(expression string) string {
+	return "replace(replace(replace(" + expression + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
 }
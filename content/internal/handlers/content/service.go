@@ -1,8 +1,22 @@
 package content
 
 import (
+	"content/internal/handlers/folders"
+	"content/internal/handlers/follows"
+	"content/internal/handlers/media"
+	"content/internal/handlers/shares"
+	"content/internal/handlers/tags"
+	"content/internal/handlers/types"
+	"content/internal/lib/impersonation"
+	"content/internal/lib/markdown"
+	"content/internal/lib/pagination"
+	"content/internal/lib/visibility"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/flores666/profileshare-lib/api"
@@ -12,13 +26,34 @@ import (
 type Service interface {
 	Create(ctx context.Context, request CreateContentRequest, userId string) api.AppResponse
 	Update(ctx context.Context, request UpdateContentRequest, userId string) api.AppResponse
-	GetById(ctx context.Context, id string) api.AppResponse
-	GetByFilter(ctx context.Context, filter Filter) api.AppResponse
-	SafeDelete(ctx context.Context, id string, userId string) api.AppResponse
+	GetById(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
+	GetByFilter(ctx context.Context, filter Filter, viewer visibility.Viewer) api.AppResponse
+	// SafeDelete moves the item to the trash if it was not changed since the version
+	SafeDelete(ctx context.Context, id string, version *int, userId string) api.AppResponse
+	GetTrash(ctx context.Context, filter TrashFilter, userId string) api.AppResponse
+	// GetFeed returns recent public items of users followed by the user
+	GetFeed(ctx context.Context, filter FeedFilter, userId string) api.AppResponse
+	Restore(ctx context.Context, id string, userId string) api.AppResponse
+	DeletePermanently(ctx context.Context, id string, userId string) api.AppResponse
+	EmptyTrash(ctx context.Context, userId string) api.AppResponse
+	GetRevisions(ctx context.Context, id string, userId string) api.AppResponse
+	RestoreRevision(ctx context.Context, id string, number int, userId string) api.AppResponse
+	React(ctx context.Context, id string, request ReactRequest, viewer visibility.Viewer) api.AppResponse
+	Unreact(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
 }
 
 type service struct {
 	repository Repository
+	folders    folders.Repository
+	follows    follows.Repository
+	media      media.Repository
+	signer     *media.Signer
+	types      *types.Registry
+	markdown   *markdown.Renderer
+	paginator  *pagination.Paginator
+	policy     *visibility.Policy
+	access     *shares.Access
+	settings   Settings
 	logger     *slog.Logger
 }
 
@@ -27,12 +62,41 @@ const (
 	ErrFailedQuery = "Не удалось выполнить запрос"
 	ErrValidation  = "Ошибка проверки данных"
 	ErrForbidden   = "Запись вам не принадлежит"
+	ErrNotFound    = "Запись не найдена"
+	ErrNoRevision  = "Версия записи не найдена"
+	// ErrNoVersion and ErrVersion guard against overwriting changes made by another client
+	ErrNoVersion = "Не указана версия записи"
+	ErrVersion   = "Запись была изменена, обновите данные"
+	// ErrImpersonate rejects irreversible actions of an admin acting on behalf of the user
+	ErrImpersonate = "Операция недоступна в режиме имперсонации"
 	Success        = "Успешно"
 )
 
-func NewService(repository Repository, logger *slog.Logger) Service {
+func NewService(
+	repository Repository,
+	folders folders.Repository,
+	follows follows.Repository,
+	media media.Repository,
+	signer *media.Signer,
+	types *types.Registry,
+	paginator *pagination.Paginator,
+	policy *visibility.Policy,
+	access *shares.Access,
+	settings Settings,
+	logger *slog.Logger,
+) Service {
 	srv := &service{
 		repository: repository,
+		folders:    folders,
+		follows:    follows,
+		media:      media,
+		signer:     signer,
+		types:      types,
+		markdown:   markdown.NewRenderer(settings.MentionUrl, settings.HashtagUrl),
+		paginator:  paginator,
+		policy:     policy,
+		access:     access,
+		settings:   settings,
 		logger:     logger,
 	}
 
@@ -42,105 +106,740 @@ func NewService(repository Repository, logger *slog.Logger) Service {
 }
 
 func (s *service) Create(ctx context.Context, request CreateContentRequest, userId string) api.AppResponse {
-	if err := validateCreate(request); err != nil {
+	contentType, err := s.types.Get(ctx, request.Type)
+	if err != nil {
+		s.logger.Error("could not get content type", slog.String("error", err.Error()), slog.String("type", request.Type))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if err := validateCreate(request, contentType, s.settings.MaxTextLength); err != nil {
 		return api.NewError(ErrValidation, err)
 	}
 
+	tagNames, errs := tags.Normalize(request.Tags, "tags")
+	if errs != nil {
+		return api.NewError(ErrValidation, errs)
+	}
+
+	folder, err := s.folders.GetById(ctx, request.FolderId)
+	if err != nil {
+		s.logger.Error("could not get folder", slog.String("error", err.Error()), slog.String("folderId", request.FolderId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if folder == nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("folderId", "folder not found")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	if folder.UserId != userId {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	if request.MediaId != "" {
+		if response, ok := s.checkMedia(ctx, request.MediaId, request.Type, userId); !ok {
+			return response
+		}
+	}
+
 	id := utils.NewGuid()
 	now := time.Now().UTC()
 
+	contentVisibility := request.Visibility
+	if contentVisibility == "" {
+		contentVisibility = folder.Visibility
+	}
+
+	format := request.Format
+	if format == "" {
+		format = markdown.FormatPlain
+	}
+
 	model := Content{
-		Id:          id,
-		UserId:      userId,
-		DisplayName: request.DisplayName,
-		Text:        request.Text,
-		MediaUrl:    request.MediaUrl,
-		Type:        request.Type,
-		FolderId:    request.FolderId,
-		CreatedAt:   now,
+		Id:              id,
+		UserId:          userId,
+		DisplayName:     request.DisplayName,
+		Text:            request.Text,
+		Format:          format,
+		TextHtml:        s.markdown.Render(request.Text, format),
+		MediaId:         request.MediaId,
+		MediaUrl:        request.MediaUrl,
+		Type:            request.Type,
+		FolderId:        request.FolderId,
+		Visibility:      contentVisibility,
+		Tags:            strings.Join(tagNames, ","),
+		Version:         1,
+		CommentsEnabled: true,
+		CreatedAt:       now,
 	}
 
-	if repoErr := s.repository.Create(ctx, model); repoErr != nil {
-		s.logger.Error("could not create content, error = ", repoErr.Error())
+	if repoErr := s.repository.Create(ctx, model, LifecycleMessage(ContentCreatedTopic, nil, &model, impersonation.ActorId(ctx, userId), now)); repoErr != nil {
+		s.logger.Error("could not create content", slog.String("error", repoErr.Error()))
 		return api.NewError(ErrFailedSave, nil)
 	}
 
-	return api.NewOk(Success, model)
+	return api.NewOk(Success, MapContentToDto(&model, s.signer))
 }
 
-func (s *service) GetById(ctx context.Context, id string) api.AppResponse {
-	if err := validateId(id); err != nil {
-		return api.NewError(ErrValidation, err)
+func (s *service) GetById(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse {
+	item, response := s.getVisible(ctx, id, viewer)
+	if item == nil {
+		return response
 	}
 
-	item, err := s.repository.GetById(ctx, id)
-	if err != nil {
-		s.logger.Error("could not get content, error = ", err, "id = ", id)
-		return api.NewError(ErrFailedQuery, nil)
+	if viewer.UserId != "" {
+		reaction, err := s.repository.GetReaction(ctx, id, viewer.UserId)
+		if err != nil {
+			s.logger.Error("could not get reaction", slog.String("error", err.Error()), slog.String("id", id))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		item.Reaction = reaction
 	}
 
-	return api.NewOk(Success, MapContentToDto(item))
+	return api.NewOk(Success, MapContentToDto(item, s.signer))
 }
 
-func (s *service) GetByFilter(ctx context.Context, filter Filter) api.AppResponse {
+func (s *service) GetByFilter(ctx context.Context, filter Filter, viewer visibility.Viewer) api.AppResponse {
 	if err := validateFilter(filter); err != nil {
 		return api.NewError(ErrValidation, err)
 	}
 
-	list, err := s.repository.Query(ctx, filter)
+	var errs *api.ValidationErrors
+	if filter.Tags, errs = tags.Normalize(filter.Tags, "tags"); errs != nil {
+		return api.NewError(ErrValidation, errs)
+	}
+
+	if filter.AllTags, errs = tags.Normalize(filter.AllTags, "allTags"); errs != nil {
+		return api.NewError(ErrValidation, errs)
+	}
+
+	filter.ViewerId = viewer.UserId
+	if response, ok := s.checkFolderAccess(ctx, &filter, viewer); !ok {
+		return response
+	}
+
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	list, err := s.repository.Query(ctx, filter, page)
+
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
 
 	if err != nil {
-		s.logger.Error("could not get content by filter, error = ", err, "filter = ", filter)
+		s.logger.Error("could not get content by filter", slog.String("error", err.Error()), slog.Any("filter", filter))
 		return api.NewError(ErrFailedQuery, nil)
 	}
 
-	return api.NewOk(Success, MapContentSliceToDto(list))
+	key := contentKey
+	if filter.Search != "" {
+		key = rankedContentKey
+	}
+
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, key)
+
+	return api.NewOk(Success, pagination.PageDto[*ContentDto]{
+		Items:      MapContentSliceToDto(list, s.signer),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
 }
 
 func (s *service) Update(ctx context.Context, request UpdateContentRequest, userId string) api.AppResponse {
-	if err := validateUpdate(request); err != nil {
+	if err := validateUpdate(request, s.settings.MaxTextLength); err != nil {
 		return api.NewError(ErrValidation, err)
 	}
 
-	content, err := s.repository.GetById(ctx, request.Id)
-	if err != nil {
-		s.logger.Error("could not get content, error = ", err, "id = ", request.Id)
-		return api.NewError(ErrFailedQuery, nil)
+	var tagNames *string
+	if request.Tags != nil {
+		names, errs := tags.Normalize(*request.Tags, "tags")
+		if errs != nil {
+			return api.NewError(ErrValidation, errs)
+		}
+
+		joined := strings.Join(names, ",")
+		tagNames = &joined
 	}
-	if content.UserId != userId {
-		return api.NewError(ErrForbidden, nil)
+
+	content, response := s.getOwned(ctx, request.Id, userId, false)
+	if content == nil {
+		return response
+	}
+
+	version, response, ok := checkVersion(content, request.Version)
+	if !ok {
+		return response
+	}
+
+	if response, ok := s.checkFields(ctx, content, request); !ok {
+		return response
+	}
+
+	if request.MediaId != nil {
+		if response, ok := s.checkMedia(ctx, *request.MediaId, content.Type, userId); !ok {
+			return response
+		}
 	}
 
 	model := UpdateContent{
 		Id:          request.Id,
 		DisplayName: request.DisplayName,
 		Text:        request.Text,
-		MediaUrl:    request.MediaUrl,
+		Format:      request.Format,
+		MediaId:     request.MediaId,
+		Visibility:  request.Visibility,
+		Version:     version,
+		Tags:        tagNames,
 	}
 
-	if err := s.repository.Update(ctx, model); err != nil {
-		s.logger.Error("could not update content, error = ", err, "id = ", request.Id)
+	s.render(content, &model)
+
+	revision := s.newRevision(request.Id, userId, 0)
+	message := LifecycleMessage(ContentUpdatedTopic, content, applyUpdate(content, model), impersonation.ActorId(ctx, userId), revision.CreatedAt)
+
+	if err := s.repository.Update(ctx, model, revision, s.settings.MaxRevisions, message); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			return api.NewError(ErrVersion, nil)
+		}
+
+		s.logger.Error("could not update content", slog.String("error", err.Error()), slog.String("id", request.Id))
 		return api.NewError(ErrFailedSave, nil)
 	}
 
-	return api.NewOk(Success, MapContentToDto(content))
+	return s.GetById(ctx, request.Id, visibility.Viewer{UserId: userId})
 }
 
-func (s *service) SafeDelete(ctx context.Context, id string, userId string) api.AppResponse {
-	content, err := s.repository.GetById(ctx, id)
+func (s *service) SafeDelete(ctx context.Context, id string, version *int, userId string) api.AppResponse {
+	content, response := s.getOwned(ctx, id, userId, false)
+	if content == nil {
+		return response
+	}
+
+	current, response, ok := checkVersion(content, version)
+	if !ok {
+		return response
+	}
+
+	err := s.repository.SafeDelete(ctx, id, current, LifecycleMessage(ContentDeletedTopic, content, nil, impersonation.ActorId(ctx, userId), time.Now().UTC()))
+	if errors.Is(err, errVersionConflict) {
+		return api.NewError(ErrVersion, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not safe delete content", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) GetTrash(ctx context.Context, filter TrashFilter, userId string) api.AppResponse {
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
 	if err != nil {
-		s.logger.Error("could not get content, error = ", err, "id = ", id)
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	list, err := s.repository.QueryTrash(ctx, userId, page)
+	if err != nil {
+		s.logger.Error("could not get trash", slog.String("error", err.Error()), slog.String("userId", userId))
 		return api.NewError(ErrFailedQuery, nil)
 	}
-	if content.UserId != userId {
-		return api.NewError(ErrForbidden, nil)
+
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, trashKey)
+
+	return api.NewOk(Success, pagination.PageDto[*ContentDto]{
+		Items:      MapContentSliceToDto(list, s.signer),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
+
+func (s *service) GetFeed(ctx context.Context, filter FeedFilter, userId string) api.AppResponse {
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	// reading from every followed user is cheap only for a few of them
+	fromInbox := false
+	if s.settings.FeedInboxEnabled {
+		following, countErr := s.follows.CountFollowing(ctx, userId)
+		if countErr != nil {
+			s.logger.Error("could not count followed users", slog.String("error", countErr.Error()), slog.String("userId", userId))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		fromInbox = following > s.settings.FeedReadMaxFollows
+	}
+
+	list, err := s.repository.QueryFeed(ctx, userId, fromInbox, page)
+	if err != nil {
+		s.logger.Error("could not get feed", slog.String("error", err.Error()), slog.String("userId", userId), slog.Bool("fromInbox", fromInbox))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, contentKey)
+
+	return api.NewOk(Success, pagination.PageDto[*ContentDto]{
+		Items:      MapContentSliceToDto(list, s.signer),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
+
+func (s *service) Restore(ctx context.Context, id string, userId string) api.AppResponse {
+	content, response := s.getOwned(ctx, id, userId, true)
+	if content == nil {
+		return response
+	}
+
+	restored := *content
+	restored.DeletedAt = time.Time{}
+	restored.Version++
+
+	err := s.repository.Restore(ctx, id, content.Version, LifecycleMessage(ContentRestoredTopic, nil, &restored, impersonation.ActorId(ctx, userId), time.Now().UTC()))
+	if errors.Is(err, errNotInTrash) {
+		return api.NewError(ErrNotFound, nil)
 	}
 
-	err = s.repository.SafeDelete(ctx, id)
 	if err != nil {
-		s.logger.Error("could not safe delete content, error = ", err, "id = ", id)
+		s.logger.Error("could not restore content", slog.String("error", err.Error()), slog.String("id", id))
 		return api.NewError(ErrFailedSave, nil)
 	}
 
-	return api.NewOk(Success, nil)
+	content = &restored
+
+	return api.NewOk(Success, MapContentToDto(content, s.signer))
+}
+
+func (s *service) DeletePermanently(ctx context.Context, id string, userId string) api.AppResponse {
+	if _, impersonating := impersonation.FromContext(ctx); impersonating {
+		return api.NewError(ErrImpersonate, nil)
+	}
+
+	if content, response := s.getOwned(ctx, id, userId, true); content == nil {
+		return response
+	}
+
+	deleted, err := s.repository.DeletePermanently(ctx, id)
+	if err != nil {
+		s.logger.Error("could not delete content permanently", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, PurgeResultDto{Deleted: deleted})
+}
+
+func (s *service) EmptyTrash(ctx context.Context, userId string) api.AppResponse {
+	if _, impersonating := impersonation.FromContext(ctx); impersonating {
+		return api.NewError(ErrImpersonate, nil)
+	}
+
+	deleted, err := s.repository.EmptyTrash(ctx, userId)
+	if err != nil {
+		s.logger.Error("could not empty trash", slog.String("error", err.Error()), slog.String("userId", userId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, PurgeResultDto{Deleted: deleted})
+}
+
+func (s *service) GetRevisions(ctx context.Context, id string, userId string) api.AppResponse {
+	if content, response := s.getOwned(ctx, id, userId, false); content == nil {
+		return response
+	}
+
+	list, err := s.repository.GetRevisions(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get revisions", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapRevisionSliceToDto(list))
+}
+
+// RestoreRevision sets fields of the item to the state of the revision, the restore is saved as a new revision
+func (s *service) RestoreRevision(ctx context.Context, id string, number int, userId string) api.AppResponse {
+	content, response := s.getOwned(ctx, id, userId, false)
+	if content == nil {
+		return response
+	}
+
+	revision, err := s.repository.GetRevision(ctx, id, number)
+	if err != nil {
+		s.logger.Error("could not get revision", slog.String("error", err.Error()), slog.String("id", id), slog.Int("number", number))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if revision == nil {
+		return api.NewError(ErrNoRevision, nil)
+	}
+
+	var state RevisionState
+	if err = json.Unmarshal([]byte(revision.Snapshot), &state); err != nil {
+		s.logger.Error("could not read revision", slog.String("error", err.Error()), slog.String("id", id), slog.Int("number", number))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	model := UpdateContent{
+		Id:          id,
+		DisplayName: &state.DisplayName,
+		Text:        &state.Text,
+		Visibility:  &state.Visibility,
+		Version:     content.Version,
+	}
+
+	// revisions saved before formats existed keep the current format
+	if state.Format != "" {
+		model.Format = &state.Format
+	}
+
+	s.render(content, &model)
+
+	// links stored before uploads existed cannot be restored, the current media is kept then
+	if state.MediaId != "" && state.MediaId != content.MediaId {
+		if checkResponse, ok := s.checkMedia(ctx, state.MediaId, content.Type, userId); !ok {
+			return checkResponse
+		}
+
+		model.MediaId = &state.MediaId
+	}
+
+	restore := s.newRevision(id, userId, number)
+	message := LifecycleMessage(ContentUpdatedTopic, content, applyUpdate(content, model), impersonation.ActorId(ctx, userId), restore.CreatedAt)
+
+	if err = s.repository.Update(ctx, model, restore, s.settings.MaxRevisions, message); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			return api.NewError(ErrVersion, nil)
+		}
+
+		s.logger.Error("could not restore revision", slog.String("error", err.Error()), slog.String("id", id), slog.Int("number", number))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return s.GetById(ctx, id, visibility.Viewer{UserId: userId})
+}
+
+func (s *service) React(ctx context.Context, id string, request ReactRequest, viewer visibility.Viewer) api.AppResponse {
+	if err := validateReaction(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	item, response := s.getVisible(ctx, id, viewer)
+	if item == nil {
+		return response
+	}
+
+	reaction := Reaction{
+		ContentId: id,
+		UserId:    viewer.UserId,
+		Reaction:  request.Reaction,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// owners are not notified about their own reactions
+	var notify *ContentReactedEvent
+	if item.UserId != viewer.UserId {
+		notify = reactedEvent(item, reaction)
+	}
+
+	_, counts, err := s.repository.React(ctx, reaction, notify)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not save reaction", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapReactionsToDto(counts, reaction.Reaction))
+}
+
+func (s *service) Unreact(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse {
+	if item, response := s.getVisible(ctx, id, viewer); item == nil {
+		return response
+	}
+
+	counts, err := s.repository.Unreact(ctx, id, viewer.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not remove reaction", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapReactionsToDto(counts, ""))
+}
+
+// reactedEvent notifies the owner of the item, the previous reaction is set when the reaction is saved
+func reactedEvent(item *Content, reaction Reaction) *ContentReactedEvent {
+	return &ContentReactedEvent{
+		ContentId: item.Id,
+		OwnerId:   item.UserId,
+		UserId:    reaction.UserId,
+		Reaction:  reaction.Reaction,
+		CreatedAt: reaction.CreatedAt,
+	}
+}
+
+// checkVersion ensures the change is based on the current version of the item and returns the version,
+// anyVersion matches the version read, concurrent changes after the read are still rejected by the repository
+func checkVersion(content *Content, version *int) (int, api.AppResponse, bool) {
+	if version == nil {
+		return 0, api.NewError(ErrNoVersion, nil), false
+	}
+
+	if *version != content.Version && *version != anyVersion {
+		return 0, api.NewError(ErrVersion, nil), false
+	}
+
+	return content.Version, api.AppResponse{}, true
+}
+
+// applyUpdate returns the state of the item after the update, the update is based on the current version
+func applyUpdate(content *Content, model UpdateContent) *Content {
+	after := *content
+	after.Version++
+
+	if model.DisplayName != nil {
+		after.DisplayName = *model.DisplayName
+	}
+	if model.Text != nil {
+		after.Text = *model.Text
+	}
+	if model.Format != nil {
+		after.Format = *model.Format
+	}
+	if model.TextHtml != nil {
+		after.TextHtml = *model.TextHtml
+	}
+	if model.MediaId != nil {
+		after.MediaId = *model.MediaId
+		after.MediaUrl = ""
+	}
+	if model.Visibility != nil {
+		after.Visibility = *model.Visibility
+	}
+	if model.Tags != nil {
+		after.Tags = *model.Tags
+	}
+
+	return &after
+}
+
+// render sets the HTML of the text when the update changes the text or its format
+func (s *service) render(content *Content, model *UpdateContent) {
+	// an empty format keeps the current one
+	if model.Format != nil && *model.Format == "" {
+		model.Format = nil
+	}
+
+	if model.Text == nil && model.Format == nil {
+		return
+	}
+
+	after := applyUpdate(content, *model)
+	html := s.markdown.Render(after.Text, after.Format)
+	model.TextHtml = &html
+}
+
+func (s *service) newRevision(contentId string, userId string, restoredFrom int) Revision {
+	return Revision{
+		Id:           utils.NewGuid(),
+		ContentId:    contentId,
+		UserId:       userId,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now().UTC(),
+	}
+}
+
+// getVisible returns the not deleted item the viewer may read, otherwise nil and an error response
+func (s *service) getVisible(ctx context.Context, id string, viewer visibility.Viewer) (*Content, api.AppResponse) {
+	if err := validateId(id); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	item, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get content", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if item == nil || !item.DeletedAt.IsZero() {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	// items blocked by moderators are not available even by share links
+	if !item.BlockedAt.IsZero() && item.UserId != viewer.UserId {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	allowed, err := s.policy.CanView(ctx, item.Visibility, item.UserId, viewer.UserId)
+	if err != nil {
+		s.logger.Error("could not check content visibility", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if !allowed && viewer.ShareToken != "" {
+		link, response := s.access.Grant(ctx, viewer.ShareToken, viewer.SharePassword)
+		if link == nil {
+			return nil, response
+		}
+
+		if allowed, err = s.access.GrantsContent(ctx, link, id); err != nil {
+			s.logger.Error("could not check share link", slog.String("error", err.Error()), slog.String("id", id))
+			return nil, api.NewError(ErrFailedQuery, nil)
+		}
+	}
+
+	// hidden items are reported as missing to not disclose their existence
+	if !allowed {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	return item, api.AppResponse{}
+}
+
+// getOwned returns the item of the user either from the trash or not deleted, otherwise nil and an error response
+func (s *service) getOwned(ctx context.Context, id string, userId string, trashed bool) (*Content, api.AppResponse) {
+	if err := validateId(id); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	content, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get content", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if content == nil || content.DeletedAt.IsZero() == trashed {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	if content.UserId != userId {
+		return nil, api.NewError(ErrForbidden, nil)
+	}
+
+	return content, api.AppResponse{}
+}
+
+// checkFields ensures the item keeps fields required by its type and gets no fields the type does not allow
+func (s *service) checkFields(ctx context.Context, content *Content, request UpdateContentRequest) (api.AppResponse, bool) {
+	contentType, err := s.types.Get(ctx, content.Type)
+	if err != nil {
+		s.logger.Error("could not get content type", slog.String("error", err.Error()), slog.String("type", content.Type))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	// items of deleted types are validated only by the general rules
+	if contentType == nil {
+		return api.AppResponse{}, true
+	}
+
+	after := applyUpdate(content, UpdateContent{Text: request.Text, MediaId: request.MediaId})
+
+	errs := &api.ValidationErrors{}
+	validateFields(errs, contentType, after.Text, after.MediaId, after.MediaUrl)
+
+	if !errs.Ok() {
+		return api.NewError(ErrValidation, errs), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+// checkMedia ensures the media is completely uploaded by the user and matches the content type
+func (s *service) checkMedia(ctx context.Context, mediaId string, contentType string, userId string) (api.AppResponse, bool) {
+	item, err := s.media.GetById(ctx, mediaId)
+	if err != nil {
+		s.logger.Error("could not get media", slog.String("error", err.Error()), slog.String("mediaId", mediaId))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	errs := &api.ValidationErrors{}
+
+	switch {
+	case item == nil || item.Status != media.StatusReady:
+		errs.Add("mediaId", "upload not found or not completed")
+	case item.UserId != userId:
+		return api.NewError(ErrForbidden, nil), false
+	case item.Type != contentType:
+		errs.Add("mediaId", "media type does not match content type")
+	}
+
+	if !errs.Ok() {
+		return api.NewError(ErrValidation, errs), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+// checkFolderAccess ensures the viewer may list the folder of the filter,
+// a share link of the folder or its ancestor grants access to all items regardless of their visibility
+func (s *service) checkFolderAccess(ctx context.Context, filter *Filter, viewer visibility.Viewer) (api.AppResponse, bool) {
+	folder, err := s.folders.GetById(ctx, filter.FolderId)
+	if err != nil {
+		s.logger.Error("could not get folder", slog.String("error", err.Error()), slog.String("folderId", filter.FolderId))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	if folder == nil || folder.UserId != filter.UserId {
+		return api.NewError(ErrNotFound, nil), false
+	}
+
+	if viewer.ShareToken != "" {
+		link, response := s.access.Grant(ctx, viewer.ShareToken, viewer.SharePassword)
+		if link == nil {
+			return response, false
+		}
+
+		if filter.Granted, err = s.access.GrantsFolder(ctx, link, folder.Id); err != nil {
+			s.logger.Error("could not check share link", slog.String("error", err.Error()), slog.String("folderId", folder.Id))
+			return api.NewError(ErrFailedQuery, nil), false
+		}
+
+		if filter.Granted {
+			return api.AppResponse{}, true
+		}
+	}
+
+	allowed, err := s.policy.CanView(ctx, folder.Visibility, folder.UserId, viewer.UserId)
+	if err != nil {
+		s.logger.Error("could not check folder visibility", slog.String("error", err.Error()), slog.String("folderId", folder.Id))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	if !allowed {
+		return api.NewError(ErrNotFound, nil), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+func contentKey(item *Content) pagination.Key {
+	return pagination.Key{CreatedAt: item.CreatedAt, Id: item.Id}
+}
+
+func trashKey(item *Content) pagination.Key {
+	return pagination.Key{CreatedAt: item.DeletedAt, Id: item.Id}
+}
+
+func rankedContentKey(item *Content) pagination.Key {
+	return pagination.Key{Rank: &item.Rank, CreatedAt: item.CreatedAt, Id: item.Id}
 }
@@ -0,0 +1,38 @@
+package content
+
+import (
+	"content/internal/lib/config"
+)
+
+type Settings struct {
+	// MaxRevisions is the number of the latest revisions kept for every item
+	MaxRevisions int
+	// FeedInboxEnabled enables reading feeds of users following more than FeedReadMaxFollows users
+	// from the inbox filled by the feed worker, otherwise feeds are always read from the followed users
+	FeedInboxEnabled   bool
+	FeedReadMaxFollows int
+	// MaxTextLength is the maximum length of the text source in characters
+	MaxTextLength int
+	// MentionUrl and HashtagUrl are prefixes of links rendered for @mentions and #hashtags of the text
+	MentionUrl string
+	HashtagUrl string
+}
+
+const (
+	defaultMaxRevisions       = 50
+	defaultFeedReadMaxFollows = 200
+	defaultMaxTextLength      = 20000
+	defaultMentionUrl         = "/users/"
+	defaultHashtagUrl         = "/tags/"
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		MaxRevisions:       config.MustGetInt("CONTENT__MAX_REVISIONS", defaultMaxRevisions),
+		FeedInboxEnabled:   config.MustGetBool("FEED__INBOX_ENABLED", true),
+		FeedReadMaxFollows: config.MustGetInt("FEED__READ_MAX_FOLLOWS", defaultFeedReadMaxFollows),
+		MaxTextLength:      config.MustGetInt("CONTENT__MAX_TEXT_LENGTH", defaultMaxTextLength),
+		MentionUrl:         config.GetString("CONTENT__MENTION_URL", defaultMentionUrl),
+		HashtagUrl:         config.GetString("CONTENT__HASHTAG_URL", defaultHashtagUrl),
+	}
+}
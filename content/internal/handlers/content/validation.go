@@ -1,16 +1,25 @@
 package content
 
 import (
+	"content/internal/handlers/types"
+	"content/internal/lib/markdown"
+	"content/internal/lib/visibility"
+	"fmt"
+	"net/url"
 	"slices"
 
 	"github.com/flores666/profileshare-lib/api"
 )
 
-var contentTypes = []string{"photo", "video"}
+// reactions is the fixed set of reactions: 👍 ❤️ 😂 😮 😢 🔥
+var reactions = []string{"like", "love", "laugh", "wow", "sad", "fire"}
 
-func validateCreate(request CreateContentRequest) *api.ValidationErrors {
+// validateCreate checks the request against the rules of its content type, contentType is nil for unknown types
+func validateCreate(request CreateContentRequest, contentType *types.ContentType, maxTextLength int) *api.ValidationErrors {
 	errs := &api.ValidationErrors{}
 
+	validateText(errs, &request.Text, &request.Format, maxTextLength)
+
 	if request.DisplayName == "" || len([]rune(request.DisplayName)) <= 2 {
 		errs.Add("displayName", "must be at least 2 characters")
 	}
@@ -21,10 +30,14 @@ func validateCreate(request CreateContentRequest) *api.ValidationErrors {
 
 	if request.Type == "" {
 		errs.Add("type", "is required")
+	} else if contentType == nil {
+		errs.Add("type", "is not supported")
+	} else {
+		validateFields(errs, contentType, request.Text, request.MediaId, request.MediaUrl)
 	}
 
-	if !slices.Contains(contentTypes, request.Type) {
-		errs.Add("type", "invalid input")
+	if request.Visibility != "" && !visibility.IsValid(request.Visibility) {
+		errs.Add("visibility", "must be one of private, unlisted, followers, public")
 	}
 
 	if errs.Ok() {
@@ -34,6 +47,47 @@ func validateCreate(request CreateContentRequest) *api.ValidationErrors {
 	return errs
 }
 
+// validateFields ensures the item has fields required by its type and no fields the type does not allow
+func validateFields(errs *api.ValidationErrors, contentType *types.ContentType, text string, mediaId string, mediaUrl string) {
+	values := map[string]string{
+		types.FieldText:     text,
+		types.FieldMediaId:  mediaId,
+		types.FieldMediaUrl: mediaUrl,
+	}
+
+	for _, field := range []string{types.FieldText, types.FieldMediaId, types.FieldMediaUrl} {
+		if values[field] == "" && contentType.Requires(field) {
+			errs.Add(field, "is required for the type")
+		}
+	}
+
+	if text != "" && !contentType.TextAllowed {
+		errs.Add("text", "is not allowed for the type")
+	}
+
+	if mediaId != "" && !contentType.HasMedia() {
+		errs.Add("mediaId", "is not allowed for the type")
+	}
+
+	// only link types have an external url
+	if mediaUrl != "" && !contentType.Requires(types.FieldMediaUrl) {
+		errs.Add("mediaUrl", "is not allowed for the type")
+	}
+
+	if mediaUrl != "" && !isHttpUrl(mediaUrl) {
+		errs.Add("mediaUrl", "must be an absolute http or https url")
+	}
+}
+
+func isHttpUrl(value string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil || len(value) > 2048 {
+		return false
+	}
+
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
 func validateFilter(filter Filter) *api.ValidationErrors {
 	errs := &api.ValidationErrors{}
 
@@ -45,10 +99,14 @@ func validateFilter(filter Filter) *api.ValidationErrors {
 		errs.Add("userId", "is required")
 	}
 
-	if len([]rune(filter.Search)) < 2 {
+	if filter.Search != "" && len([]rune(filter.Search)) < 2 {
 		errs.Add("search", "must contain at least 2 characters")
 	}
 
+	if filter.Limit < 0 {
+		errs.Add("limit", "must be positive")
+	}
+
 	if errs.Ok() {
 		return nil
 	}
@@ -56,9 +114,11 @@ func validateFilter(filter Filter) *api.ValidationErrors {
 	return errs
 }
 
-func validateUpdate(request UpdateContentRequest) *api.ValidationErrors {
+func validateUpdate(request UpdateContentRequest, maxTextLength int) *api.ValidationErrors {
 	errs := &api.ValidationErrors{}
 
+	validateText(errs, request.Text, request.Format, maxTextLength)
+
 	if request.DisplayName != nil && len([]rune(*request.DisplayName)) <= 2 {
 		errs.Add("displayName", "must be at least 2 characters")
 	}
@@ -67,6 +127,34 @@ func validateUpdate(request UpdateContentRequest) *api.ValidationErrors {
 		errs.Add("id", "is required")
 	}
 
+	if request.Visibility != nil && !visibility.IsValid(*request.Visibility) {
+		errs.Add("visibility", "must be one of private, unlisted, followers, public")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateText(errs *api.ValidationErrors, text *string, format *string, maxTextLength int) {
+	if text != nil && len([]rune(*text)) > maxTextLength {
+		errs.Add("text", fmt.Sprintf("must be at most %d characters", maxTextLength))
+	}
+
+	if format != nil && *format != "" && !markdown.IsValidFormat(*format) {
+		errs.Add("format", "must be one of plain, markdown")
+	}
+}
+
+func validateReaction(request ReactRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if !slices.Contains(reactions, request.Reaction) {
+		errs.Add("reaction", "must be one of like, love, laugh, wow, sad, fire")
+	}
+
 	if errs.Ok() {
 		return nil
 	}
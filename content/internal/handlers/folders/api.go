@@ -0,0 +1,170 @@
+package folders
+
+import (
+	"content/internal/handlers/shares"
+	"content/internal/lib/handlers"
+	"net/http"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath      = "/api/folders"
+	errValidation = "Ошибка проверки данных"
+	errMissingId  = "Отсутствует id"
+)
+
+var statuses = map[string]int{
+	ErrValidation:  http.StatusBadRequest,
+	ErrForbidden:   http.StatusForbidden,
+	ErrNotFound:    http.StatusNotFound,
+	ErrNameTaken:   http.StatusConflict,
+	ErrCycle:       http.StatusBadRequest,
+	ErrTooDeep:     http.StatusBadRequest,
+	ErrImpersonate: http.StatusForbidden,
+	// share links are checked by folder read endpoints
+	shares.ErrLinkInvalid: http.StatusNotFound,
+	shares.ErrPassword:    http.StatusUnauthorized,
+	shares.ErrAttempts:    http.StatusTooManyRequests,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewFoldersHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Group(func(r chi.Router) {
+		r.Use(handlers.OptionalAuth(authMiddleware))
+		r.Get(basePath+"/{id}", h.getById)
+		r.Get(basePath+"/{id}/path", h.getPath)
+		r.Get(basePath+"/{id}/tree", h.getTree)
+		r.Get(basePath, h.getByUser)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Post(basePath, h.create)
+		r.Put(basePath, h.rename)
+		r.Delete(basePath+"/{id}", h.delete)
+		r.Post(basePath+"/{id}/move", h.move)
+		r.Post(basePath+"/{id}/copy", h.copy)
+		r.Put(basePath+"/{id}/parent", h.setParent)
+		r.Put(basePath+"/{id}/visibility", h.setVisibility)
+	})
+}
+
+func (h *Handler) getById(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errMissingId, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.GetById(r.Context(), id, handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) getByUser(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetByUser(r.Context(), getFilter(r)), statuses)
+}
+
+func (h *Handler) getPath(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetPath(r.Context(), chi.URLParam(r, "id"), handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) getTree(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetTree(r.Context(), chi.URLParam(r, "id"), handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) setVisibility(w http.ResponseWriter, r *http.Request) {
+	var request SetVisibilityRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.SetVisibility(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) setParent(w http.ResponseWriter, r *http.Request) {
+	var request SetParentRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.SetParent(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var request CreateFolderRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Create(r.Context(), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) rename(w http.ResponseWriter, r *http.Request) {
+	var request RenameFolderRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Rename(r.Context(), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errMissingId, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Delete(r.Context(), id, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) move(w http.ResponseWriter, r *http.Request) {
+	var request TransferContentRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Move(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) copy(w http.ResponseWriter, r *http.Request) {
+	var request TransferContentRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Copy(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+// getFilter reads userId and parentId, parentId=root selects top level folders, hidden folders are skipped for the viewer
+func getFilter(r *http.Request) ListFilter {
+	filter := ListFilter{
+		UserId:   r.URL.Query().Get("userId"),
+		ParentId: r.URL.Query().Get("parentId"),
+		ViewerId: handlers.GetUserId(r),
+	}
+
+	if filter.ParentId == "root" {
+		filter.ParentId = ""
+		filter.RootOnly = true
+	}
+
+	return filter
+}
@@ -0,0 +1,144 @@
+package folders
+
+import (
+	"content/internal/handlers/media"
+	"time"
+)
+
+type CreateFolderRequest struct {
+	DisplayName string `json:"displayName" validate:"required"`
+	ParentId    string `json:"parentId,omitempty"`
+	// Visibility defaults to the visibility of the parent folder or public for top level folders
+	Visibility string `json:"visibility,omitempty"`
+}
+
+// SetVisibilityRequest changes visibility of the folder, with Recursive nested folders and all their content get it too
+type SetVisibilityRequest struct {
+	Visibility string `json:"visibility" validate:"required"`
+	Recursive  bool   `json:"recursive"`
+}
+
+// SetParentRequest moves the folder with its subtree, empty ParentId moves it to the root
+type SetParentRequest struct {
+	ParentId string `json:"parentId"`
+}
+
+// ListFilter selects folders of the user, with ParentId set only direct children are returned
+type ListFilter struct {
+	UserId   string
+	ParentId string
+	// RootOnly selects top level folders
+	RootOnly bool
+	// ViewerId is the user reading the list, folders hidden from the viewer are skipped
+	ViewerId string
+}
+
+type RenameFolderRequest struct {
+	Id          string `json:"id" validate:"required"`
+	DisplayName string `json:"displayName" validate:"required"`
+}
+
+// TransferContentRequest moves or copies content items from the folder in the route to the target folder
+type TransferContentRequest struct {
+	ContentIds     []string `json:"contentIds" validate:"required"`
+	TargetFolderId string   `json:"targetFolderId" validate:"required"`
+}
+
+type FolderDto struct {
+	Id          string          `json:"id"`
+	UserId      string          `json:"userId"`
+	ParentId    string          `json:"parentId,omitempty"`
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName"`
+	Visibility  string          `json:"visibility"`
+	ItemsCount  int             `json:"itemsCount"`
+	Cover       *FolderCoverDto `json:"cover,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+type FolderCoverDto struct {
+	ContentId string `json:"contentId"`
+	MediaUrl  string `json:"mediaUrl"`
+	Type      string `json:"type"`
+}
+
+type BreadcrumbDto struct {
+	Id          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+type FolderTreeItemDto struct {
+	Id          string `json:"id"`
+	ParentId    string `json:"parentId,omitempty"`
+	DisplayName string `json:"displayName"`
+	Visibility  string `json:"visibility"`
+	Depth       int    `json:"depth"`
+}
+
+type TransferResultDto struct {
+	Affected int64 `json:"affected"`
+}
+
+func MapFolderToDto(model *FolderListItem, signer *media.Signer) FolderDto {
+	if model == nil {
+		return FolderDto{}
+	}
+
+	dto := FolderDto{
+		Id:          model.Id,
+		UserId:      model.UserId,
+		ParentId:    model.ParentId,
+		Name:        model.Name,
+		DisplayName: model.DisplayName,
+		Visibility:  model.Visibility,
+		ItemsCount:  model.ItemsCount,
+		CreatedAt:   model.CreatedAt,
+	}
+
+	if model.CoverId != "" {
+		dto.Cover = &FolderCoverDto{
+			ContentId: model.CoverId,
+			MediaUrl:  signer.Resolve(model.CoverMediaId, model.CoverMediaUrl),
+			Type:      model.CoverType,
+		}
+	}
+
+	return dto
+}
+
+func MapFolderSliceToDto(folders []*FolderListItem, signer *media.Signer) []*FolderDto {
+	result := make([]*FolderDto, 0, len(folders))
+	for _, model := range folders {
+		dto := MapFolderToDto(model, signer)
+		result = append(result, &dto)
+	}
+
+	return result
+}
+
+func MapPathToDto(path []*Folder) []*BreadcrumbDto {
+	result := make([]*BreadcrumbDto, 0, len(path))
+	for _, model := range path {
+		result = append(result, &BreadcrumbDto{
+			Id:          model.Id,
+			DisplayName: model.DisplayName,
+		})
+	}
+
+	return result
+}
+
+func MapTreeToDto(tree []*FolderTreeItem) []*FolderTreeItemDto {
+	result := make([]*FolderTreeItemDto, 0, len(tree))
+	for _, model := range tree {
+		result = append(result, &FolderTreeItemDto{
+			Id:          model.Id,
+			ParentId:    model.ParentId,
+			DisplayName: model.DisplayName,
+			Visibility:  model.Visibility,
+			Depth:       model.Depth,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,34 @@
+package folders
+
+import "time"
+
+// Folder represents folder entity in database
+type Folder struct {
+	Id          string    `db:"id"`
+	UserId      string    `db:"user_id"`
+	ParentId    string    `db:"parent_id"`
+	Name        string    `db:"name"`
+	DisplayName string    `db:"display_name"`
+	Visibility  string    `db:"visibility"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// FolderListItem is a folder with aggregated data of its content
+type FolderListItem struct {
+	Folder
+	ItemsCount    int    `db:"items_count"`
+	CoverId       string `db:"cover_id"`
+	CoverMediaUrl string `db:"cover_media_url"`
+	CoverMediaId  string `db:"cover_media_id"`
+	CoverType     string `db:"cover_type"`
+}
+
+// FolderTreeItem is a folder of a subtree with its depth relative to the subtree root
+type FolderTreeItem struct {
+	Id          string `db:"id"`
+	ParentId    string `db:"parent_id"`
+	Name        string `db:"name"`
+	DisplayName string `db:"display_name"`
+	Visibility  string `db:"visibility"`
+	Depth       int    `db:"depth"`
+}
@@ -0,0 +1,95 @@
+package folders
+
+import (
+	"content/internal/outbox"
+	"time"
+
+	"github.com/flores666/profileshare-lib/utils"
+)
+
+const (
+	FolderCreatedTopic = "folder.created"
+	FolderUpdatedTopic = "folder.updated"
+	FolderDeletedTopic = "folder.deleted"
+)
+
+// FolderEventVersion is incremented on incompatible changes of FolderEvent and FolderSnapshot
+const FolderEventVersion = 1
+
+// FolderEvent is published on every lifecycle change of a folder keyed by the folder id.
+// Before is empty for created folders, After for deleted ones.
+type FolderEvent struct {
+	EventId       string `json:"eventId"`
+	SchemaVersion int    `json:"schemaVersion"`
+	FolderId      string `json:"folderId"`
+	// UserId is the owner of the folder, ActorId is the user who made the change
+	UserId  string          `json:"userId"`
+	ActorId string          `json:"actorId"`
+	Before  *FolderSnapshot `json:"before,omitempty"`
+	After   *FolderSnapshot `json:"after,omitempty"`
+	// Recursive means the change also applies to nested folders, e.g. their visibility and visibility of their
+	// content or their deletion, nested folders and content get no events of their own then
+	Recursive bool `json:"recursive,omitempty"`
+	// AddedContentIds and RemovedContentIds are content items linked to or unlinked from the folder
+	// by moving or copying content, the folder itself is not changed then
+	AddedContentIds   []string `json:"addedContentIds,omitempty"`
+	RemovedContentIds []string `json:"removedContentIds,omitempty"`
+	// CreatedAt is the time of the change
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// FolderSnapshot is the state of the folder published with lifecycle events
+type FolderSnapshot struct {
+	ParentId    string `json:"parentId,omitempty"`
+	DisplayName string `json:"displayName"`
+	Visibility  string `json:"visibility"`
+}
+
+func newSnapshot(folder *Folder) *FolderSnapshot {
+	return &FolderSnapshot{
+		ParentId:    folder.ParentId,
+		DisplayName: folder.DisplayName,
+		Visibility:  folder.Visibility,
+	}
+}
+
+// lifecycleMessage returns the event of the change of the folder from before to after for the outbox,
+// either state is nil for created and deleted folders
+func lifecycleMessage(topic string, before *Folder, after *Folder, actorId string, recursive bool) outbox.Message {
+	event := newEvent(before, after, actorId, recursive)
+	return outbox.Message{Topic: topic, Key: event.FolderId, Payload: event}
+}
+
+// contentEvent returns the update event of the folder whose content is moved or copied,
+// the repository completes it with the linked and unlinked content
+func contentEvent(folder *Folder, actorId string) *FolderEvent {
+	event := newEvent(folder, folder, actorId, false)
+	return &event
+}
+
+func newEvent(before *Folder, after *Folder, actorId string, recursive bool) FolderEvent {
+	event := FolderEvent{
+		EventId:       utils.NewGuid(),
+		SchemaVersion: FolderEventVersion,
+		ActorId:       actorId,
+		Recursive:     recursive,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	for _, folder := range []*Folder{before, after} {
+		if folder != nil {
+			event.FolderId = folder.Id
+			event.UserId = folder.UserId
+		}
+	}
+
+	if before != nil {
+		event.Before = newSnapshot(before)
+	}
+
+	if after != nil {
+		event.After = newSnapshot(after)
+	}
+
+	return event
+}
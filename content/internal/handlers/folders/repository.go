@@ -0,0 +1,391 @@
+package folders
+
+import (
+	"content/internal/lib/visibility"
+	"content/internal/outbox"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errNameConflict = errors.New("folder name is already taken")
+	errCycle        = errors.New("folder cannot be moved into itself or its descendant")
+)
+
+type Repository interface {
+	Create(ctx context.Context, folder Folder, messages ...outbox.Message) error
+	GetById(ctx context.Context, id string) (*FolderListItem, error)
+	Query(ctx context.Context, filter ListFilter) ([]*FolderListItem, error)
+	GetPath(ctx context.Context, id string) ([]*Folder, error)
+	GetSubtree(ctx context.Context, id string) ([]*FolderTreeItem, error)
+	Rename(ctx context.Context, id string, name string, displayName string, messages ...outbox.Message) error
+	SetVisibility(ctx context.Context, id string, visibility string, recursive bool, messages ...outbox.Message) error
+	SetParent(ctx context.Context, userId string, id string, parentId string, messages ...outbox.Message) error
+	Delete(ctx context.Context, id string, messages ...outbox.Message) error
+	// Move and Copy complete the events of the folders with the unlinked and linked content
+	// and save them to the outbox when the content of the folder is changed
+	Move(ctx context.Context, userId string, sourceId string, targetId string, contentIds []string, source *FolderEvent, target *FolderEvent) (int64, error)
+	Copy(ctx context.Context, userId string, sourceId string, targetId string, contentIds []string, target *FolderEvent) (int64, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+// selectFolders returns folders with the number of not deleted items and the latest item as a cover,
+// the cover is chosen among items visible at least to everyone who can see the folder
+const selectFolders = `
+	SELECT
+		f.id, f.user_id, COALESCE(CAST(f.parent_id AS text), '') AS parent_id, f.name, f.display_name, f.visibility, f.created_at,
+		(
+			SELECT COUNT(*)
+			FROM content.folders_contents fc
+			JOIN content.content c ON c.id = fc.content_id
+			WHERE fc.folder_id = f.id AND c.deleted_at IS NULL
+		) AS items_count,
+		COALESCE(CAST(cover.id AS text), '') AS cover_id,
+		COALESCE(cover.media_url, '') AS cover_media_url,
+		COALESCE(CAST(cover.media_id AS text), '') AS cover_media_id,
+		COALESCE(cover.type, '') AS cover_type
+	FROM content.folders f
+	LEFT JOIN LATERAL (
+		SELECT c.id, c.media_url, c.media_id, c.type
+		FROM content.folders_contents fc
+		JOIN content.content c ON c.id = fc.content_id
+		WHERE fc.folder_id = f.id AND c.deleted_at IS NULL AND c.blocked_at IS NULL
+			AND (c.visibility = f.visibility OR c.visibility = 'public')
+		ORDER BY fc.created_at DESC
+		LIMIT 1
+	) cover ON true`
+
+func (r *repository) Create(ctx context.Context, folder Folder, messages ...outbox.Message) error {
+	query := `INSERT INTO content.folders (id, user_id, parent_id, name, display_name, visibility, created_at) VALUES ($1,$2,NULLIF($3, '')::uuid,$4,$5,$6,$7)`
+
+	return r.exec(ctx, len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		if _, err := exec(query, folder.Id, folder.UserId, folder.ParentId, folder.Name, folder.DisplayName, folder.Visibility, folder.CreatedAt); err != nil {
+			return mapError(err)
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+func (r *repository) GetById(ctx context.Context, id string) (*FolderListItem, error) {
+	query := selectFolders + ` WHERE f.id = $1`
+
+	var folder FolderListItem
+	err := r.db.GetContext(ctx, &folder, query, id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+func (r *repository) Query(ctx context.Context, filter ListFilter) ([]*FolderListItem, error) {
+	query := selectFolders + ` WHERE f.user_id = :user_id AND ` + visibility.ListCondition("f.user_id", "f.visibility")
+	params := map[string]any{
+		"user_id":   filter.UserId,
+		"viewer_id": filter.ViewerId,
+	}
+
+	if filter.RootOnly {
+		query += ` AND f.parent_id IS NULL`
+	} else if filter.ParentId != "" {
+		query += ` AND f.parent_id = :parent_id`
+		params["parent_id"] = filter.ParentId
+	}
+
+	query += ` ORDER BY f.created_at DESC`
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*FolderListItem
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetPath returns ancestors of the folder and the folder itself ordered from the root
+func (r *repository) GetPath(ctx context.Context, id string) ([]*Folder, error) {
+	query := `
+		WITH RECURSIVE path AS (
+			SELECT id, user_id, parent_id, name, display_name, visibility, created_at, 0 AS level
+			FROM content.folders
+			WHERE id = $1
+			UNION ALL
+			SELECT f.id, f.user_id, f.parent_id, f.name, f.display_name, f.visibility, f.created_at, p.level + 1
+			FROM content.folders f
+			JOIN path p ON f.id = p.parent_id
+			WHERE p.level < $2
+		)
+		SELECT id, user_id, COALESCE(parent_id::text, '') AS parent_id, name, display_name, visibility, created_at
+		FROM path
+		ORDER BY level DESC`
+
+	var result []*Folder
+
+	err := r.db.SelectContext(ctx, &result, query, id, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetSubtree returns the folder (depth 0) and all its descendants ordered by depth
+func (r *repository) GetSubtree(ctx context.Context, id string) ([]*FolderTreeItem, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, parent_id, name, display_name, visibility, created_at, 0 AS depth
+			FROM content.folders
+			WHERE id = $1
+			UNION ALL
+			SELECT f.id, f.parent_id, f.name, f.display_name, f.visibility, f.created_at, s.depth + 1
+			FROM content.folders f
+			JOIN subtree s ON f.parent_id = s.id
+			WHERE s.depth < $2
+		)
+		SELECT id, COALESCE(parent_id::text, '') AS parent_id, name, display_name, visibility, depth
+		FROM subtree
+		ORDER BY depth, created_at`
+
+	var result []*FolderTreeItem
+
+	err := r.db.SelectContext(ctx, &result, query, id, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Rename(ctx context.Context, id string, name string, displayName string, messages ...outbox.Message) error {
+	query := `UPDATE content.folders SET name = $1, display_name = $2 WHERE id = $3`
+
+	return r.exec(ctx, len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		if _, err := exec(query, name, displayName, id); err != nil {
+			return mapError(err)
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+// SetVisibility changes visibility of the folder, recursive also changes nested folders and content linked to them
+func (r *repository) SetVisibility(ctx context.Context, id string, visibility string, recursive bool, messages ...outbox.Message) error {
+	return r.exec(ctx, recursive || len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		if !recursive {
+			if _, err := exec(`UPDATE content.folders SET visibility = $1 WHERE id = $2`, visibility, id); err != nil {
+				return err
+			}
+
+			return outbox.Write(exec, messages...)
+		}
+
+		subtree := `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM content.folders WHERE id = $2
+				UNION ALL
+				SELECT f.id FROM content.folders f JOIN subtree s ON f.parent_id = s.id
+			)`
+
+		if _, err := exec(subtree+` UPDATE content.folders SET visibility = $1 WHERE id IN (SELECT id FROM subtree)`, visibility, id); err != nil {
+			return err
+		}
+
+		_, err := exec(subtree+`
+			UPDATE content.content SET visibility = $1
+			WHERE id IN (SELECT content_id FROM content.folders_contents WHERE folder_id IN (SELECT id FROM subtree))`, visibility, id)
+		if err != nil {
+			return err
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+// SetParent moves the folder with its subtree under the parent, empty parentId moves it to the root.
+// Moves of the same user are serialized, so concurrent moves cannot create a cycle.
+func (r *repository) SetParent(ctx context.Context, userId string, id string, parentId string, messages ...outbox.Message) error {
+	return r.exec(ctx, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		if _, err := exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, "content.folders:"+userId); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE content.folders
+			SET parent_id = NULLIF($2, '')::uuid
+			WHERE id = $1
+				AND NOT EXISTS (
+					WITH RECURSIVE subtree AS (
+						SELECT id FROM content.folders WHERE id = $1
+						UNION ALL
+						SELECT f.id FROM content.folders f JOIN subtree s ON f.parent_id = s.id
+					)
+					SELECT 1 FROM subtree WHERE id::text = $2
+				)`
+
+		result, err := exec(query, id, parentId)
+		if err != nil {
+			return mapError(err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return errCycle
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+// Delete removes the folder with all descendant folders and their links to content, content items are kept
+func (r *repository) Delete(ctx context.Context, id string, messages ...outbox.Message) error {
+	if id == "" {
+		return errors.New("id is required")
+	}
+
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM content.folders WHERE id = $1
+			UNION ALL
+			SELECT f.id FROM content.folders f JOIN subtree s ON f.parent_id = s.id
+		)
+		DELETE FROM content.folders WHERE id IN (SELECT id FROM subtree)`
+
+	return r.exec(ctx, len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		if _, err := exec(query, id); err != nil {
+			return err
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+func (r *repository) Move(ctx context.Context, userId string, sourceId string, targetId string, contentIds []string, source *FolderEvent, target *FolderEvent) (moved int64, err error) {
+	err = postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		copied, copyErr := copyLinks(ctx, tx, userId, sourceId, targetId, contentIds)
+		if copyErr != nil {
+			return copyErr
+		}
+
+		query, args, inErr := sqlx.In(`
+			DELETE FROM content.folders_contents fc
+			USING content.content c
+			WHERE c.id = fc.content_id
+				AND fc.folder_id = ?
+				AND c.user_id = ?
+				AND fc.content_id IN (?)
+			RETURNING fc.content_id`, sourceId, userId, contentIds)
+		if inErr != nil {
+			return inErr
+		}
+
+		var removed []string
+		if selectErr := tx.SelectContext(ctx, &removed, sqlx.Rebind(sqlx.DOLLAR, query), args...); selectErr != nil {
+			return selectErr
+		}
+
+		moved = int64(len(removed))
+		source.RemovedContentIds = removed
+		target.AddedContentIds = copied
+
+		return writeContentEvents(tx, source, target)
+	})
+
+	return moved, err
+}
+
+func (r *repository) Copy(ctx context.Context, userId string, sourceId string, targetId string, contentIds []string, target *FolderEvent) (copied int64, err error) {
+	err = postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		added, copyErr := copyLinks(ctx, tx, userId, sourceId, targetId, contentIds)
+		if copyErr != nil {
+			return copyErr
+		}
+
+		copied = int64(len(added))
+		target.AddedContentIds = added
+
+		return writeContentEvents(tx, target)
+	})
+
+	return copied, err
+}
+
+// copyLinks links the user's content from the source folder to the target one and returns the linked items,
+// already linked items are skipped
+func copyLinks(ctx context.Context, tx *sqlx.Tx, userId string, sourceId string, targetId string, contentIds []string) ([]string, error) {
+	query, args, err := sqlx.In(`
+		INSERT INTO content.folders_contents (folder_id, content_id, created_at)
+		SELECT ?, fc.content_id, ?
+		FROM content.folders_contents fc
+		JOIN content.content c ON c.id = fc.content_id
+		WHERE fc.folder_id = ?
+			AND c.user_id = ?
+			AND fc.content_id IN (?)
+		ON CONFLICT (folder_id, content_id) DO NOTHING
+		RETURNING content_id`, targetId, time.Now().UTC(), sourceId, userId, contentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	if err = tx.SelectContext(ctx, &added, sqlx.Rebind(sqlx.DOLLAR, query), args...); err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// writeContentEvents saves the events of folders whose content was changed
+func writeContentEvents(tx *sqlx.Tx, events ...*FolderEvent) error {
+	var messages []outbox.Message
+
+	for _, event := range events {
+		if len(event.AddedContentIds) > 0 || len(event.RemovedContentIds) > 0 {
+			messages = append(messages, outbox.Message{Topic: FolderUpdatedTopic, Key: event.FolderId, Payload: event})
+		}
+	}
+
+	return outbox.Write(tx.Exec, messages...)
+}
+
+func (r *repository) exec(ctx context.Context, useTransaction bool, fn func(exec func(query string, args ...any) (sql.Result, error)) error) error {
+	return postgresql.Exec(ctx, r.db, useTransaction, fn)
+}
+
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return errNameConflict
+	}
+
+	return err
+}
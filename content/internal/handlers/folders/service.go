@@ -0,0 +1,499 @@
+package folders
+
+import (
+	"content/internal/handlers/media"
+	"content/internal/handlers/shares"
+	"content/internal/lib/impersonation"
+	"content/internal/lib/visibility"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+	"github.com/flores666/profileshare-lib/utils"
+)
+
+type Service interface {
+	Create(ctx context.Context, request CreateFolderRequest, userId string) api.AppResponse
+	Rename(ctx context.Context, request RenameFolderRequest, userId string) api.AppResponse
+	GetById(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
+	GetByUser(ctx context.Context, filter ListFilter) api.AppResponse
+	GetPath(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
+	GetTree(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
+	SetVisibility(ctx context.Context, id string, request SetVisibilityRequest, userId string) api.AppResponse
+	SetParent(ctx context.Context, id string, request SetParentRequest, userId string) api.AppResponse
+	Delete(ctx context.Context, id string, userId string) api.AppResponse
+	Move(ctx context.Context, folderId string, request TransferContentRequest, userId string) api.AppResponse
+	Copy(ctx context.Context, folderId string, request TransferContentRequest, userId string) api.AppResponse
+}
+
+type service struct {
+	repository Repository
+	signer     *media.Signer
+	policy     *visibility.Policy
+	access     *shares.Access
+	logger     *slog.Logger
+}
+
+const (
+	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	ErrForbidden   = "Папка вам не принадлежит"
+	ErrNotFound    = "Папка не найдена"
+	ErrNameTaken   = "Папка с таким названием уже существует"
+	ErrCycle       = "Нельзя переместить папку в саму себя или во вложенную папку"
+	ErrTooDeep     = "Превышена максимальная вложенность папок"
+	// ErrImpersonate rejects deleting folders by an admin acting on behalf of the user, folders are not kept in the trash
+	ErrImpersonate = "Операция недоступна в режиме имперсонации"
+	Success        = "Успешно"
+)
+
+func NewService(repository Repository, signer *media.Signer, policy *visibility.Policy, access *shares.Access, logger *slog.Logger) Service {
+	srv := &service{
+		repository: repository,
+		signer:     signer,
+		policy:     policy,
+		access:     access,
+		logger:     logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.folders.service"))
+
+	return srv
+}
+
+func (s *service) Create(ctx context.Context, request CreateFolderRequest, userId string) api.AppResponse {
+	if err := validateCreate(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	folderVisibility := request.Visibility
+	if folderVisibility == "" {
+		folderVisibility = visibility.Public
+	}
+
+	if request.ParentId != "" {
+		parent, response := s.getOwned(ctx, request.ParentId, userId)
+		if parent == nil {
+			return response
+		}
+
+		if request.Visibility == "" {
+			folderVisibility = parent.Visibility
+		}
+
+		path, err := s.repository.GetPath(ctx, parent.Id)
+		if err != nil {
+			s.logger.Error("could not get folder path", slog.String("error", err.Error()), slog.String("id", parent.Id))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		if len(path)+1 > maxDepth {
+			return api.NewError(ErrTooDeep, nil)
+		}
+	}
+
+	model := Folder{
+		Id:          utils.NewGuid(),
+		UserId:      userId,
+		ParentId:    request.ParentId,
+		Name:        normalizeName(request.DisplayName),
+		DisplayName: strings.TrimSpace(request.DisplayName),
+		Visibility:  folderVisibility,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err := s.repository.Create(ctx, model, lifecycleMessage(FolderCreatedTopic, nil, &model, impersonation.ActorId(ctx, userId), false)); err != nil {
+		if errors.Is(err, errNameConflict) {
+			return api.NewError(ErrNameTaken, nil)
+		}
+
+		s.logger.Error("could not create folder", slog.String("error", err.Error()))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapFolderToDto(&FolderListItem{Folder: model}, s.signer))
+}
+
+func (s *service) Rename(ctx context.Context, request RenameFolderRequest, userId string) api.AppResponse {
+	if err := validateRename(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	folder, response := s.getOwned(ctx, request.Id, userId)
+	if folder == nil {
+		return response
+	}
+
+	before := folder.Folder
+	folder.Name = normalizeName(request.DisplayName)
+	folder.DisplayName = strings.TrimSpace(request.DisplayName)
+
+	message := lifecycleMessage(FolderUpdatedTopic, &before, &folder.Folder, impersonation.ActorId(ctx, userId), false)
+
+	if err := s.repository.Rename(ctx, folder.Id, folder.Name, folder.DisplayName, message); err != nil {
+		if errors.Is(err, errNameConflict) {
+			return api.NewError(ErrNameTaken, nil)
+		}
+
+		s.logger.Error("could not rename folder", slog.String("error", err.Error()), slog.String("id", folder.Id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapFolderToDto(folder, s.signer))
+}
+
+func (s *service) GetById(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse {
+	if err := validateId(id); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	folder, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get folder", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if folder == nil {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	if _, response, ok := s.authorize(ctx, &folder.Folder, viewer); !ok {
+		return response
+	}
+
+	return api.NewOk(Success, MapFolderToDto(folder, s.signer))
+}
+
+func (s *service) GetByUser(ctx context.Context, filter ListFilter) api.AppResponse {
+	if filter.UserId == "" {
+		errs := &api.ValidationErrors{}
+		errs.Add("userId", "is required")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	list, err := s.repository.Query(ctx, filter)
+	if err != nil {
+		s.logger.Error("could not get folders", slog.String("error", err.Error()), slog.String("userId", filter.UserId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapFolderSliceToDto(list, s.signer))
+}
+
+func (s *service) GetPath(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse {
+	if err := validateId(id); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	path, err := s.repository.GetPath(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get folder path", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if len(path) == 0 {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	link, response, ok := s.authorize(ctx, path[len(path)-1], viewer)
+	if !ok {
+		return response
+	}
+
+	// ancestors hidden from the viewer are skipped, folders under the shared one are always visible
+	visible := make([]*Folder, 0, len(path))
+	shared := false
+
+	for _, folder := range path {
+		shared = shared || (link != nil && link.FolderId == folder.Id)
+
+		allowed, err := s.policy.CanView(ctx, folder.Visibility, folder.UserId, viewer.UserId)
+		if err != nil {
+			s.logger.Error("could not check folder visibility", slog.String("error", err.Error()), slog.String("id", folder.Id))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		if shared || allowed {
+			visible = append(visible, folder)
+		}
+	}
+
+	return api.NewOk(Success, MapPathToDto(visible))
+}
+
+func (s *service) GetTree(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse {
+	if err := validateId(id); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	tree, err := s.repository.GetSubtree(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get folder tree", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if len(tree) == 0 {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	root, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get folder", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if root == nil {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	link, response, ok := s.authorize(ctx, &root.Folder, viewer)
+	if !ok {
+		return response
+	}
+
+	// the whole subtree of a shared folder is visible
+	if link != nil {
+		return api.NewOk(Success, MapTreeToDto(tree))
+	}
+
+	// items are ordered by depth, so a folder is kept only after its parent was kept
+	kept := map[string]bool{tree[0].Id: true}
+	visible := []*FolderTreeItem{tree[0]}
+
+	for _, item := range tree[1:] {
+		if !kept[item.ParentId] {
+			continue
+		}
+
+		allowed, err := s.policy.CanList(ctx, item.Visibility, root.UserId, viewer.UserId)
+		if err != nil {
+			s.logger.Error("could not check folder visibility", slog.String("error", err.Error()), slog.String("id", item.Id))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		if allowed {
+			kept[item.Id] = true
+			visible = append(visible, item)
+		}
+	}
+
+	return api.NewOk(Success, MapTreeToDto(visible))
+}
+
+func (s *service) SetVisibility(ctx context.Context, id string, request SetVisibilityRequest, userId string) api.AppResponse {
+	if err := validateSetVisibility(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	folder, response := s.getOwned(ctx, id, userId)
+	if folder == nil {
+		return response
+	}
+
+	before := folder.Folder
+	folder.Visibility = request.Visibility
+
+	message := lifecycleMessage(FolderUpdatedTopic, &before, &folder.Folder, impersonation.ActorId(ctx, userId), request.Recursive)
+
+	if err := s.repository.SetVisibility(ctx, id, request.Visibility, request.Recursive, message); err != nil {
+		s.logger.Error("could not change folder visibility", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapFolderToDto(folder, s.signer))
+}
+
+func (s *service) SetParent(ctx context.Context, id string, request SetParentRequest, userId string) api.AppResponse {
+	folder, response := s.getOwned(ctx, id, userId)
+	if folder == nil {
+		return response
+	}
+
+	if request.ParentId == id {
+		return api.NewError(ErrCycle, nil)
+	}
+
+	parentDepth := 0
+	if request.ParentId != "" {
+		parent, parentResponse := s.getOwned(ctx, request.ParentId, userId)
+		if parent == nil {
+			return parentResponse
+		}
+
+		path, err := s.repository.GetPath(ctx, parent.Id)
+		if err != nil {
+			s.logger.Error("could not get folder path", slog.String("error", err.Error()), slog.String("id", parent.Id))
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		parentDepth = len(path)
+	}
+
+	subtree, err := s.repository.GetSubtree(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get folder tree", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	height := 0
+	for _, item := range subtree {
+		height = max(height, item.Depth+1)
+	}
+
+	if parentDepth+height > maxDepth {
+		return api.NewError(ErrTooDeep, nil)
+	}
+
+	before := folder.Folder
+	folder.ParentId = request.ParentId
+
+	message := lifecycleMessage(FolderUpdatedTopic, &before, &folder.Folder, impersonation.ActorId(ctx, userId), false)
+
+	if err = s.repository.SetParent(ctx, userId, id, request.ParentId, message); err != nil {
+		if errors.Is(err, errCycle) {
+			return api.NewError(ErrCycle, nil)
+		}
+
+		if errors.Is(err, errNameConflict) {
+			return api.NewError(ErrNameTaken, nil)
+		}
+
+		s.logger.Error("could not move folder", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapFolderToDto(folder, s.signer))
+}
+
+func (s *service) Delete(ctx context.Context, id string, userId string) api.AppResponse {
+	if _, impersonating := impersonation.FromContext(ctx); impersonating {
+		return api.NewError(ErrImpersonate, nil)
+	}
+
+	folder, response := s.getOwned(ctx, id, userId)
+	if folder == nil {
+		return response
+	}
+
+	if err := s.repository.Delete(ctx, id, lifecycleMessage(FolderDeletedTopic, &folder.Folder, nil, impersonation.ActorId(ctx, userId), true)); err != nil {
+		s.logger.Error("could not delete folder", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) Move(ctx context.Context, folderId string, request TransferContentRequest, userId string) api.AppResponse {
+	return s.transfer(ctx, folderId, request, userId, s.repository.Move)
+}
+
+func (s *service) Copy(ctx context.Context, folderId string, request TransferContentRequest, userId string) api.AppResponse {
+	// content of the source folder is not changed by copying
+	return s.transfer(ctx, folderId, request, userId,
+		func(ctx context.Context, userId string, sourceId string, targetId string, contentIds []string, _ *FolderEvent, target *FolderEvent) (int64, error) {
+			return s.repository.Copy(ctx, userId, sourceId, targetId, contentIds, target)
+		})
+}
+
+type transferFunc func(ctx context.Context, userId string, sourceId string, targetId string, contentIds []string, source *FolderEvent, target *FolderEvent) (int64, error)
+
+func (s *service) transfer(ctx context.Context, folderId string, request TransferContentRequest, userId string, fn transferFunc) api.AppResponse {
+	if err := validateTransfer(folderId, request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	source, response := s.getOwned(ctx, folderId, userId)
+	if source == nil {
+		return response
+	}
+
+	target, response := s.getOwned(ctx, request.TargetFolderId, userId)
+	if target == nil {
+		return response
+	}
+
+	actorId := impersonation.ActorId(ctx, userId)
+
+	affected, err := fn(ctx, userId, folderId, request.TargetFolderId, request.ContentIds,
+		contentEvent(&source.Folder, actorId), contentEvent(&target.Folder, actorId))
+	if err != nil {
+		s.logger.Error("could not transfer content", slog.String("error", err.Error()), slog.String("folderId", folderId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, TransferResultDto{Affected: affected})
+}
+
+// getOwned returns the folder if it exists and belongs to the user, otherwise nil and an error response
+func (s *service) getOwned(ctx context.Context, id string, userId string) (*FolderListItem, api.AppResponse) {
+	if err := validateId(id); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	folder, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get folder", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if folder == nil {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	if folder.UserId != userId {
+		return nil, api.NewError(ErrForbidden, nil)
+	}
+
+	return folder, api.AppResponse{}
+}
+
+// authorize checks that the viewer may read the folder, the link is returned when a share link grants access.
+// Hidden folders are reported as missing to not disclose their existence.
+func (s *service) authorize(ctx context.Context, folder *Folder, viewer visibility.Viewer) (*shares.ShareLink, api.AppResponse, bool) {
+	allowed, err := s.policy.CanView(ctx, folder.Visibility, folder.UserId, viewer.UserId)
+	if err != nil {
+		s.logger.Error("could not check folder visibility", slog.String("error", err.Error()), slog.String("id", folder.Id))
+		return nil, api.NewError(ErrFailedQuery, nil), false
+	}
+
+	if viewer.ShareToken == "" {
+		if !allowed {
+			return nil, api.NewError(ErrNotFound, nil), false
+		}
+
+		return nil, api.AppResponse{}, true
+	}
+
+	link, response := s.access.Grant(ctx, viewer.ShareToken, viewer.SharePassword)
+	if link == nil {
+		if allowed {
+			return nil, api.AppResponse{}, true
+		}
+
+		return nil, response, false
+	}
+
+	granted, err := s.access.GrantsFolder(ctx, link, folder.Id)
+	if err != nil {
+		s.logger.Error("could not check share link", slog.String("error", err.Error()), slog.String("id", folder.Id))
+		return nil, api.NewError(ErrFailedQuery, nil), false
+	}
+
+	switch {
+	case granted:
+		return link, api.AppResponse{}, true
+	case allowed:
+		return nil, api.AppResponse{}, true
+	default:
+		return nil, api.NewError(ErrNotFound, nil), false
+	}
+}
+
+// normalizeName builds the unique per user folder name from the display name
+func normalizeName(displayName string) string {
+	return strings.ToLower(strings.Join(strings.Fields(displayName), " "))
+}
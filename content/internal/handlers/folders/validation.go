@@ -0,0 +1,118 @@
+package folders
+
+import (
+	"content/internal/lib/visibility"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+const (
+	maxTransferItems = 100
+	// maxDepth limits nesting of folders, the root folder has depth 1
+	maxDepth = 16
+)
+
+func validateCreate(request CreateFolderRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	validateDisplayName(errs, request.DisplayName)
+
+	if request.Visibility != "" {
+		validateVisibility(errs, request.Visibility)
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateSetVisibility(request SetVisibilityRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	validateVisibility(errs, request.Visibility)
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateRename(request RenameFolderRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if request.Id == "" {
+		errs.Add("id", "is required")
+	}
+
+	validateDisplayName(errs, request.DisplayName)
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateTransfer(folderId string, request TransferContentRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if folderId == "" {
+		errs.Add("id", "is required")
+	}
+
+	if request.TargetFolderId == "" {
+		errs.Add("targetFolderId", "is required")
+	}
+
+	if request.TargetFolderId == folderId {
+		errs.Add("targetFolderId", "must differ from the source folder")
+	}
+
+	if len(request.ContentIds) == 0 {
+		errs.Add("contentIds", "is required")
+	}
+
+	if len(request.ContentIds) > maxTransferItems {
+		errs.Add("contentIds", "must contain at most 100 items")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateDisplayName(errs *api.ValidationErrors, displayName string) {
+	length := len([]rune(displayName))
+
+	if length < 2 {
+		errs.Add("displayName", "must be at least 2 characters")
+	}
+
+	if length > 255 {
+		errs.Add("displayName", "must be at most 255 characters")
+	}
+}
+
+func validateVisibility(errs *api.ValidationErrors, value string) {
+	if !visibility.IsValid(value) {
+		errs.Add("visibility", "must be one of private, unlisted, followers, public")
+	}
+}
+
+func validateId(id string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+	if id == "" {
+		errs.Add("id", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,45 @@
+package follows
+
+import (
+	"content/internal/lib/handlers"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const basePath = "/api/follows"
+
+var statuses = map[string]int{
+	ErrValidation: http.StatusBadRequest,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewFollowsHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Get(basePath, h.getFollowing)
+		r.Put(basePath+"/{userId}", h.follow)
+		r.Delete(basePath+"/{userId}", h.unfollow)
+	})
+}
+
+func (h *Handler) getFollowing(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetFollowing(r.Context(), handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) follow(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.Follow(r.Context(), chi.URLParam(r, "userId"), handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) unfollow(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.Unfollow(r.Context(), chi.URLParam(r, "userId"), handlers.GetUserId(r)), statuses)
+}
@@ -0,0 +1,20 @@
+package follows
+
+import "time"
+
+type FollowDto struct {
+	UserId    string    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func MapFollowingToDto(list []*Follow) []*FollowDto {
+	result := make([]*FollowDto, 0, len(list))
+	for _, model := range list {
+		result = append(result, &FollowDto{
+			UserId:    model.FolloweeId,
+			CreatedAt: model.CreatedAt,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,10 @@
+package follows
+
+import "time"
+
+// Follow represents the follower subscription to content of the followee
+type Follow struct {
+	FollowerId string    `db:"follower_id"`
+	FolloweeId string    `db:"followee_id"`
+	CreatedAt  time.Time `db:"created_at"`
+}
@@ -0,0 +1,101 @@
+package follows
+
+import (
+	"content/internal/lib/visibility"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	Follow(ctx context.Context, follow Follow) error
+	Unfollow(ctx context.Context, followerId string, followeeId string) error
+	IsFollowing(ctx context.Context, followerId string, followeeId string) (bool, error)
+	GetFollowing(ctx context.Context, followerId string) ([]*Follow, error)
+	CountFollowing(ctx context.Context, followerId string) (int, error)
+}
+
+// inboxBackfillSize is the number of recent items of the followee copied to the inbox on follow
+const inboxBackfillSize = 100
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+// Follow also copies recent public items of the followee to the feed inbox of the follower,
+// later items are added by the feed worker
+func (r *repository) Follow(ctx context.Context, follow Follow) error {
+	return postgresql.Exec(ctx, r.db, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		query := `
+			INSERT INTO content.follows (follower_id, followee_id, created_at) VALUES ($1,$2,$3)
+			ON CONFLICT (follower_id, followee_id) DO NOTHING`
+
+		if _, err := exec(query, follow.FollowerId, follow.FolloweeId, follow.CreatedAt); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO content.feed_inbox (user_id, content_id, author_id, created_at)
+			SELECT $1, c.id, c.user_id, c.created_at
+			FROM content.content c
+			WHERE c.user_id = $2 AND c.visibility = '` + visibility.Public + `' AND c.deleted_at IS NULL
+			ORDER BY c.created_at DESC
+			LIMIT $3
+			ON CONFLICT (user_id, content_id) DO NOTHING`
+
+		_, err := exec(query, follow.FollowerId, follow.FolloweeId, inboxBackfillSize)
+		return err
+	})
+}
+
+func (r *repository) Unfollow(ctx context.Context, followerId string, followeeId string) error {
+	return postgresql.Exec(ctx, r.db, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		query := `DELETE FROM content.follows WHERE follower_id = $1 AND followee_id = $2`
+
+		if _, err := exec(query, followerId, followeeId); err != nil {
+			return err
+		}
+
+		_, err := exec(`DELETE FROM content.feed_inbox WHERE user_id = $1 AND author_id = $2`, followerId, followeeId)
+		return err
+	})
+}
+
+func (r *repository) IsFollowing(ctx context.Context, followerId string, followeeId string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM content.follows WHERE follower_id = $1 AND followee_id = $2)`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, followerId, followeeId)
+
+	return exists, err
+}
+
+func (r *repository) GetFollowing(ctx context.Context, followerId string) ([]*Follow, error) {
+	query := `
+		SELECT follower_id, followee_id, created_at
+		FROM content.follows
+		WHERE follower_id = $1
+		ORDER BY created_at DESC`
+
+	var result []*Follow
+
+	err := r.db.SelectContext(ctx, &result, query, followerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) CountFollowing(ctx context.Context, followerId string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM content.follows WHERE follower_id = $1`, followerId)
+
+	return count, err
+}
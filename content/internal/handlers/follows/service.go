@@ -0,0 +1,80 @@
+package follows
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+type Service interface {
+	Follow(ctx context.Context, followeeId string, userId string) api.AppResponse
+	Unfollow(ctx context.Context, followeeId string, userId string) api.AppResponse
+	GetFollowing(ctx context.Context, userId string) api.AppResponse
+}
+
+type service struct {
+	repository Repository
+	logger     *slog.Logger
+}
+
+const (
+	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	Success        = "Успешно"
+)
+
+func NewService(repository Repository, logger *slog.Logger) Service {
+	srv := &service{
+		repository: repository,
+		logger:     logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.follows.service"))
+
+	return srv
+}
+
+func (s *service) Follow(ctx context.Context, followeeId string, userId string) api.AppResponse {
+	if err := validateFollow(userId, followeeId); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	follow := Follow{
+		FollowerId: userId,
+		FolloweeId: followeeId,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := s.repository.Follow(ctx, follow); err != nil {
+		s.logger.Error("could not follow user", slog.String("error", err.Error()), slog.String("followeeId", followeeId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) Unfollow(ctx context.Context, followeeId string, userId string) api.AppResponse {
+	if err := validateFollow(userId, followeeId); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	if err := s.repository.Unfollow(ctx, userId, followeeId); err != nil {
+		s.logger.Error("could not unfollow user", slog.String("error", err.Error()), slog.String("followeeId", followeeId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) GetFollowing(ctx context.Context, userId string) api.AppResponse {
+	list, err := s.repository.GetFollowing(ctx, userId)
+	if err != nil {
+		s.logger.Error("could not get followed users", slog.String("error", err.Error()), slog.String("userId", userId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapFollowingToDto(list))
+}
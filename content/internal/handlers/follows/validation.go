@@ -0,0 +1,23 @@
+package follows
+
+import (
+	"github.com/flores666/profileshare-lib/api"
+)
+
+func validateFollow(followerId string, followeeId string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if followeeId == "" {
+		errs.Add("userId", "is required")
+	}
+
+	if followeeId == followerId {
+		errs.Add("userId", "cannot follow yourself")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,266 @@
+package media
+
+import (
+	"content/internal/lib/handlers"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath      = "/api/media"
+	errValidation = "Ошибка проверки данных"
+	errMissingId  = "Отсутствует id"
+	errNoFile     = "Файл не передан"
+
+	tusVersion       = "1.0.0"
+	tusExtensions    = "creation,checksum"
+	offsetMediaType  = "application/offset+octet-stream"
+	multipartMemory  = 32 << 20
+	multipartReserve = 1 << 20
+	// statusChecksumMismatch is defined by the tus checksum extension
+	statusChecksumMismatch = 460
+)
+
+var statuses = map[string]int{
+	ErrValidation:  http.StatusBadRequest,
+	ErrForbidden:   http.StatusForbidden,
+	ErrNotFound:    http.StatusNotFound,
+	ErrTooLarge:    http.StatusRequestEntityTooLarge,
+	ErrUnsupported: http.StatusUnsupportedMediaType,
+	ErrChecksum:    statusChecksumMismatch,
+	ErrOffset:      http.StatusConflict,
+	ErrCompleted:   http.StatusConflict,
+	ErrLinkExpired: http.StatusForbidden,
+}
+
+type Handler struct {
+	service Service
+	maxSize int64
+}
+
+func NewMediaHandler(service Service, settings Settings) *Handler {
+	return &Handler{
+		service: service,
+		maxSize: settings.MaxUploadSize,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	// signature in the query authorizes downloads, so links work in <img> and <video> tags
+	r.Get(basePath+"/{id}/download", h.download)
+	r.Options(basePath+"/uploads", h.options)
+
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Post(basePath, h.upload)
+		r.Get(basePath+"/{id}", h.getById)
+		r.Post(basePath+"/uploads", h.createUpload)
+		r.Head(basePath+"/uploads/{id}", h.getUpload)
+		r.Patch(basePath+"/uploads/{id}", h.writeChunk)
+	})
+}
+
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+multipartReserve)
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		handlers.Respond(w, r, http.StatusRequestEntityTooLarge, api.NewError(ErrTooLarge, nil))
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errNoFile, nil))
+		return
+	}
+
+	defer file.Close()
+
+	request := UploadRequest{
+		Type:     r.FormValue("type"),
+		FileName: header.Filename,
+		Checksum: r.FormValue("checksum"),
+		Size:     header.Size,
+		Body:     file,
+	}
+
+	response := h.service.Upload(r.Context(), request, handlers.GetUserId(r))
+	if response.Ok() {
+		handlers.Respond(w, r, http.StatusCreated, response)
+		return
+	}
+
+	handlers.WriteResponse(w, r, response, statuses)
+}
+
+func (h *Handler) getById(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errMissingId, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.GetById(r.Context(), id, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) download(w http.ResponseWriter, r *http.Request) {
+	expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+
+	request := DownloadRequest{
+		Id:        chi.URLParam(r, "id"),
+		Variant:   r.URL.Query().Get("variant"),
+		Expires:   expires,
+		Signature: r.URL.Query().Get("signature"),
+	}
+
+	response := h.service.Download(r.Context(), request)
+	if !response.Ok() {
+		handlers.WriteResponse(w, r, response, statuses)
+		return
+	}
+
+	file := response.Data.(*Download)
+	defer file.Body.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": file.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", strconv.Quote(file.Checksum))
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(max(request.Expires-time.Now().Unix(), 0), 10))
+
+	// ServeContent handles range and conditional requests, e.g. video seeking
+	http.ServeContent(w, r, "", file.ModTime, file.Body)
+}
+
+// options answers tus discovery requests
+func (h *Handler) options(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	w.Header().Set("Tus-Checksum-Algorithm", checksumSha256)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) createUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("Upload-Length", "is required")
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, errs))
+		return
+	}
+
+	metadata := parseMetadata(r.Header.Get("Upload-Metadata"))
+
+	request := CreateUploadRequest{
+		Type:     metadata["type"],
+		FileName: metadata["filename"],
+		Size:     size,
+	}
+
+	response := h.service.CreateUpload(r.Context(), request, handlers.GetUserId(r))
+	if !response.Ok() {
+		handlers.WriteResponse(w, r, response, statuses)
+		return
+	}
+
+	upload := response.Data.(UploadDto)
+
+	w.Header().Set("Location", basePath+"/uploads/"+upload.Id)
+	w.Header().Set("Upload-Offset", "0")
+	handlers.Respond(w, r, http.StatusCreated, response)
+}
+
+func (h *Handler) getUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+
+	response := h.service.GetUpload(r.Context(), chi.URLParam(r, "id"), handlers.GetUserId(r))
+	if !response.Ok() {
+		w.WriteHeader(status(response))
+		return
+	}
+
+	upload := response.Data.(UploadDto)
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) writeChunk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Content-Type") != offsetMediaType {
+		handlers.Respond(w, r, http.StatusUnsupportedMediaType, api.NewError(ErrUnsupported, nil))
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("Upload-Offset", "is required")
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, errs))
+		return
+	}
+
+	request := ChunkRequest{
+		Id:       chi.URLParam(r, "id"),
+		Offset:   offset,
+		Checksum: r.Header.Get("Upload-Checksum"),
+		Body:     r.Body,
+	}
+
+	response := h.service.WriteChunk(r.Context(), request, handlers.GetUserId(r))
+	if !response.Ok() {
+		handlers.WriteResponse(w, r, response, statuses)
+		return
+	}
+
+	upload := response.Data.(UploadDto)
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func status(response api.AppResponse) int {
+	if result, ok := statuses[response.Message]; ok {
+		return result
+	}
+
+	return http.StatusInternalServerError
+}
+
+// parseMetadata decodes the tus Upload-Metadata header: comma separated keys with base64 values
+func parseMetadata(header string) map[string]string {
+	result := map[string]string{}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+
+		result[key] = string(decoded)
+	}
+
+	return result
+}
@@ -0,0 +1,127 @@
+package media
+
+import (
+	"io"
+	"time"
+)
+
+// UploadRequest is a file uploaded at once with multipart/form-data
+type UploadRequest struct {
+	Type     string
+	FileName string
+	// Checksum is an optional hex encoded sha256 of the file
+	Checksum string
+	Size     int64
+	Body     io.Reader
+}
+
+// CreateUploadRequest starts a resumable upload of Size bytes
+type CreateUploadRequest struct {
+	Type     string
+	FileName string
+	Size     int64
+}
+
+// ChunkRequest appends Body to the resumable upload at Offset
+type ChunkRequest struct {
+	Id     string
+	Offset int64
+	// Checksum of the chunk in the tus format "sha256 <base64 digest>"
+	Checksum string
+	Body     io.Reader
+}
+
+type DownloadRequest struct {
+	Id string
+	// Variant is the name of a derivative, empty for the original file
+	Variant   string
+	Expires   int64
+	Signature string
+}
+
+type MediaDto struct {
+	Id          string    `json:"id"`
+	Type        string    `json:"type"`
+	ContentType string    `json:"contentType"`
+	FileName    string    `json:"fileName"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	Url         string    `json:"url"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Width, Height, Blurhash and Thumbnails are set after photos are processed
+	Width      int            `json:"width,omitempty"`
+	Height     int            `json:"height,omitempty"`
+	Blurhash   string         `json:"blurhash,omitempty"`
+	Thumbnails []ThumbnailDto `json:"thumbnails,omitempty"`
+}
+
+type ThumbnailDto struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"`
+	Url    string `json:"url"`
+}
+
+type UploadDto struct {
+	Id        string `json:"id"`
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	Completed bool   `json:"completed"`
+}
+
+// Download is a stored file opened for reading, the caller closes Body
+type Download struct {
+	Body        io.ReadSeekCloser
+	ContentType string
+	FileName    string
+	Checksum    string
+	ModTime     time.Time
+}
+
+func MapMediaToDto(model *Media, signer *Signer) MediaDto {
+	if model == nil {
+		return MediaDto{}
+	}
+
+	return MediaDto{
+		Id:          model.Id,
+		Type:        model.Type,
+		ContentType: model.ContentType,
+		FileName:    model.FileName,
+		Size:        model.Size,
+		Checksum:    model.Checksum,
+		Url:         signer.URL(model.Id),
+		CreatedAt:   model.CreatedAt,
+		Width:       model.Width,
+		Height:      model.Height,
+		Blurhash:    model.Blurhash,
+		Thumbnails:  MapThumbnailsToDto(model.Id, ParseDerivatives(model.Derivatives), signer),
+	}
+}
+
+func MapThumbnailsToDto(mediaId string, derivatives []Derivative, signer *Signer) []ThumbnailDto {
+	if len(derivatives) == 0 {
+		return nil
+	}
+
+	result := make([]ThumbnailDto, 0, len(derivatives))
+	for _, derivative := range derivatives {
+		result = append(result, ThumbnailDto{
+			Width:  derivative.Width,
+			Height: derivative.Height,
+			Format: derivative.Format,
+			Url:    signer.VariantURL(mediaId, derivative.Name),
+		})
+	}
+
+	return result
+}
+
+func MapUploadToDto(model *Media) UploadDto {
+	return UploadDto{
+		Id:        model.Id,
+		Offset:    model.Offset,
+		Size:      model.Size,
+		Completed: model.Status == StatusReady,
+	}
+}
@@ -0,0 +1,75 @@
+package media
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	// StatusDeleted media is not used by content anymore, its files are removed by the trash purger
+	StatusDeleted = "deleted"
+)
+
+// processing statuses of derivatives of uploaded photos
+const (
+	ProcessingPending   = "pending"
+	ProcessingProcessed = "processed"
+	ProcessingFailed    = "failed"
+	ProcessingSkipped   = "skipped"
+)
+
+// Media represents uploaded file entity in database
+type Media struct {
+	Id          string    `db:"id"`
+	UserId      string    `db:"user_id"`
+	Type        string    `db:"type"`
+	Status      string    `db:"status"`
+	FileName    string    `db:"file_name"`
+	ContentType string    `db:"content_type"`
+	Size        int64     `db:"size"`
+	Offset      int64     `db:"upload_offset"`
+	Checksum    string    `db:"checksum"`
+	StorageKey  string    `db:"storage_key"`
+	CreatedAt   time.Time `db:"created_at"`
+	CompletedAt time.Time `db:"completed_at"`
+	// Width, Height, Blurhash and Derivatives are filled by the image processing worker
+	Width            int    `db:"width"`
+	Height           int    `db:"height"`
+	Blurhash         string `db:"blurhash"`
+	ProcessingStatus string `db:"processing_status"`
+	Derivatives      string `db:"derivatives"`
+}
+
+// Derivative is a thumbnail produced from an uploaded photo
+type Derivative struct {
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Size       int64  `json:"size"`
+	StorageKey string `json:"key"`
+}
+
+// ParseDerivatives decodes derivatives stored as json, invalid values are treated as no derivatives
+func ParseDerivatives(value string) []Derivative {
+	if value == "" {
+		return nil
+	}
+
+	var result []Derivative
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return nil
+	}
+
+	return result
+}
+
+// Part is a chunk of a resumable upload stored until the upload is completed
+type Part struct {
+	MediaId    string `db:"media_id"`
+	Offset     int64  `db:"upload_offset"`
+	Size       int64  `db:"size"`
+	StorageKey string `db:"storage_key"`
+}
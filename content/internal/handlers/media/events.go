@@ -0,0 +1,11 @@
+package media
+
+const MediaUploadedTopic = "content.media_uploaded"
+
+// MediaUploadedEvent is published when a file is completely uploaded
+type MediaUploadedEvent struct {
+	MediaId     string `json:"mediaId"`
+	UserId      string `json:"userId"`
+	Type        string `json:"type"`
+	ContentType string `json:"contentType"`
+}
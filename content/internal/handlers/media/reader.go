@@ -0,0 +1,60 @@
+package media
+
+import (
+	"content/internal/storage/blob"
+	"context"
+	"io"
+)
+
+// partsReader reads parts of a resumable upload one after another,
+// opening each part only when the previous one is exhausted
+type partsReader struct {
+	ctx     context.Context
+	store   blob.BlobStore
+	parts   []*Part
+	current io.ReadCloser
+}
+
+func newPartsReader(ctx context.Context, store blob.BlobStore, parts []*Part) *partsReader {
+	return &partsReader{ctx: ctx, store: store, parts: parts}
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+
+			part, err := r.store.Get(r.ctx, r.parts[0].StorageKey)
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = part
+			r.parts = r.parts[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			_ = r.current.Close()
+			r.current = nil
+
+			if n > 0 {
+				return n, nil
+			}
+
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+
+	return r.current.Close()
+}
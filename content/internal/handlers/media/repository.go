@@ -0,0 +1,195 @@
+package media
+
+import (
+	"content/internal/outbox"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// errOffsetConflict means another chunk was written at the same offset concurrently
+var errOffsetConflict = errors.New("upload offset conflict")
+
+type Repository interface {
+	Create(ctx context.Context, media Media, messages ...outbox.Message) error
+	GetById(ctx context.Context, id string) (*Media, error)
+	AddPart(ctx context.Context, part Part) error
+	GetParts(ctx context.Context, mediaId string) ([]*Part, error)
+	Complete(ctx context.Context, media Media, messages ...outbox.Message) error
+	Delete(ctx context.Context, id string) error
+	// GetDeleted returns a batch of media released by purged content
+	GetDeleted(ctx context.Context, limit int) ([]*Media, error)
+	// ReleaseAbandoned marks deleted a batch of uploads not completed since pendingBefore
+	// and of media completed before unattachedBefore but never used by content or its revisions
+	ReleaseAbandoned(ctx context.Context, pendingBefore time.Time, unattachedBefore time.Time, limit int) (int64, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) Create(ctx context.Context, media Media, messages ...outbox.Message) error {
+	query := `INSERT INTO content.media (id, user_id, type, status, file_name, content_type, size, upload_offset, checksum, storage_key, created_at, completed_at, processing_status)
+		VALUES ($1,$2,$3,$4,$5,NULLIF($6, ''),$7,$8,NULLIF($9, ''),NULLIF($10, ''),$11,$12,NULLIF($13, ''))`
+
+	var completedAt *time.Time
+	if !media.CompletedAt.IsZero() {
+		completedAt = &media.CompletedAt
+	}
+
+	return postgresql.Exec(ctx, r.db, len(messages) > 0, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		_, err := exec(query,
+			media.Id,
+			media.UserId,
+			media.Type,
+			media.Status,
+			media.FileName,
+			media.ContentType,
+			media.Size,
+			media.Offset,
+			media.Checksum,
+			media.StorageKey,
+			media.CreatedAt,
+			completedAt,
+			media.ProcessingStatus)
+		if err != nil {
+			return err
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+// selectMedia selects all columns of media, nullable ones as zero values
+const selectMedia = `
+	SELECT
+		id, user_id, type, status, file_name,
+		COALESCE(content_type, '') AS content_type,
+		size, upload_offset,
+		COALESCE(checksum, '') AS checksum,
+		COALESCE(storage_key, '') AS storage_key,
+		created_at,
+		COALESCE(completed_at, make_timestamptz(1,1,1,0,0,0)) AS completed_at,
+		COALESCE(width, 0) AS width,
+		COALESCE(height, 0) AS height,
+		COALESCE(blurhash, '') AS blurhash,
+		COALESCE(processing_status, '') AS processing_status,
+		COALESCE(derivatives::text, '') AS derivatives
+	FROM content.media`
+
+func (r *repository) GetById(ctx context.Context, id string) (*Media, error) {
+	query := selectMedia + ` WHERE id = $1`
+
+	var media Media
+	err := r.db.GetContext(ctx, &media, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// AddPart saves the chunk and moves the upload offset, failing with errOffsetConflict
+// if the offset was already moved by another request
+func (r *repository) AddPart(ctx context.Context, part Part) error {
+	return postgresql.Exec(ctx, r.db, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		result, err := exec(`UPDATE content.media SET upload_offset = $1 WHERE id = $2 AND upload_offset = $3 AND status = $4`,
+			part.Offset+part.Size, part.MediaId, part.Offset, StatusPending)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return errOffsetConflict
+		}
+
+		_, err = exec(`INSERT INTO content.media_parts (media_id, upload_offset, size, storage_key) VALUES ($1,$2,$3,$4)`,
+			part.MediaId, part.Offset, part.Size, part.StorageKey)
+
+		return err
+	})
+}
+
+func (r *repository) GetParts(ctx context.Context, mediaId string) ([]*Part, error) {
+	query := `SELECT media_id, upload_offset, size, storage_key FROM content.media_parts WHERE media_id = $1 ORDER BY upload_offset`
+
+	var parts []*Part
+	if err := r.db.SelectContext(ctx, &parts, query, mediaId); err != nil {
+		return nil, err
+	}
+
+	return parts, nil
+}
+
+// Complete marks the upload as ready and forgets its parts
+func (r *repository) Complete(ctx context.Context, media Media, messages ...outbox.Message) error {
+	return postgresql.Exec(ctx, r.db, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		_, err := exec(`UPDATE content.media SET status = $1, content_type = $2, checksum = $3, storage_key = $4, completed_at = $5, processing_status = $6 WHERE id = $7`,
+			StatusReady, media.ContentType, media.Checksum, media.StorageKey, media.CompletedAt, media.ProcessingStatus, media.Id)
+		if err != nil {
+			return err
+		}
+
+		if _, err = exec(`DELETE FROM content.media_parts WHERE media_id = $1`, media.Id); err != nil {
+			return err
+		}
+
+		return outbox.Write(exec, messages...)
+	})
+}
+
+func (r *repository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM content.media WHERE id = $1`, id)
+	return err
+}
+
+func (r *repository) GetDeleted(ctx context.Context, limit int) ([]*Media, error) {
+	query := selectMedia + ` WHERE status = $1 ORDER BY created_at LIMIT $2`
+
+	var result []*Media
+
+	err := r.db.SelectContext(ctx, &result, query, StatusDeleted, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) ReleaseAbandoned(ctx context.Context, pendingBefore time.Time, unattachedBefore time.Time, limit int) (int64, error) {
+	query := `
+		UPDATE content.media SET status = $1
+		WHERE id IN (
+			SELECT m.id FROM content.media m
+			WHERE (m.status = $2 AND m.created_at < $3)
+				OR (m.status = $4 AND m.completed_at < $5
+					AND NOT EXISTS (SELECT 1 FROM content.content c WHERE c.media_id = m.id)
+					AND NOT EXISTS (SELECT 1 FROM content.content_revisions cr WHERE cr.snapshot->>'mediaId' = CAST(m.id AS text)))
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)`
+
+	result, err := r.db.ExecContext(ctx, query, StatusDeleted, StatusPending, pendingBefore, StatusReady, unattachedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,519 @@
+package media
+
+import (
+	"bytes"
+	"content/internal/handlers/types"
+	"content/internal/outbox"
+	"content/internal/storage/blob"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+	"github.com/flores666/profileshare-lib/utils"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+type Service interface {
+	Upload(ctx context.Context, request UploadRequest, userId string) api.AppResponse
+	CreateUpload(ctx context.Context, request CreateUploadRequest, userId string) api.AppResponse
+	GetUpload(ctx context.Context, id string, userId string) api.AppResponse
+	WriteChunk(ctx context.Context, request ChunkRequest, userId string) api.AppResponse
+	GetById(ctx context.Context, id string, userId string) api.AppResponse
+	Download(ctx context.Context, request DownloadRequest) api.AppResponse
+}
+
+type service struct {
+	repository Repository
+	store      blob.BlobStore
+	signer     *Signer
+	types      *types.Registry
+	settings   Settings
+	logger     *slog.Logger
+}
+
+const (
+	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	ErrForbidden   = "Файл вам не принадлежит"
+	ErrNotFound    = "Файл не найден"
+	ErrTooLarge    = "Превышен допустимый размер файла"
+	ErrUnsupported = "Недопустимый формат файла"
+	ErrChecksum    = "Контрольная сумма не совпадает"
+	ErrOffset      = "Смещение загрузки не совпадает"
+	ErrCompleted   = "Загрузка уже завершена"
+	ErrLinkExpired = "Ссылка недействительна или истекла"
+	Success        = "Успешно"
+)
+
+const (
+	// sniffLength is the number of leading bytes used to detect the content type
+	sniffLength     = 3072
+	checksumSha256  = "sha256"
+	chunkMediaType  = "application/octet-stream"
+	storagePrefix   = "media/"
+	partsPrefix     = "uploads/"
+	defaultFileName = "file"
+)
+
+var errUnsupported = errors.New("unsupported content type")
+
+func NewService(
+	repository Repository,
+	store blob.BlobStore,
+	signer *Signer,
+	types *types.Registry,
+	settings Settings,
+	logger *slog.Logger,
+) Service {
+	srv := &service{
+		repository: repository,
+		store:      store,
+		signer:     signer,
+		types:      types,
+		settings:   settings,
+		logger:     logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.media.service"))
+
+	return srv
+}
+
+func (s *service) Upload(ctx context.Context, request UploadRequest, userId string) api.AppResponse {
+	if err := validateFile(request.Type, request.FileName, request.Size); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	if response, ok := s.checkType(ctx, request.Type, request.Size); !ok {
+		return response
+	}
+
+	model := Media{
+		Id:        utils.NewGuid(),
+		UserId:    userId,
+		Type:      request.Type,
+		Status:    StatusReady,
+		FileName:  fileName(request.FileName),
+		Size:      request.Size,
+		Offset:    request.Size,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	model.StorageKey = storageKey(model)
+
+	contentType, checksum, err := s.save(ctx, model.StorageKey, model.Type, request.Body, model.Size)
+	if errors.Is(err, errUnsupported) {
+		return api.NewError(ErrUnsupported, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not store file", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	if request.Checksum != "" && !strings.EqualFold(request.Checksum, checksum) {
+		s.deleteBlob(ctx, model.StorageKey)
+		return api.NewError(ErrChecksum, nil)
+	}
+
+	model.ContentType = contentType
+	model.Checksum = checksum
+	model.CompletedAt = model.CreatedAt
+	model.ProcessingStatus = processingStatus(model.Type)
+
+	if err = s.repository.Create(ctx, model, uploadedMessage(&model)); err != nil {
+		s.logger.Error("could not create media", slog.String("error", err.Error()), slog.String("id", model.Id))
+		s.deleteBlob(ctx, model.StorageKey)
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapMediaToDto(&model, s.signer))
+}
+
+func (s *service) CreateUpload(ctx context.Context, request CreateUploadRequest, userId string) api.AppResponse {
+	if err := validateFile(request.Type, request.FileName, request.Size); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	if response, ok := s.checkType(ctx, request.Type, request.Size); !ok {
+		return response
+	}
+
+	model := Media{
+		Id:        utils.NewGuid(),
+		UserId:    userId,
+		Type:      request.Type,
+		Status:    StatusPending,
+		FileName:  fileName(request.FileName),
+		Size:      request.Size,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.repository.Create(ctx, model); err != nil {
+		s.logger.Error("could not create upload", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapUploadToDto(&model))
+}
+
+func (s *service) GetUpload(ctx context.Context, id string, userId string) api.AppResponse {
+	model, response := s.getOwned(ctx, id, userId)
+	if model == nil {
+		return response
+	}
+
+	return api.NewOk(Success, MapUploadToDto(model))
+}
+
+func (s *service) WriteChunk(ctx context.Context, request ChunkRequest, userId string) api.AppResponse {
+	model, response := s.getOwned(ctx, request.Id, userId)
+	if model == nil {
+		return response
+	}
+
+	if model.Status != StatusPending {
+		return api.NewError(ErrCompleted, nil)
+	}
+
+	if request.Offset != model.Offset {
+		return api.NewError(ErrOffset, nil)
+	}
+
+	expected, err := parseChecksum(request.Checksum)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("checksum", err.Error())
+		return api.NewError(ErrValidation, errs)
+	}
+
+	part := Part{
+		MediaId:    model.Id,
+		Offset:     model.Offset,
+		StorageKey: fmt.Sprintf("%s%s/%020d", partsPrefix, model.Id, model.Offset),
+	}
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.LimitReader(request.Body, model.Size-model.Offset)}
+
+	if err = s.store.Put(ctx, part.StorageKey, io.TeeReader(counter, hash), -1, chunkMediaType); err != nil {
+		s.logger.Error("could not store chunk", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	part.Size = counter.n
+
+	// the chunk must not exceed the length declared on creation
+	if n, _ := io.ReadFull(request.Body, make([]byte, 1)); n > 0 {
+		s.deleteBlob(ctx, part.StorageKey)
+		return api.NewError(ErrTooLarge, nil)
+	}
+
+	if expected != nil && !bytes.Equal(expected, hash.Sum(nil)) {
+		s.deleteBlob(ctx, part.StorageKey)
+		return api.NewError(ErrChecksum, nil)
+	}
+
+	if part.Size == 0 {
+		s.deleteBlob(ctx, part.StorageKey)
+		return api.NewOk(Success, MapUploadToDto(model))
+	}
+
+	if err = s.repository.AddPart(ctx, part); err != nil {
+		s.deleteBlob(ctx, part.StorageKey)
+
+		if errors.Is(err, errOffsetConflict) {
+			return api.NewError(ErrOffset, nil)
+		}
+
+		s.logger.Error("could not save chunk", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	model.Offset += part.Size
+
+	if model.Offset == model.Size {
+		return s.complete(ctx, model)
+	}
+
+	return api.NewOk(Success, MapUploadToDto(model))
+}
+
+func (s *service) GetById(ctx context.Context, id string, userId string) api.AppResponse {
+	model, response := s.getOwned(ctx, id, userId)
+	if model == nil {
+		return response
+	}
+
+	if model.Status != StatusReady {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	return api.NewOk(Success, MapMediaToDto(model, s.signer))
+}
+
+func (s *service) Download(ctx context.Context, request DownloadRequest) api.AppResponse {
+	if !s.signer.Verify(request.Id, request.Variant, request.Expires, request.Signature) {
+		return api.NewError(ErrLinkExpired, nil)
+	}
+
+	model, err := s.repository.GetById(ctx, request.Id)
+	if err != nil {
+		s.logger.Error("could not get media", slog.String("error", err.Error()), slog.String("id", request.Id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if model == nil || model.Status != StatusReady {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	// the worker replaces originals of photos with copies without metadata, until then EXIF with GPS is kept
+	if model.Type == types.TypePhoto && model.ProcessingStatus != ProcessingProcessed && request.Variant == "" {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	download := &Download{
+		ContentType: model.ContentType,
+		FileName:    model.FileName,
+		Checksum:    model.Checksum,
+		ModTime:     model.CompletedAt,
+	}
+	key := model.StorageKey
+
+	if request.Variant != "" {
+		derivatives := ParseDerivatives(model.Derivatives)
+
+		index := slices.IndexFunc(derivatives, func(d Derivative) bool { return d.Name == request.Variant })
+		if index < 0 {
+			return api.NewError(ErrNotFound, nil)
+		}
+
+		derivative := derivatives[index]
+		key = derivative.StorageKey
+		download.ContentType = "image/" + derivative.Format
+		download.FileName = derivative.Name
+		download.Checksum = model.Checksum + "-" + derivative.Name
+	}
+
+	body, err := s.store.Get(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not open file", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	download.Body = body
+
+	return api.NewOk(Success, download)
+}
+
+// complete assembles parts of the resumable upload into the final file
+func (s *service) complete(ctx context.Context, model *Media) api.AppResponse {
+	parts, err := s.repository.GetParts(ctx, model.Id)
+	if err != nil {
+		s.logger.Error("could not get upload parts", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	reader := newPartsReader(ctx, s.store, parts)
+	defer reader.Close()
+
+	model.StorageKey = storageKey(*model)
+
+	contentType, checksum, err := s.save(ctx, model.StorageKey, model.Type, reader, model.Size)
+	if errors.Is(err, errUnsupported) {
+		s.discard(ctx, model, parts)
+		return api.NewError(ErrUnsupported, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not assemble upload", slog.String("error", err.Error()), slog.String("id", model.Id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	model.Status = StatusReady
+	model.ContentType = contentType
+	model.Checksum = checksum
+	model.CompletedAt = time.Now().UTC()
+	model.ProcessingStatus = processingStatus(model.Type)
+
+	if err = s.repository.Complete(ctx, *model, uploadedMessage(model)); err != nil {
+		s.logger.Error("could not complete upload", slog.String("error", err.Error()), slog.String("id", model.Id))
+		s.deleteBlob(ctx, model.StorageKey)
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	for _, part := range parts {
+		s.deleteBlob(ctx, part.StorageKey)
+	}
+
+	return api.NewOk(Success, MapUploadToDto(model))
+}
+
+// uploadedMessage notifies workers, e.g. the image processing, it is saved with the completed upload
+func uploadedMessage(model *Media) outbox.Message {
+	event := MediaUploadedEvent{
+		MediaId:     model.Id,
+		UserId:      model.UserId,
+		Type:        model.Type,
+		ContentType: model.ContentType,
+	}
+
+	return outbox.Message{Topic: MediaUploadedTopic, Key: model.Id, Payload: event}
+}
+
+// save sniffs the content type and computes the checksum while writing the file to the store
+func (s *service) save(ctx context.Context, key string, mediaType string, body io.Reader, size int64) (string, string, error) {
+	allowed, err := s.types.Get(ctx, mediaType)
+	if err != nil {
+		return "", "", err
+	}
+
+	head := make([]byte, sniffLength)
+
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", err
+	}
+
+	head = head[:n]
+
+	contentType := mimetype.Detect(head).String()
+	if allowed == nil || !allowed.AllowsMime(contentType) {
+		return "", "", errUnsupported
+	}
+
+	hash := sha256.New()
+	reader := io.TeeReader(io.MultiReader(bytes.NewReader(head), body), hash)
+
+	if err = s.store.Put(ctx, key, reader, size, contentType); err != nil {
+		return "", "", err
+	}
+
+	return contentType, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// discard removes a rejected upload with all its parts
+func (s *service) discard(ctx context.Context, model *Media, parts []*Part) {
+	for _, part := range parts {
+		s.deleteBlob(ctx, part.StorageKey)
+	}
+
+	if err := s.repository.Delete(ctx, model.Id); err != nil {
+		s.logger.Error("could not delete upload", slog.String("error", err.Error()), slog.String("id", model.Id))
+	}
+}
+
+func (s *service) deleteBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		s.logger.Error("could not delete blob", slog.String("error", err.Error()), slog.String("key", key))
+	}
+}
+
+// getOwned returns the media of the user or nil with the error response
+func (s *service) getOwned(ctx context.Context, id string, userId string) (*Media, api.AppResponse) {
+	if err := validateId(id); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	model, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get media", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if model == nil {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	if model.UserId != userId {
+		return nil, api.NewError(ErrForbidden, nil)
+	}
+
+	return model, api.AppResponse{}
+}
+
+// parseChecksum parses the tus Upload-Checksum value, only sha256 is supported
+func parseChecksum(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	algorithm, digest, ok := strings.Cut(value, " ")
+	if !ok || algorithm != checksumSha256 {
+		return nil, errors.New("only sha256 is supported")
+	}
+
+	result, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil {
+		return nil, errors.New("must be base64 encoded")
+	}
+
+	return result, nil
+}
+
+// checkType ensures files may be uploaded for the content type and the size is within its limit
+func (s *service) checkType(ctx context.Context, name string, size int64) (api.AppResponse, bool) {
+	contentType, err := s.types.Get(ctx, name)
+	if err != nil {
+		s.logger.Error("could not get content type", slog.String("error", err.Error()), slog.String("type", name))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	if contentType == nil || !contentType.HasMedia() {
+		errs := &api.ValidationErrors{}
+		errs.Add("type", "is not supported")
+		return api.NewError(ErrValidation, errs), false
+	}
+
+	if size > contentType.MaxSize {
+		return api.NewError(ErrTooLarge, nil), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+// processingStatus marks photos for the image processing worker
+func processingStatus(mediaType string) string {
+	if mediaType == types.TypePhoto {
+		return ProcessingPending
+	}
+
+	return ProcessingSkipped
+}
+
+func storageKey(model Media) string {
+	return storagePrefix + model.UserId + "/" + model.Id
+}
+
+func fileName(name string) string {
+	if name == "" {
+		return defaultFileName
+	}
+
+	return name
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
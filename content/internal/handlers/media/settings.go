@@ -0,0 +1,29 @@
+package media
+
+import (
+	"content/internal/lib/config"
+	"os"
+	"time"
+)
+
+type Settings struct {
+	// MaxUploadSize caps request bodies in bytes, limits of content types are checked by the service
+	MaxUploadSize int64
+	UrlSecret     string
+	UrlLifetime   time.Duration
+	PublicBaseUrl string
+}
+
+func MustLoadSettings() Settings {
+	secret := os.Getenv("MEDIA__URL_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SECURITY__ACCESS_SECRET")
+	}
+
+	return Settings{
+		MaxUploadSize: int64(config.MustGetInt("MEDIA__MAX_UPLOAD_SIZE_MB", 1024)) << 20,
+		UrlSecret:     secret,
+		UrlLifetime:   time.Duration(config.MustGetInt("MEDIA__URL_LIFETIME_MINUTES", 60)) * time.Minute,
+		PublicBaseUrl: os.Getenv("MEDIA__PUBLIC_BASE_URL"),
+	}
+}
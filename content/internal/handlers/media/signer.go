@@ -0,0 +1,69 @@
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Signer issues expiring download links, so stored files are never exposed by raw urls
+type Signer struct {
+	secret   []byte
+	lifetime time.Duration
+	baseUrl  string
+}
+
+func NewSigner(settings Settings) *Signer {
+	return &Signer{
+		secret:   []byte(settings.UrlSecret),
+		lifetime: settings.UrlLifetime,
+		baseUrl:  settings.PublicBaseUrl,
+	}
+}
+
+// URL returns a signed download link, the expiry is rounded up
+// so links stay the same for a while and can be cached by clients
+func (s *Signer) URL(mediaId string) string {
+	return s.VariantURL(mediaId, "")
+}
+
+// VariantURL returns a signed download link of a derivative, e.g. "640.webp"
+func (s *Signer) VariantURL(mediaId string, variant string) string {
+	step := max(s.lifetime/4, time.Second)
+	expires := time.Now().Add(s.lifetime).Truncate(step).Add(step).Unix()
+
+	query := url.Values{}
+	if variant != "" {
+		query.Set("variant", variant)
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(mediaId, variant, expires))
+
+	return s.baseUrl + basePath + "/" + mediaId + "/download?" + query.Encode()
+}
+
+// Resolve returns a signed link for uploaded media and the raw url for items created before uploads existed
+func (s *Signer) Resolve(mediaId string, rawUrl string) string {
+	if mediaId == "" {
+		return rawUrl
+	}
+
+	return s.URL(mediaId)
+}
+
+func (s *Signer) Verify(mediaId string, variant string, expires int64, signature string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(s.sign(mediaId, variant, expires)))
+}
+
+func (s *Signer) sign(mediaId string, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(mediaId + ":" + variant + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
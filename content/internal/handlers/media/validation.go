@@ -0,0 +1,40 @@
+package media
+
+import (
+	"github.com/flores666/profileshare-lib/api"
+)
+
+func validateFile(mediaType string, fileName string, size int64) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if mediaType == "" {
+		errs.Add("type", "is required")
+	}
+
+	if len([]rune(fileName)) > 255 {
+		errs.Add("fileName", "must be at most 255 characters")
+	}
+
+	if size <= 0 {
+		errs.Add("size", "must be positive")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateId(id string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+	if id == "" {
+		errs.Add("id", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
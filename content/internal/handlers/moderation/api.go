@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/handlers/shares"
+	"content/internal/lib/handlers"
+	"net/http"
+	"strconv"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath    = "/api/moderation"
+	reportsPath = "/api/reports"
+)
+
+var statuses = map[string]int{
+	ErrValidation:         http.StatusBadRequest,
+	ErrForbidden:          http.StatusForbidden,
+	ErrNotFound:           http.StatusNotFound,
+	ErrTargetNotFound:     http.StatusNotFound,
+	ErrOwnItem:            http.StatusBadRequest,
+	ErrReported:           http.StatusConflict,
+	ErrClaimed:            http.StatusConflict,
+	ErrNotClaimed:         http.StatusConflict,
+	ErrResolved:           http.StatusConflict,
+	content.ErrNotFound:   http.StatusNotFound,
+	shares.ErrLinkInvalid: http.StatusNotFound,
+	shares.ErrPassword:    http.StatusUnauthorized,
+	shares.ErrAttempts:    http.StatusTooManyRequests,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewModerationHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Post(reportsPath, h.report)
+		r.Get(basePath+"/cases", h.getQueue)
+		r.Get(basePath+"/cases/{id}", h.getCase)
+		r.Post(basePath+"/cases/{id}/claim", h.claim)
+		r.Post(basePath+"/cases/{id}/resolve", h.resolve)
+		r.Get(basePath+"/history", h.getHistory)
+		r.Get(basePath+"/decisions", h.getDecisions)
+	})
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request) {
+	var request ReportRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Report(r.Context(), request, handlers.GetViewer(r)), statuses)
+}
+
+func (h *Handler) getQueue(w http.ResponseWriter, r *http.Request) {
+	limit, ok := getLimit(w, r)
+	if !ok {
+		return
+	}
+
+	filter := QueueFilter{
+		Status:     r.URL.Query().Get("status"),
+		TargetType: r.URL.Query().Get("targetType"),
+		Mine:       r.URL.Query().Get("mine") == "true",
+		Cursor:     r.URL.Query().Get("cursor"),
+		Limit:      limit,
+	}
+
+	handlers.WriteResponse(w, r, h.service.GetQueue(r.Context(), filter, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) getCase(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetCase(r.Context(), chi.URLParam(r, "id"), handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) claim(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.Claim(r.Context(), chi.URLParam(r, "id"), handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request) {
+	var request ResolveRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Resolve(r.Context(), chi.URLParam(r, "id"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) getHistory(w http.ResponseWriter, r *http.Request) {
+	targetType := r.URL.Query().Get("targetType")
+	targetId := r.URL.Query().Get("targetId")
+
+	handlers.WriteResponse(w, r, h.service.GetHistory(r.Context(), targetType, targetId, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) getDecisions(w http.ResponseWriter, r *http.Request) {
+	limit, ok := getLimit(w, r)
+	if !ok {
+		return
+	}
+
+	filter := DecisionFilter{
+		Cursor: r.URL.Query().Get("cursor"),
+		Limit:  limit,
+	}
+
+	handlers.WriteResponse(w, r, h.service.GetDecisions(r.Context(), filter, handlers.GetUserId(r)), statuses)
+}
+
+func getLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		return 0, true
+	}
+
+	value, err := strconv.Atoi(limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("limit", "должно быть числом")
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, errs))
+		return 0, false
+	}
+
+	return value, true
+}
@@ -0,0 +1,169 @@
+package moderation
+
+import "time"
+
+type ReportRequest struct {
+	TargetType string `json:"targetType" validate:"required"`
+	TargetId   string `json:"targetId" validate:"required"`
+	Reason     string `json:"reason" validate:"required"`
+	Details    string `json:"details,omitempty"`
+}
+
+type ResolveRequest struct {
+	Action string `json:"action" validate:"required"`
+	// Reason is shown to the owner of the item
+	Reason string `json:"reason,omitempty"`
+	// BanDays is the ban duration for the ban action, defaults to MODERATION__BAN_DAYS
+	BanDays int `json:"banDays,omitempty"`
+}
+
+// QueueFilter selects a page of cases, open cases include claims that timed out
+type QueueFilter struct {
+	Status     string
+	TargetType string
+	// Mine selects cases claimed or resolved by the moderator
+	Mine   bool
+	Cursor string
+	Limit  int
+	// ModeratorId and StaleBefore are set by the service
+	ModeratorId string
+	StaleBefore time.Time
+}
+
+// DecisionFilter selects a page of decisions on items of the user
+type DecisionFilter struct {
+	Cursor string
+	Limit  int
+}
+
+type CaseDto struct {
+	Id           string     `json:"id"`
+	TargetType   string     `json:"targetType"`
+	TargetId     string     `json:"targetId"`
+	OwnerId      string     `json:"ownerId"`
+	Status       string     `json:"status"`
+	ReportsCount int        `json:"reportsCount"`
+	AutoHidden   bool       `json:"autoHidden"`
+	ClaimedBy    string     `json:"claimedBy,omitempty"`
+	ClaimedAt    *time.Time `json:"claimedAt,omitempty"`
+	Resolution   string     `json:"resolution,omitempty"`
+	ResolvedBy   string     `json:"resolvedBy,omitempty"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	// Reports are returned only with a single case
+	Reports []*ReportDto `json:"reports,omitempty"`
+}
+
+type ReportDto struct {
+	Id         string    `json:"id"`
+	ReporterId string    `json:"reporterId"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type DecisionDto struct {
+	Id         string `json:"id"`
+	CaseId     string `json:"caseId"`
+	TargetType string `json:"targetType"`
+	TargetId   string `json:"targetId"`
+	OwnerId    string `json:"ownerId"`
+	// ModeratorId is not disclosed to owners of items
+	ModeratorId  string     `json:"moderatorId,omitempty"`
+	Action       string     `json:"action"`
+	Reason       string     `json:"reason,omitempty"`
+	BannedBefore *time.Time `json:"bannedBefore,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func MapCaseToDto(model *Case) CaseDto {
+	if model == nil {
+		return CaseDto{}
+	}
+
+	dto := CaseDto{
+		Id:           model.Id,
+		TargetType:   model.TargetType,
+		TargetId:     model.TargetId,
+		OwnerId:      model.OwnerId,
+		Status:       model.Status,
+		ReportsCount: model.ReportsCount,
+		AutoHidden:   model.AutoHidden,
+		ClaimedBy:    model.ClaimedBy,
+		Resolution:   model.Resolution,
+		ResolvedBy:   model.ResolvedBy,
+		CreatedAt:    model.CreatedAt,
+	}
+
+	if !model.ClaimedAt.IsZero() {
+		claimedAt := model.ClaimedAt
+		dto.ClaimedAt = &claimedAt
+	}
+
+	if !model.ResolvedAt.IsZero() {
+		resolvedAt := model.ResolvedAt
+		dto.ResolvedAt = &resolvedAt
+	}
+
+	return dto
+}
+
+func MapCaseSliceToDto(list []*Case) []*CaseDto {
+	result := make([]*CaseDto, 0, len(list))
+	for _, model := range list {
+		dto := MapCaseToDto(model)
+		result = append(result, &dto)
+	}
+
+	return result
+}
+
+func MapReportSliceToDto(list []*Report) []*ReportDto {
+	result := make([]*ReportDto, 0, len(list))
+	for _, model := range list {
+		result = append(result, &ReportDto{
+			Id:         model.Id,
+			ReporterId: model.ReporterId,
+			Reason:     model.Reason,
+			Details:    model.Details,
+			CreatedAt:  model.CreatedAt,
+		})
+	}
+
+	return result
+}
+
+func MapDecisionToDto(model *Decision) DecisionDto {
+	if model == nil {
+		return DecisionDto{}
+	}
+
+	dto := DecisionDto{
+		Id:          model.Id,
+		CaseId:      model.CaseId,
+		TargetType:  model.TargetType,
+		TargetId:    model.TargetId,
+		OwnerId:     model.OwnerId,
+		ModeratorId: model.ModeratorId,
+		Action:      model.Action,
+		Reason:      model.Reason,
+		CreatedAt:   model.CreatedAt,
+	}
+
+	if !model.BannedBefore.IsZero() {
+		bannedBefore := model.BannedBefore
+		dto.BannedBefore = &bannedBefore
+	}
+
+	return dto
+}
+
+func MapDecisionSliceToDto(list []*Decision) []*DecisionDto {
+	result := make([]*DecisionDto, 0, len(list))
+	for _, model := range list {
+		dto := MapDecisionToDto(model)
+		result = append(result, &dto)
+	}
+
+	return result
+}
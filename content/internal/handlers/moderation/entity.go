@@ -0,0 +1,80 @@
+package moderation
+
+import "time"
+
+// Types of reported items
+const (
+	TargetContent = "content"
+	TargetComment = "comment"
+)
+
+// Statuses of cases, claimed cases return to the queue when the claim times out
+const (
+	StatusOpen     = "open"
+	StatusClaimed  = "claimed"
+	StatusResolved = "resolved"
+)
+
+// Moderation actions, ActionAutoHide is taken by the service when an item gets enough reports
+const (
+	ActionDismiss  = "dismiss"
+	ActionHide     = "hide"
+	ActionDelete   = "delete"
+	ActionWarn     = "warn"
+	ActionBan      = "ban"
+	ActionAutoHide = "auto_hide"
+)
+
+// Case collects reports to an item, each item has at most one not resolved case
+type Case struct {
+	Id         string `db:"id"`
+	TargetType string `db:"target_type"`
+	TargetId   string `db:"target_id"`
+	// OwnerId is the author of the reported item
+	OwnerId      string `db:"owner_id"`
+	Status       string `db:"status"`
+	ReportsCount int    `db:"reports_count"`
+	// AutoHidden is set when the item was blocked because of the number of reports
+	AutoHidden bool      `db:"auto_hidden"`
+	ClaimedBy  string    `db:"claimed_by"`
+	ClaimedAt  time.Time `db:"claimed_at"`
+	// Resolution is the action taken by the moderator resolving the case
+	Resolution string    `db:"resolution"`
+	ResolvedBy string    `db:"resolved_by"`
+	ResolvedAt time.Time `db:"resolved_at"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Report is a complaint of a user about an item, a user reports an item at most once per case
+type Report struct {
+	Id         string    `db:"id"`
+	CaseId     string    `db:"case_id"`
+	ReporterId string    `db:"reporter_id"`
+	Reason     string    `db:"reason"`
+	Details    string    `db:"details"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Decision is an action taken on an item, decisions are kept after the item is purged
+type Decision struct {
+	Id         string `db:"id"`
+	CaseId     string `db:"case_id"`
+	TargetType string `db:"target_type"`
+	TargetId   string `db:"target_id"`
+	OwnerId    string `db:"owner_id"`
+	// ModeratorId is empty for automatic decisions
+	ModeratorId string `db:"moderator_id"`
+	Action      string `db:"action"`
+	Reason      string `db:"reason"`
+	// BannedBefore is set for bans
+	BannedBefore time.Time `db:"banned_before"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// Target is the reported item
+type Target struct {
+	OwnerId string `db:"owner_id"`
+	// ContentId is the item the comment is written to, empty for content
+	ContentId string `db:"content_id"`
+	Blocked   bool   `db:"blocked"`
+}
@@ -0,0 +1,17 @@
+package moderation
+
+import "time"
+
+const UserBanRequestedTopic = "users.ban_requested"
+
+// UserBanRequestedEvent is published when a moderator escalates a case to a ban,
+// the auth service bans the user and revokes the sessions
+type UserBanRequestedEvent struct {
+	// DecisionId identifies the request, so redelivered events are applied once
+	DecisionId   string    `json:"decisionId"`
+	UserId       string    `json:"userId"`
+	ModeratorId  string    `json:"moderatorId"`
+	Reason       string    `json:"reason"`
+	BannedBefore time.Time `json:"bannedBefore"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
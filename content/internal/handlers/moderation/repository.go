@@ -0,0 +1,405 @@
+package moderation
+
+import (
+	"content/internal/lib/pagination"
+	"content/internal/outbox"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errReported   = errors.New("item is already reported by the user")
+	errNotClaimed = errors.New("case is not claimed by the moderator")
+)
+
+type Repository interface {
+	// GetTarget returns the owner of the not deleted item or nil
+	GetTarget(ctx context.Context, targetType string, targetId string) (*Target, error)
+	// Report adds the report to the not resolved case of the item creating the case if needed,
+	// the item is blocked when the case gets autoHideReports reports and hidden is saved to the outbox then
+	Report(ctx context.Context, report Report, item Case, autoHideReports int, hidden ...outbox.Message) error
+	GetCase(ctx context.Context, id string) (*Case, error)
+	GetReports(ctx context.Context, caseId string) ([]*Report, error)
+	QueryCases(ctx context.Context, filter QueueFilter, page pagination.Page) ([]*Case, error)
+	// Claim assigns the case to the moderator unless another moderator claimed it after staleBefore
+	Claim(ctx context.Context, id string, moderatorId string, claimedAt time.Time, staleBefore time.Time) (bool, error)
+	// Resolve closes the case claimed by the moderator and applies the decision to the item
+	Resolve(ctx context.Context, item *Case, decision Decision, messages ...outbox.Message) error
+	GetHistory(ctx context.Context, targetType string, targetId string) ([]*Decision, error)
+	QueryDecisions(ctx context.Context, ownerId string, page pagination.Page) ([]*Decision, error)
+}
+
+const selectCase = `
+	SELECT id, target_type, target_id, owner_id, status, reports_count, auto_hidden,
+		COALESCE(CAST(claimed_by AS text), '') AS claimed_by,
+		COALESCE(claimed_at, make_timestamptz(1,1,1,0,0,0)) AS claimed_at,
+		COALESCE(resolution, '') AS resolution,
+		COALESCE(CAST(resolved_by AS text), '') AS resolved_by,
+		COALESCE(resolved_at, make_timestamptz(1,1,1,0,0,0)) AS resolved_at,
+		created_at
+	FROM content.moderation_cases`
+
+const selectDecision = `
+	SELECT id, case_id, target_type, target_id, owner_id,
+		COALESCE(CAST(moderator_id AS text), '') AS moderator_id,
+		action, COALESCE(reason, '') AS reason,
+		COALESCE(banned_before, make_timestamptz(1,1,1,0,0,0)) AS banned_before,
+		created_at
+	FROM content.moderation_decisions`
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) GetTarget(ctx context.Context, targetType string, targetId string) (*Target, error) {
+	query := `
+		SELECT CAST(user_id AS text) AS owner_id, '' AS content_id, blocked_at IS NOT NULL AS blocked
+		FROM content.content
+		WHERE id = $1 AND deleted_at IS NULL`
+	if targetType == TargetComment {
+		query = `
+			SELECT CAST(user_id AS text) AS owner_id, CAST(content_id AS text) AS content_id, blocked_at IS NOT NULL AS blocked
+			FROM content.comments
+			WHERE id = $1 AND deleted_at IS NULL`
+	}
+
+	var target Target
+
+	err := r.db.GetContext(ctx, &target, query, targetId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &target, nil
+}
+
+func (r *repository) Report(ctx context.Context, report Report, item Case, autoHideReports int, hidden ...outbox.Message) error {
+	return postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		// the partial unique index keeps a single not resolved case per item
+		query := `
+			INSERT INTO content.moderation_cases (id, target_type, target_id, owner_id, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, 'open', $5, $5)
+			ON CONFLICT (target_type, target_id) WHERE status <> 'resolved' DO NOTHING`
+
+		if _, err := tx.ExecContext(ctx, query, item.Id, item.TargetType, item.TargetId, item.OwnerId, report.CreatedAt); err != nil {
+			return err
+		}
+
+		var current Case
+		query = selectCase + ` WHERE target_type = $1 AND target_id = $2 AND status <> 'resolved' FOR UPDATE`
+
+		if err := tx.GetContext(ctx, &current, query, item.TargetType, item.TargetId); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO content.reports (id, case_id, reporter_id, reason, details, created_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+			ON CONFLICT (case_id, reporter_id) DO NOTHING`
+
+		result, err := tx.ExecContext(ctx, query, report.Id, current.Id, report.ReporterId, report.Reason, report.Details, report.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			if err == nil {
+				err = errReported
+			}
+
+			return err
+		}
+
+		query = `UPDATE content.moderation_cases SET reports_count = reports_count + 1, updated_at = $2 WHERE id = $1`
+		if _, err = tx.ExecContext(ctx, query, current.Id, report.CreatedAt); err != nil {
+			return err
+		}
+
+		// reports are counted per user, so a single user cannot hide the item
+		if current.AutoHidden || current.ReportsCount+1 < autoHideReports {
+			return nil
+		}
+
+		if err = block(ctx, tx, current.TargetType, current.TargetId, report.CreatedAt); err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, `UPDATE content.moderation_cases SET auto_hidden = true WHERE id = $1`, current.Id); err != nil {
+			return err
+		}
+
+		err = insertDecision(ctx, tx, Decision{
+			CaseId:     current.Id,
+			TargetType: current.TargetType,
+			TargetId:   current.TargetId,
+			OwnerId:    current.OwnerId,
+			Action:     ActionAutoHide,
+			CreatedAt:  report.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return outbox.Write(tx.Exec, hidden...)
+	})
+}
+
+func (r *repository) GetCase(ctx context.Context, id string) (*Case, error) {
+	var item Case
+
+	err := r.db.GetContext(ctx, &item, selectCase+` WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (r *repository) GetReports(ctx context.Context, caseId string) ([]*Report, error) {
+	query := `
+		SELECT id, case_id, reporter_id, reason, COALESCE(details, '') AS details, created_at
+		FROM content.reports
+		WHERE case_id = $1
+		ORDER BY created_at`
+
+	var result []*Report
+
+	err := r.db.SelectContext(ctx, &result, query, caseId)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) QueryCases(ctx context.Context, filter QueueFilter, page pagination.Page) ([]*Case, error) {
+	query := selectCase
+
+	params := map[string]any{
+		"stale_before": filter.StaleBefore,
+	}
+
+	switch filter.Status {
+	case StatusClaimed:
+		query += ` WHERE status = 'claimed' AND claimed_at >= :stale_before`
+	case StatusResolved:
+		query += ` WHERE status = 'resolved'`
+	default:
+		query += ` WHERE (status = 'open' OR status = 'claimed' AND claimed_at < :stale_before)`
+	}
+
+	if filter.TargetType != "" {
+		query += " AND target_type = :target_type"
+		params["target_type"] = filter.TargetType
+	}
+
+	if filter.Mine {
+		query += " AND claimed_by = :moderator_id"
+		params["moderator_id"] = filter.ModeratorId
+	}
+
+	if where := page.Where("created_at", "id"); where != "" {
+		query += " AND " + where
+	}
+
+	query += page.OrderBy("created_at", "id")
+	page.AddParams(params)
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Case
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Claim(ctx context.Context, id string, moderatorId string, claimedAt time.Time, staleBefore time.Time) (bool, error) {
+	query := `
+		UPDATE content.moderation_cases
+		SET status = 'claimed', claimed_by = $2, claimed_at = $3, updated_at = $3
+		WHERE id = $1 AND (status = 'open' OR status = 'claimed' AND (claimed_by = $2 OR claimed_at < $4))`
+
+	result, err := r.db.ExecContext(ctx, query, id, moderatorId, claimedAt, staleBefore)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *repository) Resolve(ctx context.Context, item *Case, decision Decision, messages ...outbox.Message) error {
+	return postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		query := `
+			UPDATE content.moderation_cases
+			SET status = 'resolved', resolution = $3, resolved_by = $2, resolved_at = $4, updated_at = $4
+			WHERE id = $1 AND status = 'claimed' AND claimed_by = $2`
+
+		result, err := tx.ExecContext(ctx, query, item.Id, decision.ModeratorId, decision.Action, decision.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			if err == nil {
+				err = errNotClaimed
+			}
+
+			return err
+		}
+
+		switch decision.Action {
+		case ActionHide, ActionBan:
+			err = block(ctx, tx, item.TargetType, item.TargetId, decision.CreatedAt)
+		case ActionDelete:
+			err = remove(ctx, tx, item.TargetType, item.TargetId, decision.CreatedAt)
+		case ActionDismiss:
+			// only the block made by reports of this case is lifted
+			if item.AutoHidden {
+				err = unblock(ctx, tx, item.TargetType, item.TargetId)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = insertDecision(ctx, tx, decision); err != nil {
+			return err
+		}
+
+		return outbox.Write(tx.Exec, messages...)
+	})
+}
+
+func (r *repository) GetHistory(ctx context.Context, targetType string, targetId string) ([]*Decision, error) {
+	query := selectDecision + ` WHERE target_type = $1 AND target_id = $2 ORDER BY created_at DESC, id DESC`
+
+	var result []*Decision
+
+	err := r.db.SelectContext(ctx, &result, query, targetType, targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) QueryDecisions(ctx context.Context, ownerId string, page pagination.Page) ([]*Decision, error) {
+	query := selectDecision + ` WHERE owner_id = :owner_id AND action <> '` + ActionDismiss + `'`
+
+	params := map[string]any{
+		"owner_id": ownerId,
+	}
+
+	if where := page.Where("created_at", "id"); where != "" {
+		query += " AND " + where
+	}
+
+	query += page.OrderBy("created_at", "id")
+	page.AddParams(params)
+
+	query, args, err := sqlx.Named(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Decision
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// block hides the item from everyone except its author, the earliest block time is kept
+// and the version of blocked content is not bumped again
+func block(ctx context.Context, tx *sqlx.Tx, targetType string, targetId string, blockedAt time.Time) error {
+	query := `UPDATE content.content SET blocked_at = $2, version = version + 1 WHERE id = $1 AND blocked_at IS NULL`
+	if targetType == TargetComment {
+		query = `UPDATE content.comments SET blocked_at = COALESCE(blocked_at, $2) WHERE id = $1`
+	}
+
+	_, err := tx.ExecContext(ctx, query, targetId, blockedAt)
+	return err
+}
+
+func unblock(ctx context.Context, tx *sqlx.Tx, targetType string, targetId string) error {
+	query := `UPDATE content.content SET blocked_at = NULL, version = version + 1 WHERE id = $1 AND blocked_at IS NOT NULL`
+	if targetType == TargetComment {
+		query = `UPDATE content.comments SET blocked_at = NULL WHERE id = $1`
+	}
+
+	_, err := tx.ExecContext(ctx, query, targetId)
+	return err
+}
+
+// remove deletes the item, content goes to the trash blocked, so restoring it does not publish it again
+func remove(ctx context.Context, tx *sqlx.Tx, targetType string, targetId string, deletedAt time.Time) error {
+	query := `
+		UPDATE content.content
+		SET deleted_at = COALESCE(deleted_at, $2), blocked_at = COALESCE(blocked_at, $2), version = version + 1
+		WHERE id = $1`
+	if targetType == TargetComment {
+		query = `UPDATE content.comments SET deleted_at = COALESCE(deleted_at, $2), blocked_at = COALESCE(blocked_at, $2) WHERE id = $1`
+	}
+
+	_, err := tx.ExecContext(ctx, query, targetId, deletedAt)
+	return err
+}
+
+// insertDecision saves the decision, automatic decisions get a generated id
+func insertDecision(ctx context.Context, tx *sqlx.Tx, decision Decision) error {
+	var bannedBefore *time.Time
+	if !decision.BannedBefore.IsZero() {
+		bannedBefore = &decision.BannedBefore
+	}
+
+	query := `
+		INSERT INTO content.moderation_decisions (
+			id, case_id, target_type, target_id, owner_id, moderator_id, action, reason, banned_before, created_at
+		) VALUES (
+			COALESCE(CAST(NULLIF($1, '') AS uuid), gen_random_uuid()),
+			$2, $3, $4, $5, CAST(NULLIF($6, '') AS uuid), $7, NULLIF($8, ''), $9, $10
+		)`
+
+	_, err := tx.ExecContext(ctx, query,
+		decision.Id,
+		decision.CaseId,
+		decision.TargetType,
+		decision.TargetId,
+		decision.OwnerId,
+		decision.ModeratorId,
+		decision.Action,
+		decision.Reason,
+		bannedBefore,
+		decision.CreatedAt)
+
+	return err
+}
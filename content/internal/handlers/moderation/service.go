@@ -0,0 +1,488 @@
+package moderation
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/lib/pagination"
+	"content/internal/lib/visibility"
+	"content/internal/outbox"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+	"github.com/flores666/profileshare-lib/utils"
+)
+
+type Service interface {
+	// Report files a complaint about content or a comment the viewer may read
+	Report(ctx context.Context, request ReportRequest, viewer visibility.Viewer) api.AppResponse
+	GetQueue(ctx context.Context, filter QueueFilter, userId string) api.AppResponse
+	GetCase(ctx context.Context, id string, userId string) api.AppResponse
+	Claim(ctx context.Context, id string, userId string) api.AppResponse
+	Resolve(ctx context.Context, id string, request ResolveRequest, userId string) api.AppResponse
+	// GetHistory returns all decisions on the item
+	GetHistory(ctx context.Context, targetType string, targetId string, userId string) api.AppResponse
+	// GetDecisions returns decisions on items of the user
+	GetDecisions(ctx context.Context, filter DecisionFilter, userId string) api.AppResponse
+}
+
+// Contents returns the content item if the viewer may read it
+type Contents interface {
+	GetById(ctx context.Context, id string, viewer visibility.Viewer) api.AppResponse
+}
+
+// Items reads content items regardless of visibility, deletions publish their last state
+type Items interface {
+	GetById(ctx context.Context, id string) (*content.Content, error)
+}
+
+// Permissions checks permissions of users with the auth service
+type Permissions interface {
+	HasPermission(ctx context.Context, userId string, permission string) (bool, error)
+}
+
+// PermissionModerate is the permission of the auth service allowed to moderate
+const PermissionModerate = "content.moderate"
+
+type service struct {
+	repository  Repository
+	contents    Contents
+	items       Items
+	permissions Permissions
+	paginator   *pagination.Paginator
+	settings    Settings
+	logger      *slog.Logger
+}
+
+const (
+	ErrFailedSave     = "Не удалось сохранить данные"
+	ErrFailedQuery    = "Не удалось выполнить запрос"
+	ErrValidation     = "Ошибка проверки данных"
+	ErrForbidden      = "Недостаточно прав для модерации"
+	ErrNotFound       = "Жалоба не найдена"
+	ErrTargetNotFound = "Объект жалобы не найден"
+	ErrOwnItem        = "Действие недоступно для своей записи"
+	ErrReported       = "Вы уже пожаловались на эту запись"
+	ErrClaimed        = "Жалоба уже взята в работу другим модератором"
+	ErrNotClaimed     = "Жалоба не взята вами в работу"
+	ErrResolved       = "Жалоба уже рассмотрена"
+	Success           = "Успешно"
+)
+
+func NewService(
+	repository Repository,
+	contents Contents,
+	items Items,
+	permissions Permissions,
+	paginator *pagination.Paginator,
+	settings Settings,
+	logger *slog.Logger,
+) Service {
+	srv := &service{
+		repository:  repository,
+		contents:    contents,
+		items:       items,
+		permissions: permissions,
+		paginator:   paginator,
+		settings:    settings,
+		logger:      logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.moderation.service"))
+
+	return srv
+}
+
+func (s *service) Report(ctx context.Context, request ReportRequest, viewer visibility.Viewer) api.AppResponse {
+	if err := validateReport(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	target, err := s.repository.GetTarget(ctx, request.TargetType, request.TargetId)
+	if err != nil {
+		s.logger.Error("could not get reported item", slog.String("error", err.Error()), slog.String("id", request.TargetId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if target == nil || target.Blocked && target.OwnerId != viewer.UserId {
+		return api.NewError(ErrTargetNotFound, nil)
+	}
+
+	// comments are readable by everyone who may read their content item
+	contentId := request.TargetId
+	if request.TargetType == TargetComment {
+		contentId = target.ContentId
+	}
+
+	if response := s.contents.GetById(ctx, contentId, viewer); !response.Ok() {
+		return response
+	}
+
+	if target.OwnerId == viewer.UserId {
+		return api.NewError(ErrOwnItem, nil)
+	}
+
+	now := time.Now().UTC()
+
+	report := Report{
+		Id:         utils.NewGuid(),
+		ReporterId: viewer.UserId,
+		Reason:     request.Reason,
+		Details:    request.Details,
+		CreatedAt:  now,
+	}
+
+	item := Case{
+		Id:         utils.NewGuid(),
+		TargetType: request.TargetType,
+		TargetId:   request.TargetId,
+		OwnerId:    target.OwnerId,
+	}
+
+	var hidden []outbox.Message
+
+	if request.TargetType == TargetContent {
+		// automatic hiding has no actor
+		messages, ok := s.blockedMessages(ctx, request.TargetId, "", now, true)
+		if !ok {
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		hidden = messages
+	}
+
+	err = s.repository.Report(ctx, report, item, s.settings.AutoHideReports, hidden...)
+	if errors.Is(err, errReported) {
+		return api.NewError(ErrReported, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not save report", slog.String("error", err.Error()), slog.String("id", request.TargetId))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+func (s *service) GetQueue(ctx context.Context, filter QueueFilter, userId string) api.AppResponse {
+	if response, ok := s.checkModerator(ctx, userId); !ok {
+		return response
+	}
+
+	if err := validateQueueFilter(filter); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	filter.ModeratorId = userId
+	filter.StaleBefore = time.Now().UTC().Add(-s.settings.ClaimTimeout)
+
+	list, err := s.repository.QueryCases(ctx, filter, page)
+	if err != nil {
+		s.logger.Error("could not get moderation queue", slog.String("error", err.Error()), slog.Any("filter", filter))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, caseKey)
+
+	return api.NewOk(Success, pagination.PageDto[*CaseDto]{
+		Items:      MapCaseSliceToDto(list),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
+
+func (s *service) GetCase(ctx context.Context, id string, userId string) api.AppResponse {
+	if response, ok := s.checkModerator(ctx, userId); !ok {
+		return response
+	}
+
+	item, response := s.getCase(ctx, id)
+	if item == nil {
+		return response
+	}
+
+	reports, err := s.repository.GetReports(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get reports", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	dto := MapCaseToDto(item)
+	dto.Reports = MapReportSliceToDto(reports)
+
+	return api.NewOk(Success, dto)
+}
+
+func (s *service) Claim(ctx context.Context, id string, userId string) api.AppResponse {
+	if response, ok := s.checkModerator(ctx, userId); !ok {
+		return response
+	}
+
+	item, response := s.getCase(ctx, id)
+	if item == nil {
+		return response
+	}
+
+	if response, ok := checkClaimable(item, userId); !ok {
+		return response
+	}
+
+	now := time.Now().UTC()
+
+	claimed, err := s.repository.Claim(ctx, id, userId, now, now.Add(-s.settings.ClaimTimeout))
+	if err != nil {
+		s.logger.Error("could not claim case", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	if !claimed {
+		return api.NewError(ErrClaimed, nil)
+	}
+
+	item.Status = StatusClaimed
+	item.ClaimedBy = userId
+	item.ClaimedAt = now
+
+	return api.NewOk(Success, MapCaseToDto(item))
+}
+
+func (s *service) Resolve(ctx context.Context, id string, request ResolveRequest, userId string) api.AppResponse {
+	if response, ok := s.checkModerator(ctx, userId); !ok {
+		return response
+	}
+
+	if err := validateResolve(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	item, response := s.getCase(ctx, id)
+	if item == nil {
+		return response
+	}
+
+	if response, ok := checkClaimable(item, userId); !ok {
+		return response
+	}
+
+	decision := Decision{
+		Id:          utils.NewGuid(),
+		CaseId:      item.Id,
+		TargetType:  item.TargetType,
+		TargetId:    item.TargetId,
+		OwnerId:     item.OwnerId,
+		ModeratorId: userId,
+		Action:      request.Action,
+		Reason:      request.Reason,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	var messages []outbox.Message
+
+	if item.TargetType == TargetContent {
+		var changed []outbox.Message
+		ok := true
+
+		switch {
+		case request.Action == ActionDelete:
+			changed, ok = s.deletedMessages(ctx, item.TargetId, decision)
+		case request.Action == ActionHide || request.Action == ActionBan:
+			changed, ok = s.blockedMessages(ctx, item.TargetId, decision.ModeratorId, decision.CreatedAt, true)
+		case request.Action == ActionDismiss && item.AutoHidden:
+			changed, ok = s.blockedMessages(ctx, item.TargetId, decision.ModeratorId, decision.CreatedAt, false)
+		}
+
+		if !ok {
+			return api.NewError(ErrFailedQuery, nil)
+		}
+
+		messages = append(messages, changed...)
+	}
+
+	if request.Action == ActionBan {
+		days := request.BanDays
+		if days == 0 {
+			days = s.settings.BanDays
+		}
+
+		decision.BannedBefore = decision.CreatedAt.AddDate(0, 0, days)
+		messages = append(messages, banRequestedMessage(&decision))
+	}
+
+	err := s.repository.Resolve(ctx, item, decision, messages...)
+	if errors.Is(err, errNotClaimed) {
+		return api.NewError(ErrNotClaimed, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not resolve case", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, MapDecisionToDto(&decision))
+}
+
+func (s *service) GetHistory(ctx context.Context, targetType string, targetId string, userId string) api.AppResponse {
+	if response, ok := s.checkModerator(ctx, userId); !ok {
+		return response
+	}
+
+	if err := validateTarget(targetType, targetId); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	list, err := s.repository.GetHistory(ctx, targetType, targetId)
+	if err != nil {
+		s.logger.Error("could not get moderation history", slog.String("error", err.Error()), slog.String("id", targetId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapDecisionSliceToDto(list))
+}
+
+func (s *service) GetDecisions(ctx context.Context, filter DecisionFilter, userId string) api.AppResponse {
+	if err := validateDecisionFilter(filter); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	page, err := s.paginator.Parse(filter.Cursor, filter.Limit)
+	if err != nil {
+		errs := &api.ValidationErrors{}
+		errs.Add("cursor", "is invalid")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	list, err := s.repository.QueryDecisions(ctx, userId, page)
+	if err != nil {
+		s.logger.Error("could not get decisions", slog.String("error", err.Error()), slog.String("userId", userId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	list, next, prev := pagination.Finish(s.paginator.Codec, page, list, decisionKey)
+
+	items := MapDecisionSliceToDto(list)
+	for _, item := range items {
+		item.ModeratorId = ""
+	}
+
+	return api.NewOk(Success, pagination.PageDto[*DecisionDto]{
+		Items:      items,
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
+
+// banRequestedMessage asks the auth service to ban the owner of the item, it is saved with the decision
+func banRequestedMessage(decision *Decision) outbox.Message {
+	event := UserBanRequestedEvent{
+		DecisionId:   decision.Id,
+		UserId:       decision.OwnerId,
+		ModeratorId:  decision.ModeratorId,
+		Reason:       decision.Reason,
+		BannedBefore: decision.BannedBefore,
+		CreatedAt:    decision.CreatedAt,
+	}
+
+	return outbox.Message{Topic: UserBanRequestedTopic, Key: decision.OwnerId, Payload: event}
+}
+
+// deletedMessages publish the deletion of the content item by the moderator, items already deleted by the owner are skipped
+func (s *service) deletedMessages(ctx context.Context, contentId string, decision Decision) ([]outbox.Message, bool) {
+	target, err := s.items.GetById(ctx, contentId)
+	if err != nil {
+		s.logger.Error("could not get content", slog.String("error", err.Error()), slog.String("id", contentId))
+		return nil, false
+	}
+
+	if target == nil || !target.DeletedAt.IsZero() {
+		return nil, true
+	}
+
+	return []outbox.Message{content.LifecycleMessage(content.ContentDeletedTopic, target, nil, decision.ModeratorId, decision.CreatedAt)}, true
+}
+
+// blockedMessages publish blocking or unblocking of the content item as its update,
+// deleted items and items already in the requested state are skipped as the change does not bump their version
+func (s *service) blockedMessages(ctx context.Context, contentId string, actorId string, now time.Time, blocked bool) ([]outbox.Message, bool) {
+	target, err := s.items.GetById(ctx, contentId)
+	if err != nil {
+		s.logger.Error("could not get content", slog.String("error", err.Error()), slog.String("id", contentId))
+		return nil, false
+	}
+
+	if target == nil || !target.DeletedAt.IsZero() || target.BlockedAt.IsZero() != blocked {
+		return nil, true
+	}
+
+	after := *target
+	after.Version++
+	after.BlockedAt = time.Time{}
+
+	if blocked {
+		after.BlockedAt = now
+	}
+
+	return []outbox.Message{content.LifecycleMessage(content.ContentUpdatedTopic, target, &after, actorId, now)}, true
+}
+
+// checkModerator ensures the auth service grants the user moderation
+func (s *service) checkModerator(ctx context.Context, userId string) (api.AppResponse, bool) {
+	allowed, err := s.permissions.HasPermission(ctx, userId, PermissionModerate)
+	if err != nil {
+		s.logger.Error("could not check user permission", slog.String("error", err.Error()), slog.String("userId", userId))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	if !allowed {
+		return api.NewError(ErrForbidden, nil), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+// getCase returns the case, otherwise nil and an error response
+func (s *service) getCase(ctx context.Context, id string) (*Case, api.AppResponse) {
+	if err := validateId(id); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	item, err := s.repository.GetCase(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get case", slog.String("error", err.Error()), slog.String("id", id))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if item == nil {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	return item, api.AppResponse{}
+}
+
+// checkClaimable ensures the case is not resolved and the moderator does not own the reported item
+func checkClaimable(item *Case, userId string) (api.AppResponse, bool) {
+	if item.Status == StatusResolved {
+		return api.NewError(ErrResolved, nil), false
+	}
+
+	if item.OwnerId == userId {
+		return api.NewError(ErrOwnItem, nil), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+func caseKey(item *Case) pagination.Key {
+	return pagination.Key{CreatedAt: item.CreatedAt, Id: item.Id}
+}
+
+func decisionKey(item *Decision) pagination.Key {
+	return pagination.Key{CreatedAt: item.CreatedAt, Id: item.Id}
+}
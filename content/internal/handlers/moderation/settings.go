@@ -0,0 +1,29 @@
+package moderation
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// AutoHideReports is the number of reports by different users hiding the item until a moderator decides
+	AutoHideReports int
+	// ClaimTimeout returns claimed but not resolved cases to the queue
+	ClaimTimeout time.Duration
+	// BanDays is the ban duration used when the moderator does not set it
+	BanDays int
+}
+
+const (
+	defaultAutoHideReports     = 5
+	defaultClaimTimeoutMinutes = 30
+	defaultBanDays             = 7
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		AutoHideReports: config.MustGetInt("MODERATION__AUTO_HIDE_REPORTS", defaultAutoHideReports),
+		ClaimTimeout:    time.Duration(config.MustGetInt("MODERATION__CLAIM_TIMEOUT_MINUTES", defaultClaimTimeoutMinutes)) * time.Minute,
+		BanDays:         config.MustGetInt("MODERATION__BAN_DAYS", defaultBanDays),
+	}
+}
@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"slices"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+const (
+	maxDetailsLength = 1000
+	maxReasonLength  = 1000
+	maxBanDays       = 3650
+)
+
+var (
+	targetTypes  = []string{TargetContent, TargetComment}
+	reasons      = []string{"spam", "abuse", "hate", "violence", "nudity", "copyright", "other"}
+	caseStatuses = []string{StatusOpen, StatusClaimed, StatusResolved}
+	// actions are taken by moderators, ActionAutoHide is only taken by the service
+	actions = []string{ActionDismiss, ActionHide, ActionDelete, ActionWarn, ActionBan}
+)
+
+func validateReport(request ReportRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if !slices.Contains(targetTypes, request.TargetType) {
+		errs.Add("targetType", "is invalid")
+	}
+
+	if request.TargetId == "" {
+		errs.Add("targetId", "is required")
+	}
+
+	if !slices.Contains(reasons, request.Reason) {
+		errs.Add("reason", "is invalid")
+	}
+
+	if len([]rune(request.Details)) > maxDetailsLength {
+		errs.Add("details", "is too long")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateResolve(request ResolveRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if !slices.Contains(actions, request.Action) {
+		errs.Add("action", "is invalid")
+	}
+
+	if len([]rune(request.Reason)) > maxReasonLength {
+		errs.Add("reason", "is too long")
+	}
+
+	if request.BanDays < 0 || request.BanDays > maxBanDays {
+		errs.Add("banDays", "is invalid")
+	}
+
+	if request.BanDays != 0 && request.Action != ActionBan {
+		errs.Add("banDays", "is allowed only for the ban action")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateQueueFilter(filter QueueFilter) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if filter.Status != "" && !slices.Contains(caseStatuses, filter.Status) {
+		errs.Add("status", "is invalid")
+	}
+
+	if filter.TargetType != "" && !slices.Contains(targetTypes, filter.TargetType) {
+		errs.Add("targetType", "is invalid")
+	}
+
+	if filter.Limit < 0 {
+		errs.Add("limit", "must be positive")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateTarget(targetType string, targetId string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if !slices.Contains(targetTypes, targetType) {
+		errs.Add("targetType", "is invalid")
+	}
+
+	if targetId == "" {
+		errs.Add("targetId", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateId(id string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+	if id == "" {
+		errs.Add("id", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateDecisionFilter(filter DecisionFilter) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if filter.Limit < 0 {
+		errs.Add("limit", "must be positive")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
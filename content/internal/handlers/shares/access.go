@@ -0,0 +1,84 @@
+package shares
+
+import (
+	"content/internal/lib/password"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+// Access checks share links presented to read endpoints of content and folders
+type Access struct {
+	repository Repository
+	settings   Settings
+	logger     *slog.Logger
+}
+
+func NewAccess(repository Repository, settings Settings, logger *slog.Logger) *Access {
+	return &Access{
+		repository: repository,
+		settings:   settings,
+		logger:     logger.With(slog.String("caller", "handlers.shares.access")),
+	}
+}
+
+// Grant returns the active link of the token if the password matches, otherwise nil and an error response
+func (a *Access) Grant(ctx context.Context, token string, sharePassword string) (*ShareLink, api.AppResponse) {
+	link, err := a.repository.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		a.logger.Error("could not get share link", slog.String("error", err.Error()))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if link == nil || !link.Active(time.Now().UTC()) {
+		return nil, api.NewError(ErrLinkInvalid, nil)
+	}
+
+	if link.PasswordHash != "" {
+		// every check costs a 64 MB argon2id hash, so guesses are limited per link
+		since := time.Now().UTC().Add(-a.settings.PasswordWindow)
+		ok, verifyErr := a.repository.VerifyPassword(ctx, link.Id, a.settings.PasswordAttempts, since, func() (bool, error) {
+			return password.Verify(sharePassword, link.PasswordHash)
+		})
+
+		if errors.Is(verifyErr, errTooManyAttempts) {
+			return nil, api.NewError(ErrAttempts, nil)
+		}
+
+		if verifyErr != nil {
+			a.logger.Error("could not verify share password", slog.String("error", verifyErr.Error()), slog.String("id", link.Id))
+			return nil, api.NewError(ErrFailedQuery, nil)
+		}
+
+		if !ok {
+			return nil, api.NewError(ErrPassword, nil)
+		}
+	}
+
+	return link, api.AppResponse{}
+}
+
+// GrantsContent reports whether the link shares the content item directly or through a folder
+func (a *Access) GrantsContent(ctx context.Context, link *ShareLink, contentId string) (bool, error) {
+	if link.ContentId != "" {
+		return link.ContentId == contentId, nil
+	}
+
+	return a.repository.FolderContainsContent(ctx, link.FolderId, contentId)
+}
+
+// GrantsFolder reports whether the link shares the folder or one of its ancestors
+func (a *Access) GrantsFolder(ctx context.Context, link *ShareLink, folderId string) (bool, error) {
+	if link.FolderId == "" {
+		return false, nil
+	}
+
+	if link.FolderId == folderId {
+		return true, nil
+	}
+
+	return a.repository.FolderContainsFolder(ctx, link.FolderId, folderId)
+}
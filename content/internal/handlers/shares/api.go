@@ -0,0 +1,63 @@
+package shares
+
+import (
+	"content/internal/lib/handlers"
+	"net/http"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath      = "/api/shares"
+	errValidation = "Ошибка проверки данных"
+)
+
+var statuses = map[string]int{
+	ErrValidation: http.StatusBadRequest,
+	ErrForbidden:  http.StatusForbidden,
+	ErrNotFound:   http.StatusNotFound,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewSharesHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Get(basePath, h.getByUser)
+		r.Post(basePath, h.create)
+		r.Delete(basePath+"/{id}", h.revoke)
+	})
+}
+
+func (h *Handler) getByUser(w http.ResponseWriter, r *http.Request) {
+	filter := Filter{
+		ContentId: r.URL.Query().Get("contentId"),
+		FolderId:  r.URL.Query().Get("folderId"),
+	}
+
+	handlers.WriteResponse(w, r, h.service.GetByUser(r.Context(), filter, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var request CreateShareRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(errValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Create(r.Context(), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) revoke(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.Revoke(r.Context(), chi.URLParam(r, "id"), handlers.GetUserId(r)), statuses)
+}
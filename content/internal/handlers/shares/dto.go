@@ -0,0 +1,55 @@
+package shares
+
+import "time"
+
+// CreateShareRequest shares either ContentId or FolderId, empty ExpiresAt and Password make the link permanent and open
+type CreateShareRequest struct {
+	ContentId string     `json:"contentId,omitempty"`
+	FolderId  string     `json:"folderId,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Password  string     `json:"password,omitempty"`
+}
+
+// Filter selects links of the user, optionally only links of the content item or folder
+type Filter struct {
+	ContentId string
+	FolderId  string
+}
+
+type ShareLinkDto struct {
+	Id        string `json:"id"`
+	ContentId string `json:"contentId,omitempty"`
+	FolderId  string `json:"folderId,omitempty"`
+	// Token is returned only when the link is created
+	Token       string     `json:"token,omitempty"`
+	HasPassword bool       `json:"hasPassword"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func MapShareLinkToDto(model *ShareLink) ShareLinkDto {
+	if model == nil {
+		return ShareLinkDto{}
+	}
+
+	return ShareLinkDto{
+		Id:          model.Id,
+		ContentId:   model.ContentId,
+		FolderId:    model.FolderId,
+		HasPassword: model.PasswordHash != "",
+		ExpiresAt:   model.ExpiresAt,
+		RevokedAt:   model.RevokedAt,
+		CreatedAt:   model.CreatedAt,
+	}
+}
+
+func MapShareLinkSliceToDto(links []*ShareLink) []*ShareLinkDto {
+	result := make([]*ShareLinkDto, 0, len(links))
+	for _, model := range links {
+		dto := MapShareLinkToDto(model)
+		result = append(result, &dto)
+	}
+
+	return result
+}
@@ -0,0 +1,21 @@
+package shares
+
+import "time"
+
+// ShareLink grants read access to a single content item or a folder with nested folders
+type ShareLink struct {
+	Id           string     `db:"id"`
+	UserId       string     `db:"user_id"`
+	ContentId    string     `db:"content_id"`
+	FolderId     string     `db:"folder_id"`
+	TokenHash    string     `db:"token_hash"`
+	PasswordHash string     `db:"password_hash"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	RevokedAt    *time.Time `db:"revoked_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// Active reports whether the link is neither revoked nor expired at the moment
+func (l *ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
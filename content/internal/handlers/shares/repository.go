@@ -0,0 +1,216 @@
+package shares
+
+import (
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	Create(ctx context.Context, link ShareLink) error
+	GetById(ctx context.Context, id string) (*ShareLink, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*ShareLink, error)
+	Query(ctx context.Context, userId string, filter Filter) ([]*ShareLink, error)
+	Revoke(ctx context.Context, id string) error
+	// GetOwner returns the owner of the not deleted content item or folder, empty when it does not exist
+	GetOwner(ctx context.Context, contentId string, folderId string) (string, error)
+	// FolderContainsContent reports whether the content is linked to the folder or its nested folders
+	FolderContainsContent(ctx context.Context, folderId string, contentId string) (bool, error)
+	// FolderContainsFolder reports whether the folder is the root folder or nested into it
+	FolderContainsFolder(ctx context.Context, rootId string, folderId string) (bool, error)
+	// VerifyPassword runs verify unless the link had limit failed attempts after since, failed attempts are recorded.
+	// The attempt is counted before verify runs outside of the transaction, so concurrent guesses cannot exceed the limit
+	VerifyPassword(ctx context.Context, linkId string, limit int, since time.Time, verify func() (bool, error)) (bool, error)
+}
+
+// errTooManyAttempts means the password of the link was guessed too many times recently
+var errTooManyAttempts = errors.New("too many password attempts")
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+const selectLinks = `
+	SELECT
+		id, user_id, COALESCE(content_id::text, '') AS content_id, COALESCE(folder_id::text, '') AS folder_id,
+		token_hash, COALESCE(password_hash, '') AS password_hash, expires_at, revoked_at, created_at
+	FROM content.share_links`
+
+// subtree selects the folder $1 and all its nested folders
+const subtree = `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM content.folders WHERE id = $1
+		UNION ALL
+		SELECT f.id FROM content.folders f JOIN subtree s ON f.parent_id = s.id
+	)`
+
+func (r *repository) Create(ctx context.Context, link ShareLink) error {
+	query := `
+		INSERT INTO content.share_links (id, user_id, content_id, folder_id, token_hash, password_hash, expires_at, created_at)
+		VALUES ($1,$2,NULLIF($3, '')::uuid,NULLIF($4, '')::uuid,$5,NULLIF($6, ''),$7,$8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		link.Id,
+		link.UserId,
+		link.ContentId,
+		link.FolderId,
+		link.TokenHash,
+		link.PasswordHash,
+		link.ExpiresAt,
+		link.CreatedAt)
+
+	return err
+}
+
+func (r *repository) GetById(ctx context.Context, id string) (*ShareLink, error) {
+	return r.get(ctx, selectLinks+` WHERE id = $1`, id)
+}
+
+func (r *repository) GetByTokenHash(ctx context.Context, tokenHash string) (*ShareLink, error) {
+	return r.get(ctx, selectLinks+` WHERE token_hash = $1`, tokenHash)
+}
+
+func (r *repository) Query(ctx context.Context, userId string, filter Filter) ([]*ShareLink, error) {
+	query := selectLinks + ` WHERE user_id = $1`
+	args := []any{userId}
+
+	if filter.ContentId != "" {
+		args = append(args, filter.ContentId)
+		query += ` AND content_id = $2`
+	} else if filter.FolderId != "" {
+		args = append(args, filter.FolderId)
+		query += ` AND folder_id = $2`
+	}
+
+	query += ` ORDER BY created_at DESC`
+
+	var result []*ShareLink
+
+	err := r.db.SelectContext(ctx, &result, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE content.share_links SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *repository) GetOwner(ctx context.Context, contentId string, folderId string) (string, error) {
+	query := `SELECT CAST(user_id AS text) FROM content.folders WHERE id = $1`
+	id := folderId
+
+	if contentId != "" {
+		query = `SELECT CAST(user_id AS text) FROM content.content WHERE id = $1 AND deleted_at IS NULL`
+		id = contentId
+	}
+
+	var owner string
+	err := r.db.GetContext(ctx, &owner, query, id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return owner, err
+}
+
+func (r *repository) FolderContainsContent(ctx context.Context, folderId string, contentId string) (bool, error) {
+	query := subtree + `
+		SELECT EXISTS (
+			SELECT 1 FROM content.folders_contents
+			WHERE content_id = $2 AND folder_id IN (SELECT id FROM subtree)
+		)`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, folderId, contentId)
+
+	return exists, err
+}
+
+func (r *repository) FolderContainsFolder(ctx context.Context, rootId string, folderId string) (bool, error) {
+	query := subtree + ` SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, rootId, folderId)
+
+	return exists, err
+}
+
+func (r *repository) get(ctx context.Context, query string, args ...any) (*ShareLink, error) {
+	var link ShareLink
+	err := r.db.GetContext(ctx, &link, query, args...)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (r *repository) VerifyPassword(ctx context.Context, linkId string, limit int, since time.Time, verify func() (bool, error)) (bool, error) {
+	attemptedAt, err := r.reserveAttempt(ctx, linkId, limit, since)
+	if err != nil {
+		return false, err
+	}
+
+	// the password is hashed slowly, so it is verified without holding the lock
+	ok, err := verify()
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `DELETE FROM content.share_link_attempts WHERE link_id = $1 AND created_at = $2`, linkId, attemptedAt)
+	return ok, err
+}
+
+// reserveAttempt records the attempt as failed unless the link had limit failed attempts after since
+// and returns its time, the attempt is forgotten when the password is correct
+func (r *repository) reserveAttempt(ctx context.Context, linkId string, limit int, since time.Time) (time.Time, error) {
+	var attemptedAt time.Time
+
+	err := postgresql.Transaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		// the lock serializes checks of the link, so concurrent guesses cannot exceed the limit
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "content.share_links:"+linkId); err != nil {
+			return err
+		}
+
+		var failed int
+		query := `SELECT COUNT(*) FROM content.share_link_attempts WHERE link_id = $1 AND created_at > $2`
+
+		if err := tx.GetContext(ctx, &failed, query, linkId, since); err != nil {
+			return err
+		}
+
+		if failed >= limit {
+			return errTooManyAttempts
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM content.share_link_attempts WHERE link_id = $1 AND created_at <= $2`, linkId, since); err != nil {
+			return err
+		}
+
+		// attempts of the link are serialized by the lock, so the clock time identifies the attempt
+		query = `INSERT INTO content.share_link_attempts (link_id, created_at) VALUES ($1, clock_timestamp()) RETURNING created_at`
+		return tx.GetContext(ctx, &attemptedAt, query, linkId)
+	})
+
+	return attemptedAt, err
+}
@@ -0,0 +1,157 @@
+package shares
+
+import (
+	"content/internal/lib/password"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+	"github.com/flores666/profileshare-lib/utils"
+)
+
+type Service interface {
+	Create(ctx context.Context, request CreateShareRequest, userId string) api.AppResponse
+	GetByUser(ctx context.Context, filter Filter, userId string) api.AppResponse
+	Revoke(ctx context.Context, id string, userId string) api.AppResponse
+}
+
+type service struct {
+	repository Repository
+	logger     *slog.Logger
+}
+
+const (
+	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	ErrForbidden   = "Ссылка вам не принадлежит"
+	ErrNotFound    = "Ссылка не найдена"
+	ErrLinkInvalid = "Ссылка недействительна или истекла"
+	ErrPassword    = "Неверный пароль ссылки"
+	ErrAttempts    = "Слишком много попыток ввода пароля, повторите позже"
+	Success        = "Успешно"
+)
+
+const tokenLength = 32
+
+func NewService(repository Repository, logger *slog.Logger) Service {
+	srv := &service{
+		repository: repository,
+		logger:     logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.shares.service"))
+
+	return srv
+}
+
+func (s *service) Create(ctx context.Context, request CreateShareRequest, userId string) api.AppResponse {
+	now := time.Now().UTC()
+
+	if err := validateCreate(request, now); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	owner, err := s.repository.GetOwner(ctx, request.ContentId, request.FolderId)
+	if err != nil {
+		s.logger.Error("could not get shared item owner", slog.String("error", err.Error()))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if owner == "" {
+		errs := &api.ValidationErrors{}
+		errs.Add("contentId", "content or folder not found")
+		return api.NewError(ErrValidation, errs)
+	}
+
+	if owner != userId {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		s.logger.Error("could not generate share token", slog.String("error", err.Error()))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	link := ShareLink{
+		Id:        utils.NewGuid(),
+		UserId:    userId,
+		ContentId: request.ContentId,
+		FolderId:  request.FolderId,
+		TokenHash: hashToken(token),
+		ExpiresAt: request.ExpiresAt,
+		CreatedAt: now,
+	}
+
+	if request.Password != "" {
+		link.PasswordHash = password.Hash(request.Password)
+	}
+
+	if err = s.repository.Create(ctx, link); err != nil {
+		s.logger.Error("could not create share link", slog.String("error", err.Error()))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	dto := MapShareLinkToDto(&link)
+	dto.Token = token
+
+	return api.NewOk(Success, dto)
+}
+
+func (s *service) GetByUser(ctx context.Context, filter Filter, userId string) api.AppResponse {
+	list, err := s.repository.Query(ctx, userId, filter)
+	if err != nil {
+		s.logger.Error("could not get share links", slog.String("error", err.Error()), slog.String("userId", userId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapShareLinkSliceToDto(list))
+}
+
+func (s *service) Revoke(ctx context.Context, id string, userId string) api.AppResponse {
+	if err := validateId(id); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	link, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		s.logger.Error("could not get share link", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	if link == nil {
+		return api.NewError(ErrNotFound, nil)
+	}
+
+	if link.UserId != userId {
+		return api.NewError(ErrForbidden, nil)
+	}
+
+	if err = s.repository.Revoke(ctx, id); err != nil {
+		s.logger.Error("could not revoke share link", slog.String("error", err.Error()), slog.String("id", id))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	return api.NewOk(Success, nil)
+}
+
+// newToken returns a random url safe token, only its hash is stored
+func newToken() (string, error) {
+	buf := make([]byte, tokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,24 @@
+package shares
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// PasswordAttempts is the number of wrong passwords of a link accepted within PasswordWindow
+	PasswordAttempts int
+	PasswordWindow   time.Duration
+}
+
+const (
+	defaultPasswordAttempts      = 10
+	defaultPasswordWindowMinutes = 15
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		PasswordAttempts: config.MustGetPositiveInt("SHARES__PASSWORD_ATTEMPTS", defaultPasswordAttempts),
+		PasswordWindow:   time.Duration(config.MustGetPositiveInt("SHARES__PASSWORD_WINDOW_MINUTES", defaultPasswordWindowMinutes)) * time.Minute,
+	}
+}
@@ -0,0 +1,44 @@
+package shares
+
+import (
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+const maxPasswordLength = 128
+
+func validateCreate(request CreateShareRequest, now time.Time) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if (request.ContentId == "") == (request.FolderId == "") {
+		errs.Add("contentId", "either contentId or folderId is required")
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(now) {
+		errs.Add("expiresAt", "must be in the future")
+	}
+
+	if len([]rune(request.Password)) > maxPasswordLength {
+		errs.Add("password", "must be at most 128 characters")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateId(id string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+	if id == "" {
+		errs.Add("id", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
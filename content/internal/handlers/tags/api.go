@@ -0,0 +1,68 @@
+package tags
+
+import (
+	"content/internal/lib/handlers"
+	"net/http"
+	"strconv"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const basePath = "/api/tags"
+
+var statuses = map[string]int{
+	ErrValidation: http.StatusBadRequest,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewTagsHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Group(func(r chi.Router) {
+		r.Use(handlers.OptionalAuth(authMiddleware))
+		r.Get(basePath, h.getByUser)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Get(basePath+"/autocomplete", h.autocomplete)
+	})
+}
+
+func (h *Handler) getByUser(w http.ResponseWriter, r *http.Request) {
+	filter := ListFilter{
+		UserId:   r.URL.Query().Get("userId"),
+		ViewerId: handlers.GetUserId(r),
+	}
+
+	handlers.WriteResponse(w, r, h.service.GetByUser(r.Context(), filter), statuses)
+}
+
+func (h *Handler) autocomplete(w http.ResponseWriter, r *http.Request) {
+	filter := AutocompleteFilter{
+		Prefix: r.URL.Query().Get("q"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			errs := &api.ValidationErrors{}
+			errs.Add("limit", "должно быть числом")
+			handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, errs))
+			return
+		}
+
+		filter.Limit = value
+	}
+
+	handlers.WriteResponse(w, r, h.service.Autocomplete(r.Context(), filter, handlers.GetUserId(r)), statuses)
+}
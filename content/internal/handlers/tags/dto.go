@@ -0,0 +1,30 @@
+package tags
+
+type TagDto struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ListFilter selects tags of the user's items visible to the viewer
+type ListFilter struct {
+	UserId   string
+	ViewerId string
+}
+
+// AutocompleteFilter selects tags starting with Prefix, the user's own tags come first
+type AutocompleteFilter struct {
+	Prefix string
+	Limit  int
+}
+
+func MapTagSliceToDto(list []*Tag) []*TagDto {
+	result := make([]*TagDto, 0, len(list))
+	for _, model := range list {
+		result = append(result, &TagDto{
+			Name:  model.Name,
+			Count: model.Count,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,7 @@
+package tags
+
+// Tag is the tag name with the number of items marked with it
+type Tag struct {
+	Name  string `db:"name"`
+	Count int    `db:"count"`
+}
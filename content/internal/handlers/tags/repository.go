@@ -0,0 +1,84 @@
+package tags
+
+import (
+	"content/internal/lib/visibility"
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	// GetByUser returns tags of the user's items visible to the viewer with the number of such items
+	GetByUser(ctx context.Context, userId string, viewerId string) ([]*Tag, error)
+	// Autocomplete returns tags starting with the prefix ordered by the number of the user's items marked with them
+	Autocomplete(ctx context.Context, prefix string, userId string, limit int) ([]*Tag, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) GetByUser(ctx context.Context, userId string, viewerId string) ([]*Tag, error) {
+	query := `
+		SELECT t.name, COUNT(*) AS count
+		FROM content.content c
+		JOIN content.content_tags ct ON ct.content_id = c.id
+		JOIN content.tags t ON t.id = ct.tag_id
+		WHERE c.user_id = :user_id AND c.deleted_at IS NULL AND c.blocked_at IS NULL
+			AND ` + visibility.ListCondition("c.user_id", "c.visibility") + `
+		GROUP BY t.name
+		ORDER BY count DESC, t.name`
+
+	query, args, err := sqlx.Named(query, map[string]any{
+		"user_id":   userId,
+		"viewer_id": viewerId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Tag
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Autocomplete(ctx context.Context, prefix string, userId string, limit int) ([]*Tag, error) {
+	// the prefix match uses the text_pattern_ops index on the tag name
+	query := `
+		SELECT t.name, COALESCE(u.count, 0) AS count
+		FROM content.tags t
+		LEFT JOIN (
+			SELECT ct.tag_id, COUNT(*) AS count
+			FROM content.content c
+			JOIN content.content_tags ct ON ct.content_id = c.id
+			WHERE c.user_id = $2 AND c.deleted_at IS NULL
+			GROUP BY ct.tag_id
+		) u ON u.tag_id = t.id
+		WHERE t.name LIKE $1 ESCAPE '\'
+		ORDER BY count DESC, t.name
+		LIMIT $3`
+
+	var result []*Tag
+
+	err := r.db.SelectContext(ctx, &result, query, escapeLike(prefix)+"%", userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// escapeLike escapes wildcards of the LIKE pattern, normalized tags may contain _
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,74 @@
+package tags
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+type Service interface {
+	GetByUser(ctx context.Context, filter ListFilter) api.AppResponse
+	Autocomplete(ctx context.Context, filter AutocompleteFilter, userId string) api.AppResponse
+}
+
+type service struct {
+	repository Repository
+	logger     *slog.Logger
+}
+
+const (
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	Success        = "Успешно"
+)
+
+func NewService(repository Repository, logger *slog.Logger) Service {
+	srv := &service{
+		repository: repository,
+		logger:     logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.tags.service"))
+
+	return srv
+}
+
+func (s *service) GetByUser(ctx context.Context, filter ListFilter) api.AppResponse {
+	if err := validateList(filter); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	list, err := s.repository.GetByUser(ctx, filter.UserId, filter.ViewerId)
+	if err != nil {
+		s.logger.Error("could not get user tags", slog.String("error", err.Error()), slog.String("userId", filter.UserId))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapTagSliceToDto(list))
+}
+
+func (s *service) Autocomplete(ctx context.Context, filter AutocompleteFilter, userId string) api.AppResponse {
+	if err := validateAutocomplete(filter); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	// the prefix is normalized like tags, so "#Go Lang" completes "go-lang"
+	prefix, ok := normalize(filter.Prefix)
+	if !ok {
+		return api.NewOk(Success, make([]*TagDto, 0))
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultAutocompleteLimit
+	}
+
+	list, err := s.repository.Autocomplete(ctx, prefix, userId, limit)
+	if err != nil {
+		s.logger.Error("could not autocomplete tags", slog.String("error", err.Error()), slog.String("prefix", prefix))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapTagSliceToDto(list))
+}
@@ -0,0 +1,99 @@
+package tags
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+const (
+	// MaxPerItem is the maximum number of tags of one item
+	MaxPerItem = 20
+	// MaxLength is the maximum length of a tag in characters
+	MaxLength = 50
+
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+)
+
+// Normalize converts tags to the stored form: lower case without the leading #, spaces replaced with -,
+// only letters, digits, - and _ are allowed. Duplicates are removed and the result is sorted.
+func Normalize(values []string, field string) ([]string, *api.ValidationErrors) {
+	errs := &api.ValidationErrors{}
+	result := make([]string, 0, len(values))
+
+	for _, value := range values {
+		name, ok := normalize(value)
+		if !ok {
+			errs.Add(field, "must contain only letters, digits, - and _ and be at most 50 characters")
+			continue
+		}
+
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+
+	if len(result) > MaxPerItem {
+		errs.Add(field, "must contain at most 20 tags")
+	}
+
+	if !errs.Ok() {
+		return nil, errs
+	}
+
+	slices.Sort(result)
+
+	return result, nil
+}
+
+func normalize(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "#"))
+	value = strings.Join(strings.Fields(value), "-")
+
+	if value == "" || len([]rune(value)) > MaxLength {
+		return "", false
+	}
+
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", false
+		}
+	}
+
+	return value, true
+}
+
+func validateList(filter ListFilter) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if filter.UserId == "" {
+		errs.Add("userId", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateAutocomplete(filter AutocompleteFilter) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if filter.Prefix == "" {
+		errs.Add("q", "is required")
+	}
+
+	if filter.Limit < 0 || filter.Limit > maxAutocompleteLimit {
+		errs.Add("limit", "must be between 1 and 50")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"content/internal/lib/handlers"
+	"net/http"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const basePath = "/api/content-types"
+
+var statuses = map[string]int{
+	ErrValidation: http.StatusBadRequest,
+	ErrForbidden:  http.StatusForbidden,
+	ErrNotFound:   http.StatusNotFound,
+	ErrNameTaken:  http.StatusConflict,
+	ErrInUse:      http.StatusConflict,
+	ErrBuiltIn:    http.StatusConflict,
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewTypesHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware func(http.Handler) http.Handler) {
+	r.Get(basePath, h.getAll)
+	r.Get(basePath+"/{name}", h.getByName)
+
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Post(basePath, h.create)
+		r.Put(basePath+"/{name}", h.update)
+		r.Delete(basePath+"/{name}", h.delete)
+	})
+}
+
+func (h *Handler) getAll(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetAll(r.Context()), statuses)
+}
+
+func (h *Handler) getByName(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.GetByName(r.Context(), chi.URLParam(r, "name")), statuses)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var request CreateTypeRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Create(r.Context(), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
+	var request UpdateTypeRequest
+	if err := api.GetBodyWithValidation(r, &request); err != nil {
+		handlers.Respond(w, r, http.StatusBadRequest, api.NewError(ErrValidation, nil))
+		return
+	}
+
+	handlers.WriteResponse(w, r, h.service.Update(r.Context(), chi.URLParam(r, "name"), request, handlers.GetUserId(r)), statuses)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	handlers.WriteResponse(w, r, h.service.Delete(r.Context(), chi.URLParam(r, "name"), handlers.GetUserId(r)), statuses)
+}
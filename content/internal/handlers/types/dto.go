@@ -0,0 +1,63 @@
+package types
+
+import "time"
+
+type CreateTypeRequest struct {
+	Name        string `json:"name" validate:"required"`
+	DisplayName string `json:"displayName" validate:"required"`
+	// MimeTypes are accepted media types of uploads, an empty list means items of the type have no media
+	MimeTypes []string `json:"mimeTypes,omitempty"`
+	// MaxSize is the upload limit in bytes, required with MimeTypes
+	MaxSize        int64    `json:"maxSize,omitempty"`
+	RequiredFields []string `json:"requiredFields,omitempty"`
+	TextAllowed    bool     `json:"textAllowed"`
+}
+
+// UpdateTypeRequest replaces all settings of the type
+type UpdateTypeRequest struct {
+	DisplayName    string   `json:"displayName" validate:"required"`
+	MimeTypes      []string `json:"mimeTypes,omitempty"`
+	MaxSize        int64    `json:"maxSize,omitempty"`
+	RequiredFields []string `json:"requiredFields,omitempty"`
+	TextAllowed    bool     `json:"textAllowed"`
+}
+
+type ContentTypeDto struct {
+	Name           string    `json:"name"`
+	DisplayName    string    `json:"displayName"`
+	MimeTypes      []string  `json:"mimeTypes"`
+	MaxSize        int64     `json:"maxSize"`
+	RequiredFields []string  `json:"requiredFields"`
+	TextAllowed    bool      `json:"textAllowed"`
+	BuiltIn        bool      `json:"builtIn"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func MapTypeToDto(item *ContentType) *ContentTypeDto {
+	dto := &ContentTypeDto{
+		Name:           item.Name,
+		DisplayName:    item.DisplayName,
+		MimeTypes:      make([]string, 0),
+		MaxSize:        item.MaxSize,
+		RequiredFields: make([]string, 0),
+		TextAllowed:    item.TextAllowed,
+		BuiltIn:        item.BuiltIn,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+	}
+
+	dto.MimeTypes = append(dto.MimeTypes, split(item.MimeTypes)...)
+	dto.RequiredFields = append(dto.RequiredFields, split(item.RequiredFields)...)
+
+	return dto
+}
+
+func MapTypeSliceToDto(items []*ContentType) []*ContentTypeDto {
+	result := make([]*ContentTypeDto, 0, len(items))
+	for _, item := range items {
+		result = append(result, MapTypeToDto(item))
+	}
+
+	return result
+}
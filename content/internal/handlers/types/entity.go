@@ -0,0 +1,77 @@
+package types
+
+import (
+	"mime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Fields of content a type may require
+const (
+	FieldText     = "text"
+	FieldMediaId  = "mediaId"
+	FieldMediaUrl = "mediaUrl"
+)
+
+// Built-in types are referenced by the code, e.g. photos are processed by the image worker
+const (
+	TypePhoto    = "photo"
+	TypeVideo    = "video"
+	TypeAudio    = "audio"
+	TypeDocument = "document"
+	TypeLink     = "link"
+	TypeText     = "text"
+)
+
+// PhotoMimeTypes are formats the image worker strips metadata from,
+// photos of other formats, e.g. HEIC, would be served with EXIF and GPS
+var PhotoMimeTypes = []string{"image/jpeg", "image/png", "image/webp", "image/gif"}
+
+// ContentType is a kind of content with the rules its items and uploads are validated by
+type ContentType struct {
+	Name        string `db:"name"`
+	DisplayName string `db:"display_name"`
+	// MimeTypes are sniffed media types accepted for uploads joined with commas, types without them have no media
+	MimeTypes string `db:"mime_types"`
+	// MaxSize is the upload limit in bytes
+	MaxSize int64 `db:"max_size"`
+	// RequiredFields are names of fields required by items of the type joined with commas
+	RequiredFields string `db:"required_fields"`
+	TextAllowed    bool   `db:"text_allowed"`
+	// BuiltIn types cannot be deleted
+	BuiltIn   bool      `db:"built_in"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// HasMedia reports whether items of the type are uploaded files
+func (t *ContentType) HasMedia() bool {
+	return t.MimeTypes != ""
+}
+
+// AllowsMime reports whether the sniffed media type may be uploaded for the type
+func (t *ContentType) AllowsMime(contentType string) bool {
+	base, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if t.Name == TypePhoto && !slices.Contains(PhotoMimeTypes, base) {
+		return false
+	}
+
+	return slices.Contains(split(t.MimeTypes), base)
+}
+
+func (t *ContentType) Requires(field string) bool {
+	return slices.Contains(split(t.RequiredFields), field)
+}
+
+func split(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
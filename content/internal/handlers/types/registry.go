@@ -0,0 +1,69 @@
+package types
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Registry reads content types through a cache, all types are reloaded when the cache is older than
+// Settings.CacheLifetime. Changes made through the service reset the cache of this replica at once.
+type Registry struct {
+	repository Repository
+	lifetime   time.Duration
+
+	mu       sync.RWMutex
+	items    map[string]*ContentType
+	loadedAt time.Time
+}
+
+func NewRegistry(repository Repository, settings Settings) *Registry {
+	return &Registry{
+		repository: repository,
+		lifetime:   settings.CacheLifetime,
+	}
+}
+
+// Get returns the type or nil if it does not exist, returned types must not be changed
+func (r *Registry) Get(ctx context.Context, name string) (*ContentType, error) {
+	items, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return items[name], nil
+}
+
+// Reset makes the next Get reload the types
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items = nil
+}
+
+func (r *Registry) load(ctx context.Context) (map[string]*ContentType, error) {
+	r.mu.RLock()
+	items, loadedAt := r.items, r.loadedAt
+	r.mu.RUnlock()
+
+	if items != nil && time.Since(loadedAt) < r.lifetime {
+		return items, nil
+	}
+
+	list, err := r.repository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items = make(map[string]*ContentType, len(list))
+	for _, item := range list {
+		items[item.Name] = item
+	}
+
+	r.mu.Lock()
+	r.items, r.loadedAt = items, time.Now()
+	r.mu.Unlock()
+
+	return items, nil
+}
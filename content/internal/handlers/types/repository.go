@@ -0,0 +1,103 @@
+package types
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errNameTaken = errors.New("content type name is already taken")
+	errInUse     = errors.New("content type is used by content or media")
+)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]*ContentType, error)
+	GetByName(ctx context.Context, name string) (*ContentType, error)
+	Create(ctx context.Context, item ContentType) error
+	// Update changes settings of the type, the name is kept
+	Update(ctx context.Context, item ContentType) error
+	// Delete removes the type if no content or media uses it
+	Delete(ctx context.Context, name string) error
+}
+
+const selectTypes = `
+	SELECT name, display_name, mime_types, max_size, required_fields, text_allowed, built_in, created_at, updated_at
+	FROM content.content_types`
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]*ContentType, error) {
+	var result []*ContentType
+
+	err := r.db.SelectContext(ctx, &result, selectTypes+` ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) GetByName(ctx context.Context, name string) (*ContentType, error) {
+	var item ContentType
+
+	err := r.db.GetContext(ctx, &item, selectTypes+` WHERE name = $1`, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (r *repository) Create(ctx context.Context, item ContentType) error {
+	query := `
+		INSERT INTO content.content_types (name, display_name, mime_types, max_size, required_fields, text_allowed, built_in, created_at, updated_at)
+		VALUES (:name, :display_name, :mime_types, :max_size, :required_fields, :text_allowed, false, :created_at, :updated_at)`
+
+	_, err := r.db.NamedExecContext(ctx, query, item)
+	return mapError(err)
+}
+
+func (r *repository) Update(ctx context.Context, item ContentType) error {
+	query := `
+		UPDATE content.content_types
+		SET display_name = :display_name, mime_types = :mime_types, max_size = :max_size,
+			required_fields = :required_fields, text_allowed = :text_allowed, updated_at = :updated_at
+		WHERE name = :name`
+
+	_, err := r.db.NamedExecContext(ctx, query, item)
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, name string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM content.content_types WHERE name = $1 AND NOT built_in`, name)
+	return mapError(err)
+}
+
+// mapError maps the unique violation of the name and foreign key violations of content and media
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return errNameTaken
+		case "23503":
+			return errInUse
+		}
+	}
+
+	return err
+}
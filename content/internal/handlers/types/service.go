@@ -0,0 +1,224 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+type Service interface {
+	GetAll(ctx context.Context) api.AppResponse
+	GetByName(ctx context.Context, name string) api.AppResponse
+	Create(ctx context.Context, request CreateTypeRequest, userId string) api.AppResponse
+	Update(ctx context.Context, name string, request UpdateTypeRequest, userId string) api.AppResponse
+	Delete(ctx context.Context, name string, userId string) api.AppResponse
+}
+
+// Permissions checks permissions of users with the auth service
+type Permissions interface {
+	HasPermission(ctx context.Context, userId string, permission string) (bool, error)
+}
+
+// PermissionManage is the permission of the auth service allowed to manage types
+const PermissionManage = "content.types.manage"
+
+type service struct {
+	repository  Repository
+	registry    *Registry
+	permissions Permissions
+	settings    Settings
+	logger      *slog.Logger
+}
+
+const (
+	ErrFailedSave  = "Не удалось сохранить данные"
+	ErrFailedQuery = "Не удалось выполнить запрос"
+	ErrValidation  = "Ошибка проверки данных"
+	ErrForbidden   = "Недостаточно прав для управления типами контента"
+	ErrNotFound    = "Тип контента не найден"
+	ErrNameTaken   = "Тип контента с таким названием уже существует"
+	ErrInUse       = "Тип контента используется записями или файлами"
+	ErrBuiltIn     = "Встроенный тип контента нельзя удалить"
+	Success        = "Успешно"
+)
+
+func NewService(repository Repository, registry *Registry, permissions Permissions, settings Settings, logger *slog.Logger) Service {
+	srv := &service{
+		repository:  repository,
+		registry:    registry,
+		permissions: permissions,
+		settings:    settings,
+		logger:      logger,
+	}
+
+	srv.logger = srv.logger.With(slog.String("caller", "handlers.types.service"))
+
+	return srv
+}
+
+func (s *service) GetAll(ctx context.Context) api.AppResponse {
+	list, err := s.repository.GetAll(ctx)
+	if err != nil {
+		s.logger.Error("could not get content types", slog.String("error", err.Error()))
+		return api.NewError(ErrFailedQuery, nil)
+	}
+
+	return api.NewOk(Success, MapTypeSliceToDto(list))
+}
+
+func (s *service) GetByName(ctx context.Context, name string) api.AppResponse {
+	item, response := s.get(ctx, name)
+	if item == nil {
+		return response
+	}
+
+	return api.NewOk(Success, MapTypeToDto(item))
+}
+
+func (s *service) Create(ctx context.Context, request CreateTypeRequest, userId string) api.AppResponse {
+	if response, ok := s.checkAdmin(ctx, userId); !ok {
+		return response
+	}
+
+	if err := validateCreate(request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	now := time.Now().UTC()
+
+	model := ContentType{
+		Name:           request.Name,
+		DisplayName:    strings.TrimSpace(request.DisplayName),
+		MimeTypes:      join(request.MimeTypes),
+		MaxSize:        request.MaxSize,
+		RequiredFields: join(request.RequiredFields),
+		TextAllowed:    request.TextAllowed,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	err := s.repository.Create(ctx, model)
+	if errors.Is(err, errNameTaken) {
+		return api.NewError(ErrNameTaken, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not create content type", slog.String("error", err.Error()), slog.String("name", request.Name))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	s.registry.Reset()
+
+	return api.NewOk(Success, MapTypeToDto(&model))
+}
+
+func (s *service) Update(ctx context.Context, name string, request UpdateTypeRequest, userId string) api.AppResponse {
+	if response, ok := s.checkAdmin(ctx, userId); !ok {
+		return response
+	}
+
+	if err := validateUpdate(name, request); err != nil {
+		return api.NewError(ErrValidation, err)
+	}
+
+	item, response := s.get(ctx, name)
+	if item == nil {
+		return response
+	}
+
+	item.DisplayName = strings.TrimSpace(request.DisplayName)
+	item.MimeTypes = join(request.MimeTypes)
+	item.MaxSize = request.MaxSize
+	item.RequiredFields = join(request.RequiredFields)
+	item.TextAllowed = request.TextAllowed
+	item.UpdatedAt = time.Now().UTC()
+
+	if err := s.repository.Update(ctx, *item); err != nil {
+		s.logger.Error("could not update content type", slog.String("error", err.Error()), slog.String("name", name))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	s.registry.Reset()
+
+	return api.NewOk(Success, MapTypeToDto(item))
+}
+
+func (s *service) Delete(ctx context.Context, name string, userId string) api.AppResponse {
+	if response, ok := s.checkAdmin(ctx, userId); !ok {
+		return response
+	}
+
+	item, response := s.get(ctx, name)
+	if item == nil {
+		return response
+	}
+
+	if item.BuiltIn {
+		return api.NewError(ErrBuiltIn, nil)
+	}
+
+	err := s.repository.Delete(ctx, name)
+	if errors.Is(err, errInUse) {
+		return api.NewError(ErrInUse, nil)
+	}
+
+	if err != nil {
+		s.logger.Error("could not delete content type", slog.String("error", err.Error()), slog.String("name", name))
+		return api.NewError(ErrFailedSave, nil)
+	}
+
+	s.registry.Reset()
+
+	return api.NewOk(Success, nil)
+}
+
+// get returns the type read from the database, otherwise nil and an error response
+func (s *service) get(ctx context.Context, name string) (*ContentType, api.AppResponse) {
+	if err := validateName(name); err != nil {
+		return nil, api.NewError(ErrValidation, err)
+	}
+
+	item, err := s.repository.GetByName(ctx, name)
+	if err != nil {
+		s.logger.Error("could not get content type", slog.String("error", err.Error()), slog.String("name", name))
+		return nil, api.NewError(ErrFailedQuery, nil)
+	}
+
+	if item == nil {
+		return nil, api.NewError(ErrNotFound, nil)
+	}
+
+	return item, api.AppResponse{}
+}
+
+// checkAdmin ensures the auth service grants the user managing types
+func (s *service) checkAdmin(ctx context.Context, userId string) (api.AppResponse, bool) {
+	allowed, err := s.permissions.HasPermission(ctx, userId, PermissionManage)
+	if err != nil {
+		s.logger.Error("could not check user permission", slog.String("error", err.Error()), slog.String("userId", userId))
+		return api.NewError(ErrFailedQuery, nil), false
+	}
+
+	if !allowed {
+		return api.NewError(ErrForbidden, nil), false
+	}
+
+	return api.AppResponse{}, true
+}
+
+// join stores the list joined with commas without duplicates
+func join(values []string) string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+
+	return strings.Join(result, ",")
+}
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// CacheLifetime is how long other replicas may use types changed by an administrator
+	CacheLifetime time.Duration
+}
+
+const (
+	defaultCacheLifetimeSec = 60
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		CacheLifetime: time.Duration(config.MustGetInt("TYPES__CACHE_SECONDS", defaultCacheLifetimeSec)) * time.Second,
+	}
+}
@@ -0,0 +1,115 @@
+package types
+
+import (
+	"mime"
+	"slices"
+	"strings"
+
+	"github.com/flores666/profileshare-lib/api"
+)
+
+const maxNameLength = 32
+
+var fields = []string{FieldText, FieldMediaId, FieldMediaUrl}
+
+func validateCreate(request CreateTypeRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	if !isValidName(request.Name) {
+		errs.Add("name", "must start with a letter and contain at most 32 lower case latin letters, digits and _")
+	}
+
+	validateSettings(errs, request.DisplayName, request.MimeTypes, request.MaxSize, request.RequiredFields, request.TextAllowed)
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+func validateUpdate(name string, request UpdateTypeRequest) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+
+	validateSettings(errs, request.DisplayName, request.MimeTypes, request.MaxSize, request.RequiredFields, request.TextAllowed)
+
+	if name == TypePhoto {
+		for _, value := range request.MimeTypes {
+			if !slices.Contains(PhotoMimeTypes, value) {
+				errs.Add("mimeTypes", "photos must be some of "+strings.Join(PhotoMimeTypes, ", "))
+				break
+			}
+		}
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
+
+// validateSettings ensures the rules of the type do not contradict each other
+func validateSettings(errs *api.ValidationErrors, displayName string, mimeTypes []string, maxSize int64, requiredFields []string, textAllowed bool) {
+	if strings.TrimSpace(displayName) == "" || len([]rune(displayName)) > 255 {
+		errs.Add("displayName", "must be from 1 to 255 characters")
+	}
+
+	for _, value := range mimeTypes {
+		base, params, err := mime.ParseMediaType(value)
+		if err != nil || len(params) > 0 || base != value {
+			errs.Add("mimeTypes", "must contain media types in lower case without parameters")
+			break
+		}
+	}
+
+	if len(mimeTypes) > 0 && maxSize <= 0 {
+		errs.Add("maxSize", "must be positive for types with media")
+	}
+
+	if len(mimeTypes) == 0 && maxSize != 0 {
+		errs.Add("maxSize", "is allowed only for types with media")
+	}
+
+	for _, field := range requiredFields {
+		if !slices.Contains(fields, field) {
+			errs.Add("requiredFields", "must be some of text, mediaId, mediaUrl")
+			break
+		}
+	}
+
+	if slices.Contains(requiredFields, FieldMediaId) && len(mimeTypes) == 0 {
+		errs.Add("requiredFields", "mediaId requires mimeTypes")
+	}
+
+	if slices.Contains(requiredFields, FieldText) && !textAllowed {
+		errs.Add("requiredFields", "text requires textAllowed")
+	}
+}
+
+func isValidName(name string) bool {
+	if name == "" || len(name) > maxNameLength || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+
+	for _, char := range name {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateName(name string) *api.ValidationErrors {
+	errs := &api.ValidationErrors{}
+	if name == "" {
+		errs.Add("name", "is required")
+	}
+
+	if errs.Ok() {
+		return nil
+	}
+
+	return errs
+}
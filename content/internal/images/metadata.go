@@ -0,0 +1,207 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	orientationTag    = 0x0112
+	orientationNormal = 1
+)
+
+var (
+	exifHeader   = []byte("Exif\x00\x00")
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// jpegOrientation reads the EXIF orientation of a JPEG file, 1 when it is missing
+func jpegOrientation(data []byte) int {
+	orientation := orientationNormal
+
+	walkJpeg(data, func(marker byte, segment []byte) bool {
+		if marker != 0xE1 || len(segment) < 4+len(exifHeader) || !bytes.HasPrefix(segment[4:], exifHeader) {
+			return true
+		}
+
+		orientation = tiffOrientation(segment[4+len(exifHeader):])
+		return false
+	})
+
+	return orientation
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationNormal
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return orientationNormal
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return orientationNormal
+			}
+
+			return value
+		}
+	}
+
+	return orientationNormal
+}
+
+// stripJpeg removes EXIF, XMP, IPTC and comments without re-encoding,
+// JFIF, ICC profile and Adobe segments are kept since they affect colors
+func stripJpeg(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	rest := walkJpeg(data, func(marker byte, segment []byte) bool {
+		if keepJpegSegment(marker) {
+			out = append(out, segment...)
+		}
+
+		return true
+	})
+
+	return append(out, rest...)
+}
+
+func keepJpegSegment(marker byte) bool {
+	switch {
+	case marker == 0xE0, marker == 0xE2, marker == 0xEE:
+		return true
+	case marker >= 0xE0 && marker <= 0xEF, marker == 0xFE:
+		return false
+	default:
+		return true
+	}
+}
+
+// walkJpeg calls fn for each segment before the image data and returns the remaining bytes
+// starting with the start of scan marker, fn returns false to stop
+func walkJpeg(data []byte, fn func(marker byte, segment []byte) bool) []byte {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return data[i:]
+		}
+
+		marker := data[i+1]
+		if marker == 0xFF {
+			// fill byte before a marker
+			i++
+			continue
+		}
+
+		if marker == 0xDA {
+			return data[i:]
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return data[i:]
+		}
+
+		if !fn(marker, data[i:end]) {
+			return data[end:]
+		}
+
+		i = end
+	}
+
+	return data[i:]
+}
+
+// stripPng removes EXIF, text and time chunks
+func stripPng(data []byte) []byte {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	for i := len(pngSignature); i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if end > len(data) {
+			return data
+		}
+
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+
+		i = end
+	}
+
+	return out
+}
+
+// stripWebp removes EXIF and XMP chunks and clears their flags in the extended header
+func stripWebp(data []byte) []byte {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return data
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	for i := 12; i+8 <= len(data); {
+		fourcc := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) {
+			return data
+		}
+
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			chunk[8] &^= 0x08 | 0x04
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+
+	return out
+}
@@ -0,0 +1,214 @@
+package images
+
+import (
+	"bytes"
+	"content/internal/handlers/media"
+	"content/internal/storage/blob"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/gen2brain/webp"
+)
+
+const (
+	formatWebp       = "webp"
+	formatJpeg       = "jpeg"
+	blurhashSize     = 32
+	blurhashXComp    = 4
+	blurhashYComp    = 3
+	derivativePrefix = "derivatives/"
+)
+
+var (
+	// errUnsupported means the format can not be decoded, metadata of such photos can not be stripped
+	errUnsupported = errors.New("unsupported image format")
+	errTooLarge    = errors.New("image is too large")
+)
+
+// Result describes a processed photo
+type Result struct {
+	Width       int
+	Height      int
+	Blurhash    string
+	Derivatives []media.Derivative
+	// Size and Checksum of the original after metadata was stripped
+	Size     int64
+	Checksum string
+}
+
+type Processor struct {
+	store    blob.BlobStore
+	settings Settings
+}
+
+func NewProcessor(store blob.BlobStore, settings Settings) *Processor {
+	return &Processor{store: store, settings: settings}
+}
+
+// Process replaces the original with a copy without metadata and stores thumbnails
+func (p *Processor) Process(ctx context.Context, item *media.Media) (*Result, error) {
+	data, err := p.read(ctx, item.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized, img, err := p.sanitize(data, item.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(sanitized, data) {
+		if err = p.store.Put(ctx, item.StorageKey, bytes.NewReader(sanitized), int64(len(sanitized)), item.ContentType); err != nil {
+			return nil, err
+		}
+	}
+
+	checksum := sha256.Sum256(sanitized)
+	bounds := img.Bounds()
+
+	result := &Result{
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		Size:     int64(len(sanitized)),
+		Checksum: hex.EncodeToString(checksum[:]),
+	}
+
+	longest := max(result.Width, result.Height)
+	sizes := slices.Sorted(slices.Values(p.settings.ThumbnailSizes))
+
+	for _, size := range sizes {
+		if size >= longest {
+			break
+		}
+
+		width, height := fit(result.Width, result.Height, size)
+		thumbnail := resize(img, width, height)
+
+		for _, format := range []string{formatWebp, formatJpeg} {
+			derivative, err := p.saveDerivative(ctx, item.Id, size, format, thumbnail)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Derivatives = append(result.Derivatives, derivative)
+		}
+	}
+
+	width, height := fit(result.Width, result.Height, min(blurhashSize, longest))
+	if result.Blurhash, err = blurhash.Encode(blurhashXComp, blurhashYComp, resize(img, width, height)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete removes derivatives stored for the media
+func (p *Processor) Delete(ctx context.Context, derivatives []media.Derivative) error {
+	var result error
+	for _, derivative := range derivatives {
+		result = errors.Join(result, p.store.Delete(ctx, derivative.StorageKey))
+	}
+
+	return result
+}
+
+func (p *Processor) read(ctx context.Context, key string) ([]byte, error) {
+	reader, err := p.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// sanitize strips metadata and decodes the image, JPEG files with EXIF orientation
+// are re-encoded with rotated pixels since the orientation is lost with metadata
+func (p *Processor) sanitize(data []byte, contentType string) ([]byte, image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errUnsupported
+	}
+
+	if config.Width*config.Height > p.settings.MaxPixels {
+		return nil, nil, errTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errUnsupported
+	}
+
+	switch contentType {
+	case "image/jpeg":
+		orientation := jpegOrientation(data)
+		if orientation == orientationNormal {
+			return stripJpeg(data), img, nil
+		}
+
+		img = orient(img, orientation)
+
+		var buffer bytes.Buffer
+		if err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: p.settings.JpegQuality}); err != nil {
+			return nil, nil, err
+		}
+
+		return buffer.Bytes(), img, nil
+	case "image/png":
+		return stripPng(data), img, nil
+	case "image/webp":
+		return stripWebp(data), img, nil
+	default:
+		return data, img, nil
+	}
+}
+
+func (p *Processor) saveDerivative(ctx context.Context, mediaId string, size int, format string, img image.Image) (media.Derivative, error) {
+	var buffer bytes.Buffer
+	var err error
+
+	contentType := "image/" + format
+
+	switch format {
+	case formatWebp:
+		err = webp.Encode(&buffer, img, webp.Options{Quality: p.settings.WebpQuality, Method: webp.DefaultMethod})
+	case formatJpeg:
+		err = jpeg.Encode(&buffer, flatten(img), &jpeg.Options{Quality: p.settings.JpegQuality})
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+
+	if err != nil {
+		return media.Derivative{}, err
+	}
+
+	name := strconv.Itoa(size) + "." + format
+	key := derivativePrefix + mediaId + "/" + name
+
+	if err = p.store.Put(ctx, key, bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), contentType); err != nil {
+		return media.Derivative{}, err
+	}
+
+	bounds := img.Bounds()
+
+	return media.Derivative{
+		Name:       name,
+		Format:     format,
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		Size:       int64(buffer.Len()),
+		StorageKey: key,
+	}, nil
+}
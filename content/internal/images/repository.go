@@ -0,0 +1,48 @@
+package images
+
+import (
+	"content/internal/handlers/media"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func newRepository(db *sqlx.DB) *repository {
+	return &repository{db: db}
+}
+
+// Save stores results on the media and copies them to content already created with it
+func (r *repository) Save(ctx context.Context, mediaId string, result *Result) error {
+	derivatives, err := json.Marshal(result.Derivatives)
+	if err != nil {
+		return err
+	}
+
+	return postgresql.Exec(ctx, r.db, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		_, err := exec(`
+			UPDATE content.media
+			SET width = $1, height = $2, blurhash = $3, derivatives = $4::jsonb, size = $5, upload_offset = $5, checksum = $6, processing_status = $7
+			WHERE id = $8`,
+			result.Width, result.Height, result.Blurhash, string(derivatives), result.Size, result.Checksum, media.ProcessingProcessed, mediaId)
+		if err != nil {
+			return err
+		}
+
+		_, err = exec(`UPDATE content.content SET width = $1, height = $2, blurhash = $3, thumbnails = $4::jsonb WHERE media_id = $5`,
+			result.Width, result.Height, result.Blurhash, string(derivatives), mediaId)
+
+		return err
+	})
+}
+
+func (r *repository) SetStatus(ctx context.Context, mediaId string, status string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE content.media SET processing_status = $1 WHERE id = $2`, status, mediaId)
+	return err
+}
@@ -0,0 +1,23 @@
+package images
+
+import (
+	"content/internal/lib/config"
+)
+
+type Settings struct {
+	// ThumbnailSizes are lengths of the longest side of thumbnails, larger than the original are skipped
+	ThumbnailSizes []int
+	WebpQuality    int
+	JpegQuality    int
+	// MaxPixels protects the worker from decompression bombs
+	MaxPixels int
+}
+
+func MustLoadSettings() Settings {
+	return Settings{
+		ThumbnailSizes: config.MustGetInts("IMAGES__THUMBNAIL_SIZES", []int{320, 640, 1280}),
+		WebpQuality:    config.MustGetInt("IMAGES__WEBP_QUALITY", 80),
+		JpegQuality:    config.MustGetInt("IMAGES__JPEG_QUALITY", 85),
+		MaxPixels:      config.MustGetInt("IMAGES__MAX_MEGAPIXELS", 50) * 1_000_000,
+	}
+}
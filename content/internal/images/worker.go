@@ -0,0 +1,93 @@
+package images
+
+import (
+	"content/internal/handlers/media"
+	"content/internal/storage/blob"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/flores666/profileshare-lib/eventBus"
+	"github.com/jmoiron/sqlx"
+)
+
+// Worker processes uploaded photos consuming media.MediaUploadedTopic,
+// so uploads and content creation do not wait for thumbnails
+type Worker struct {
+	consumer   eventBus.Consumer
+	media      media.Repository
+	repository *repository
+	processor  *Processor
+	logger     *slog.Logger
+}
+
+func NewWorker(consumer eventBus.Consumer, db *sqlx.DB, store blob.BlobStore, settings Settings, logger *slog.Logger) *Worker {
+	return &Worker{
+		consumer:   consumer,
+		media:      media.NewRepository(db),
+		repository: newRepository(db),
+		processor:  NewProcessor(store, settings),
+		logger:     logger.With(slog.String("caller", "images.worker")),
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	return w.consumer.Consume(ctx, func(data []byte) error {
+		var event media.MediaUploadedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			w.logger.Error("unmarshal error", slog.String("error", err.Error()))
+			return nil
+		}
+
+		return w.handle(ctx, event)
+	})
+}
+
+// handle returns errors only for failures worth retrying, broken images are marked as failed
+func (w *Worker) handle(ctx context.Context, event media.MediaUploadedEvent) error {
+	item, err := w.media.GetById(ctx, event.MediaId)
+	if err != nil {
+		w.logger.Error("could not get media", slog.String("error", err.Error()), slog.String("id", event.MediaId))
+		return err
+	}
+
+	// events are delivered at least once
+	if item == nil || item.Status != media.StatusReady || item.ProcessingStatus != media.ProcessingPending {
+		return nil
+	}
+
+	result, err := w.processor.Process(ctx, item)
+
+	// originals of failed photos are not served, they keep their metadata
+	switch {
+	case errors.Is(err, errUnsupported), errors.Is(err, errTooLarge):
+		return w.setStatus(ctx, item.Id, media.ProcessingFailed)
+	case err != nil:
+		w.logger.Error("could not process image", slog.String("error", err.Error()), slog.String("id", item.Id))
+		return err
+	}
+
+	if err = w.repository.Save(ctx, item.Id, result); err != nil {
+		w.logger.Error("could not save processed image", slog.String("error", err.Error()), slog.String("id", item.Id))
+
+		if deleteErr := w.processor.Delete(ctx, result.Derivatives); deleteErr != nil {
+			w.logger.Error("could not delete derivatives", slog.String("error", deleteErr.Error()), slog.String("id", item.Id))
+		}
+
+		return err
+	}
+
+	w.logger.Info("image processed", slog.String("id", item.Id), slog.Int("derivatives", len(result.Derivatives)))
+
+	return nil
+}
+
+func (w *Worker) setStatus(ctx context.Context, mediaId string, status string) error {
+	if err := w.repository.SetStatus(ctx, mediaId, status); err != nil {
+		w.logger.Error("could not set processing status", slog.String("error", err.Error()), slog.String("id", mediaId))
+		return err
+	}
+
+	return nil
+}
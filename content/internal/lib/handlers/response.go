@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/flores666/profileshare-lib/api"
+
+	"github.com/go-chi/render"
+)
+
+func Respond(w http.ResponseWriter, r *http.Request, status int, response api.AppResponse) {
+	render.Status(r, status)
+	render.JSON(w, r, response)
+}
+
+// WriteResponse responds with 200 for successful responses,
+// otherwise with the status mapped from the response message or 500
+func WriteResponse(w http.ResponseWriter, r *http.Request, response api.AppResponse, statuses map[string]int) {
+	if response.Ok() {
+		Respond(w, r, http.StatusOK, response)
+		return
+	}
+
+	status, ok := statuses[response.Message]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	Respond(w, r, status, response)
+}
+
+// GetUserId returns the id of the authenticated user or an empty string
+func GetUserId(r *http.Request) string {
+	userId, _ := r.Context().Value("user_id").(string)
+	return userId
+}
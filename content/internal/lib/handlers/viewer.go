@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"content/internal/lib/visibility"
+	"net/http"
+)
+
+const (
+	shareParam          = "share"
+	sharePasswordHeader = "X-Share-Password"
+)
+
+// OptionalAuth authenticates requests with the Authorization header and passes anonymous requests as is
+func OptionalAuth(authMiddleware func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := authMiddleware(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetViewer returns the authenticated user and the share link of the request
+func GetViewer(r *http.Request) visibility.Viewer {
+	return visibility.Viewer{
+		UserId:        GetUserId(r),
+		ShareToken:    r.URL.Query().Get(shareParam),
+		SharePassword: r.Header.Get(sharePasswordHeader),
+	}
+}
@@ -0,0 +1,73 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type actorKey struct{}
+
+// Middleware authenticates requests with authMiddleware and keeps the admin of impersonation tokens
+// from the "act" claim (RFC 8693) in the context, the claim is read only from tokens accepted by authMiddleware
+func Middleware(authMiddleware func(http.Handler) http.Handler, secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+			if actorId, err := getActor(token, secret); err == nil && actorId != "" {
+				r = r.WithContext(context.WithValue(r.Context(), actorKey{}, actorId))
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// FromContext returns the id of the impersonating admin if the request is made on behalf of another user
+func FromContext(ctx context.Context) (string, bool) {
+	actorId, ok := ctx.Value(actorKey{}).(string)
+	return actorId, ok && actorId != ""
+}
+
+// ActorId returns the admin acting on behalf of the user or the user itself
+func ActorId(ctx context.Context, userId string) string {
+	if actorId, ok := FromContext(ctx); ok {
+		return actorId
+	}
+
+	return userId
+}
+
+func getActor(tokenStr string, secret []byte) (string, error) {
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return secret, nil
+	})
+
+	if err != nil || !token.Valid {
+		return "", errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", errors.New("invalid claims")
+	}
+
+	act, ok := claims["act"].(map[string]any)
+	if !ok {
+		return "", nil
+	}
+
+	actorId, ok := act["sub"].(string)
+	if !ok {
+		return "", errors.New("invalid act")
+	}
+
+	return actorId, nil
+}
@@ -0,0 +1,321 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxMentionLength and maxHashtagLength limit names linked from the text
+	maxMentionLength = 64
+	maxHashtagLength = 50
+)
+
+// inline renders spans of a block
+type inline struct {
+	renderer *Renderer
+	text     string
+	markdown bool
+	// inLink disables links inside labels of links
+	inLink bool
+}
+
+func newInline(renderer *Renderer, text string, markdown bool) *inline {
+	return &inline{renderer: renderer, text: text, markdown: markdown}
+}
+
+func (p *inline) render(out *strings.Builder, depth int) {
+	p.span(out, p.text, depth)
+}
+
+func (p *inline) span(out *strings.Builder, text string, depth int) {
+	// missing remembers delimiters without a closing one, later searches would fail too,
+	// so unbalanced markup does not make rendering quadratic
+	missing := make(map[string]bool)
+	closing := func(delimiter string, from int) int {
+		if missing[delimiter] || from > len(text) {
+			return -1
+		}
+
+		index := strings.Index(text[from:], delimiter)
+		if index < 0 {
+			missing[delimiter] = true
+			return -1
+		}
+
+		return from + index
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		if p.markdown && depth < maxDepth {
+			next, ok := 0, false
+
+			switch {
+			case c == '\\' && i+1 < len(text) && isPunct(text[i+1]):
+				out.WriteString(html.EscapeString(text[i+1 : i+2]))
+				next, ok = i+2, true
+			case c == '`':
+				next, ok = p.code(out, text, i, closing)
+			case c == '*' || c == '_' || c == '~':
+				next, ok = p.emphasis(out, text, i, depth, closing)
+			case !p.inLink && (c == '[' || c == '!' && i+1 < len(text) && text[i+1] == '['):
+				next, ok = p.link(out, text, i, depth, closing)
+			}
+
+			if ok {
+				i = next
+				continue
+			}
+		}
+
+		if next, ok := p.auto(out, text, i); ok {
+			i = next
+			continue
+		}
+
+		if c == '\n' {
+			out.WriteString("<br>\n")
+			i++
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		out.WriteString(html.EscapeString(text[i : i+size]))
+		i += size
+	}
+}
+
+func (p *inline) code(out *strings.Builder, text string, i int, closing func(string, int) int) (int, bool) {
+	run := countRun(text, i, '`')
+	delimiter := text[i : i+run]
+
+	end := closing(delimiter, i+run)
+	if end < 0 {
+		out.WriteString(delimiter)
+		return i + run, true
+	}
+
+	out.WriteString("<code>")
+	out.WriteString(html.EscapeString(strings.TrimSpace(text[i+run : end])))
+	out.WriteString("</code>")
+
+	return end + run, true
+}
+
+func (p *inline) emphasis(out *strings.Builder, text string, i int, depth int, closing func(string, int) int) (int, bool) {
+	c := text[i]
+	run := countRun(text, i, c)
+
+	var delimiter, tag string
+	switch {
+	case c == '~' && run == 2:
+		delimiter, tag = "~~", "del"
+	case c != '~' && run >= 2:
+		delimiter, tag = text[i:i+2], "strong"
+	case c != '~' && run == 1:
+		delimiter, tag = text[i:i+1], "em"
+	default:
+		return 0, false
+	}
+
+	// the opening delimiter is followed by text, underscores inside words are text
+	start := i + len(delimiter)
+	if start >= len(text) || isSpace(text[start]) || c == '_' && !isWordStart(text, i) {
+		return 0, false
+	}
+
+	end := closing(delimiter, start+1)
+	if end < 0 || isSpace(text[end-1]) || c == '_' && !isWordEnd(text, end+len(delimiter)) {
+		return 0, false
+	}
+
+	out.WriteString("<" + tag + ">")
+	p.span(out, text[start:end], depth+1)
+	out.WriteString("</" + tag + ">")
+
+	return end + len(delimiter), true
+}
+
+// link writes [label](url), images are written as links, so pages do not load images from other sites
+func (p *inline) link(out *strings.Builder, text string, i int, depth int, closing func(string, int) int) (int, bool) {
+	open := i
+	if text[i] == '!' {
+		open++
+	}
+
+	labelEnd := closing("](", open+1)
+	if labelEnd < 0 {
+		return 0, false
+	}
+
+	targetEnd := strings.IndexByte(text[labelEnd+2:], ')')
+	if targetEnd < 0 || strings.Contains(text[labelEnd+2:labelEnd+2+targetEnd], "\n") {
+		return 0, false
+	}
+
+	targetEnd += labelEnd + 2
+	label := text[open+1 : labelEnd]
+
+	// the optional title after the url is dropped
+	target := strings.TrimSpace(text[labelEnd+2 : targetEnd])
+	if space := strings.IndexAny(target, " \t"); space >= 0 {
+		target = target[:space]
+	}
+
+	href := safeUrl(strings.Trim(target, "<>"))
+	if href == "" {
+		p.span(out, label, depth+1)
+		return targetEnd + 1, true
+	}
+
+	if strings.TrimSpace(label) == "" {
+		label = href
+	}
+
+	out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow ugc">`)
+	p.inLink = true
+	p.span(out, label, depth+1)
+	p.inLink = false
+	out.WriteString("</a>")
+
+	return targetEnd + 1, true
+}
+
+// auto links urls, @mentions and #hashtags of the text
+func (p *inline) auto(out *strings.Builder, text string, i int) (int, bool) {
+	if p.inLink || !isWordStart(text, i) {
+		return 0, false
+	}
+
+	switch c := text[i]; {
+	case c == 'h' && (strings.HasPrefix(text[i:], "http://") || strings.HasPrefix(text[i:], "https://")):
+		end := i
+		for end < len(text) && !isSpace(text[end]) && !strings.ContainsRune(`<>"`, rune(text[end])) {
+			end++
+		}
+
+		// trailing punctuation ends the sentence, not the url
+		for end > i && strings.ContainsRune(".,;:!?)'*_~", rune(text[end-1])) {
+			end--
+		}
+
+		href := safeUrl(text[i:end])
+		if href == "" {
+			return 0, false
+		}
+
+		out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow ugc">` + html.EscapeString(text[i:end]) + "</a>")
+		return end, true
+	case c == '@' && p.renderer.mentionUrl != "":
+		end := scanName(text, i+1, maxMentionLength, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+		})
+
+		// names do not end with dots and dashes, they end sentences
+		end = i + 1 + len(strings.TrimRight(text[i+1:end], ".-"))
+		if end == i+1 {
+			return 0, false
+		}
+
+		name := text[i+1 : end]
+		out.WriteString(`<a href="` + html.EscapeString(p.renderer.mentionUrl+url.PathEscape(name)) + `" class="mention">@` + html.EscapeString(name) + "</a>")
+		return end, true
+	case c == '#' && p.renderer.hashtagUrl != "":
+		end := scanName(text, i+1, maxHashtagLength, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+		})
+
+		// numbers like #1 are not hashtags
+		tag := text[i+1 : end]
+		if strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+			return 0, false
+		}
+
+		href := p.renderer.hashtagUrl + url.PathEscape(strings.ToLower(tag))
+		out.WriteString(`<a href="` + html.EscapeString(href) + `" class="hashtag">#` + html.EscapeString(tag) + "</a>")
+		return end, true
+	}
+
+	return 0, false
+}
+
+// safeUrl returns the normalized url if it is an absolute http, https or mailto url
+func safeUrl(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		if parsed.Host == "" {
+			return ""
+		}
+	case "mailto":
+		if parsed.Opaque == "" {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	return parsed.String()
+}
+
+// scanName returns the end of the name starting at the position, the name is at most max characters
+func scanName(text string, start int, max int, allowed func(rune) bool) int {
+	end := start
+
+	for count := 0; end < len(text) && count < max; count++ {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !allowed(r) {
+			break
+		}
+
+		end += size
+	}
+
+	return end
+}
+
+func countRun(text string, i int, c byte) int {
+	run := 0
+	for i+run < len(text) && text[i+run] == c {
+		run++
+	}
+
+	return run
+}
+
+// isWordStart reports whether the position is not preceded by a letter or a digit
+func isWordStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// isWordEnd reports whether the position is not followed by a letter or a digit
+func isWordEnd(text string, i int) bool {
+	if i >= len(text) {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("`*_~[]()#+-.!<>\\", c) >= 0
+}
@@ -0,0 +1,207 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Formats of content text
+const (
+	// FormatPlain text is escaped, line breaks, links, mentions and hashtags are kept
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// maxDepth limits nesting of quotes, lists and emphasis, deeper markup is rendered as text
+const maxDepth = 8
+
+var (
+	headingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	fencePattern   = regexp.MustCompile("^ {0,3}(```|~~~)")
+	rulePattern    = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	quotePattern   = regexp.MustCompile(`^ {0,3}> ?`)
+	bulletPattern  = regexp.MustCompile(`^ {0,3}[-*+][ \t]+`)
+	orderedPattern = regexp.MustCompile(`^ {0,3}\d{1,9}[.)][ \t]+`)
+	// paragraphPattern separates paragraphs of plain text
+	paragraphPattern = regexp.MustCompile(`\n[ \t]*\n`)
+)
+
+// Renderer converts user text to HTML. Raw HTML of the source is always escaped,
+// so the output contains only tags produced by the renderer: p, br, h1-h6, strong, em, del, code, pre,
+// blockquote, ul, ol, li, hr and a. External links get rel="nofollow ugc", only http, https and mailto links are kept.
+type Renderer struct {
+	// mentionUrl and hashtagUrl are prefixes of links to profiles of mentioned users and to hashtags
+	mentionUrl string
+	hashtagUrl string
+}
+
+func NewRenderer(mentionUrl string, hashtagUrl string) *Renderer {
+	return &Renderer{mentionUrl: mentionUrl, hashtagUrl: hashtagUrl}
+}
+
+func IsValidFormat(format string) bool {
+	return format == FormatPlain || format == FormatMarkdown
+}
+
+// Render returns the sanitized HTML of the text
+func (r *Renderer) Render(source string, format string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+
+	var out strings.Builder
+
+	if format == FormatMarkdown {
+		r.blocks(&out, strings.Split(source, "\n"), 0)
+	} else {
+		r.plain(&out, source)
+	}
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// plain writes paragraphs separated by blank lines, only links, mentions and hashtags are recognized
+func (r *Renderer) plain(out *strings.Builder, source string) {
+	for _, paragraph := range paragraphPattern.Split(source, -1) {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		out.WriteString("<p>")
+		newInline(r, paragraph, false).render(out, 0)
+		out.WriteString("</p>\n")
+	}
+}
+
+// blocks writes block elements of the lines
+func (r *Renderer) blocks(out *strings.Builder, lines []string, depth int) {
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+
+		out.WriteString("<p>")
+		r.inline(out, strings.Join(paragraph, "\n"), depth)
+		out.WriteString("</p>\n")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case fencePattern.MatchString(line):
+			flush()
+			fence := fencePattern.FindStringSubmatch(line)[1]
+			var code []string
+
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+
+			out.WriteString("<pre><code>")
+			out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			out.WriteString("</code></pre>\n")
+		case headingPattern.MatchString(line):
+			flush()
+			match := headingPattern.FindStringSubmatch(line)
+			tag := "h" + string(rune('0'+len(match[1])))
+
+			out.WriteString("<" + tag + ">")
+			r.inline(out, match[2], depth)
+			out.WriteString("</" + tag + ">\n")
+		case rulePattern.MatchString(line):
+			flush()
+			out.WriteString("<hr>\n")
+		case quotePattern.MatchString(line) && depth < maxDepth:
+			flush()
+			var quote []string
+
+			for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+				quote = append(quote, quotePattern.ReplaceAllString(lines[i], ""))
+			}
+			i--
+
+			out.WriteString("<blockquote>\n")
+			r.blocks(out, quote, depth+1)
+			out.WriteString("</blockquote>\n")
+		case (bulletPattern.MatchString(line) || orderedPattern.MatchString(line)) && depth < maxDepth:
+			flush()
+			i = r.list(out, lines, i, depth) - 1
+		default:
+			paragraph = append(paragraph, strings.TrimSpace(line))
+		}
+	}
+
+	flush()
+}
+
+// list writes the list starting at the line and returns the index of the first line after it,
+// lines indented under an item belong to the item
+func (r *Renderer) list(out *strings.Builder, lines []string, start int, depth int) int {
+	pattern, tag := bulletPattern, "ul"
+	if orderedPattern.MatchString(lines[start]) {
+		pattern, tag = orderedPattern, "ol"
+	}
+
+	out.WriteString("<" + tag + ">\n")
+
+	i := start
+	for i < len(lines) && pattern.MatchString(lines[i]) {
+		item := []string{pattern.ReplaceAllString(lines[i], "")}
+
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// a blank line ends the list unless the next line continues the item
+				if i+1 < len(lines) && isIndented(lines[i+1]) {
+					item = append(item, "")
+					continue
+				}
+
+				break
+			}
+
+			if !isIndented(line) {
+				break
+			}
+
+			item = append(item, strings.TrimLeft(line, " \t"))
+		}
+
+		var body strings.Builder
+		r.blocks(&body, item, depth+1)
+
+		// items of a single paragraph are written without it
+		content := strings.TrimSuffix(body.String(), "\n")
+		if strings.HasPrefix(content, "<p>") && strings.Count(content, "<p>") == 1 && strings.HasSuffix(content, "</p>") {
+			content = strings.TrimSuffix(strings.TrimPrefix(content, "<p>"), "</p>")
+		}
+
+		out.WriteString("<li>" + content + "</li>\n")
+
+		// blank lines between items keep the list
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) && pattern.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+
+	out.WriteString("</" + tag + ">\n")
+
+	return i
+}
+
+func (r *Renderer) inline(out *strings.Builder, text string, depth int) {
+	newInline(r, text, true).render(out, depth)
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+}
@@ -0,0 +1,209 @@
+package markdown
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	mentionUrl = "/users/"
+	hashtagUrl = "/tags/"
+)
+
+// allowedAttributes are the tags the renderer produces and their attributes
+var allowedAttributes = map[string][]string{
+	"p": nil, "br": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"strong": nil, "em": nil, "del": nil, "code": nil, "pre": nil, "blockquote": nil,
+	"ul": nil, "ol": nil, "li": nil, "hr": nil,
+	"a": {"href", "rel", "class"},
+}
+
+func TestRenderUnsafeMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "javascript link", source: "[x](javascript:alert(1))", want: "<p>x)</p>"},
+		{name: "mixed case scheme", source: "[x](JaVaScRiPt:alert(1))", want: "<p>x)</p>"},
+		{name: "angle brackets", source: "[x](<javascript:alert(1)>)", want: "<p>x&gt;)</p>"},
+		{name: "vbscript link", source: "[x](vbscript:msgbox(1))", want: "<p>x)</p>"},
+		{name: "data link", source: "[x](data:text/html;base64,PHNjcmlwdD4=)", want: "<p>x</p>"},
+		{name: "data image", source: "![x](data:image/svg+xml,<svg onload=alert(1)>)", want: "<p>x&gt;)</p>"},
+		{name: "encoded colon", source: "[x](javascript&#58;alert(1))", want: "<p>x)</p>"},
+		{name: "encoded letter", source: "[x](&#106;avascript:alert(1))", want: "<p>x)</p>"},
+		{name: "encoded tab", source: "[x](java&#x09;script:alert(1))", want: "<p>x)</p>"},
+		{name: "percent encoded scheme", source: "[x](%6Aavascript:alert(1))", want: "<p>x)</p>"},
+		{name: "scheme relative link", source: "[x](//evil.example/)", want: "<p>x</p>"},
+		{name: "empty mailto", source: "[x](mailto:)", want: "<p>x</p>"},
+		{
+			name:   "mailto link",
+			source: "[x](mailto:a@example.com)",
+			want:   `<p><a href="mailto:a@example.com" rel="nofollow ugc">x</a></p>`,
+		},
+		{name: "javascript link in emphasis", source: "**[x](javascript:alert(1))**", want: "<p><strong>x)</strong></p>"},
+		{
+			name:   "emphasis in link",
+			source: "[**bold** _em_](https://example.com)",
+			want:   `<p><a href="https://example.com" rel="nofollow ugc"><strong>bold</strong> <em>em</em></a></p>`,
+		},
+		{
+			name:   "link in nested emphasis",
+			source: "*a **b [c](https://example.com) d** e*",
+			want:   `<p>*a <strong>b <a href="https://example.com" rel="nofollow ugc">c</a> d</strong> e*</p>`,
+		},
+		{
+			name:   "link in link",
+			source: "[[inner](https://a.example)](https://b.example)",
+			want: `<p><a href="https://a.example" rel="nofollow ugc">[inner</a>](` +
+				`<a href="https://b.example" rel="nofollow ugc">https://b.example</a>)</p>`,
+		},
+		{
+			name:   "deep emphasis",
+			source: strings.Repeat("*", 40) + "x" + strings.Repeat("*", 40),
+		},
+		{name: "script", source: "<script>alert(1)</script>", want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{name: "event handler", source: "<img src=x onerror=alert(1)>", want: "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{
+			name:   "raw link",
+			source: `<a href="javascript:alert(1)">x</a>`,
+			want:   "<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>",
+		},
+		{name: "raw html in heading", source: "# <b>head</b>", want: "<h1>&lt;b&gt;head&lt;/b&gt;</h1>"},
+		{name: "raw html in code", source: "`<script>alert(1)</script>`", want: "<p><code>&lt;script&gt;alert(1)&lt;/script&gt;</code></p>"},
+		{name: "raw html in code block", source: "```\n<script>alert(1)</script>\n```"},
+		{
+			name:   "raw html in label",
+			source: "[<img src=x onerror=alert(1)>](https://example.com)",
+			want:   `<p><a href="https://example.com" rel="nofollow ugc">&lt;img src=x onerror=alert(1)&gt;</a></p>`,
+		},
+		{name: "escaped brackets", source: `\<script\>`, want: "<p>&lt;script&gt;</p>"},
+		{
+			name:   "double quote in link",
+			source: `[x](https://example.com/"onmouseover="alert(1))`,
+			want:   `<p><a href="https://example.com/%22onmouseover=%22alert%281" rel="nofollow ugc">x</a>)</p>`,
+		},
+		{
+			name:   "single quote in link",
+			source: `[x](https://example.com/'onmouseover='alert(1))`,
+			want:   `<p><a href="https://example.com/&#39;onmouseover=&#39;alert(1" rel="nofollow ugc">x</a>)</p>`,
+		},
+		{
+			name:   "quote in query",
+			source: `[x](https://example.com/?q="><script>alert(1)</script>)`,
+			want:   `<p><a href="https://example.com/?q=&#34;&gt;&lt;script&gt;alert(1" rel="nofollow ugc">x</a>&lt;/script&gt;)</p>`,
+		},
+		{
+			name:   "quote after autolink",
+			source: `https://example.com/"onmouseover="alert(1)`,
+			want:   `<p><a href="https://example.com/" rel="nofollow ugc">https://example.com/</a>&#34;onmouseover=&#34;alert(1)</p>`,
+		},
+		{
+			name:   "quote after mention",
+			source: `@user"onmouseover="alert(1)`,
+			want:   `<p><a href="/users/user" class="mention">@user</a>&#34;onmouseover=&#34;alert(1)</p>`,
+		},
+		{
+			name:   "quote after hashtag",
+			source: `#tag"><script>`,
+			want:   `<p><a href="/tags/tag" class="hashtag">#tag</a>&#34;&gt;&lt;script&gt;</p>`,
+		},
+		{name: "script in quote", source: "> <script>alert(1)</script>\n> - <b>x</b>"},
+	}
+
+	renderer := NewRenderer(mentionUrl, hashtagUrl)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renderer.Render(test.source, FormatMarkdown)
+
+			if test.want != "" && got != test.want {
+				t.Fatalf("Render(%q) = %q, want %q", test.source, got, test.want)
+			}
+
+			checkSafe(t, got)
+		})
+	}
+}
+
+func TestRenderUnsafePlain(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "javascript text", source: "javascript:alert(1)", want: "<p>javascript:alert(1)</p>"},
+		{name: "markdown link", source: "[x](javascript:alert(1))", want: "<p>[x](javascript:alert(1))</p>"},
+		{name: "script", source: "<script>alert(1)</script>", want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{
+			name:   "quote after autolink",
+			source: `https://example.com/"><b>`,
+			want:   `<p><a href="https://example.com/" rel="nofollow ugc">https://example.com/</a>&#34;&gt;&lt;b&gt;</p>`,
+		},
+	}
+
+	renderer := NewRenderer(mentionUrl, hashtagUrl)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renderer.Render(test.source, FormatPlain)
+			if got != test.want {
+				t.Fatalf("Render(%q) = %q, want %q", test.source, got, test.want)
+			}
+
+			checkSafe(t, got)
+		})
+	}
+}
+
+// checkSafe parses the output like a browser and checks tags, attributes and link schemes
+func checkSafe(t *testing.T, output string) {
+	t.Helper()
+
+	tokenizer := html.NewTokenizer(strings.NewReader(output))
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return
+		}
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+
+		allowed, ok := allowedAttributes[token.Data]
+		if !ok {
+			t.Fatalf("unexpected tag %q in %q", token.Data, output)
+		}
+
+		for _, attribute := range token.Attr {
+			if !slices.Contains(allowed, attribute.Key) {
+				t.Fatalf("unexpected attribute %q of %q in %q", attribute.Key, token.Data, output)
+			}
+
+			if attribute.Key == "href" && !isSafeHref(attribute.Val) {
+				t.Fatalf("unsafe href %q in %q", attribute.Val, output)
+			}
+		}
+	}
+}
+
+func isSafeHref(href string) bool {
+	if strings.HasPrefix(href, mentionUrl) || strings.HasPrefix(href, hashtagUrl) {
+		return true
+	}
+
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Scheme == "http" || parsed.Scheme == "https" || parsed.Scheme == "mailto"
+}
@@ -0,0 +1,98 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type Direction string
+
+const (
+	Next Direction = "n"
+	Prev Direction = "p"
+)
+
+// Key is a keyset position over (created_at, id), Rank is set for pages ordered by relevance
+type Key struct {
+	Rank      *float64
+	CreatedAt time.Time
+	Id        string
+}
+
+type Cursor struct {
+	Key
+	Direction Direction
+}
+
+type payload struct {
+	Rank      *float64  `json:"r,omitempty"`
+	CreatedAt string    `json:"t"`
+	Id        string    `json:"i"`
+	Direction Direction `json:"d"`
+}
+
+// Codec encodes cursors as opaque signed strings, so clients cannot forge arbitrary positions
+type Codec struct {
+	secret []byte
+}
+
+func NewCodec(secret string) *Codec {
+	return &Codec{secret: []byte(secret)}
+}
+
+func (c *Codec) Encode(cursor Cursor) string {
+	data, _ := json.Marshal(payload{
+		Rank:      cursor.Rank,
+		CreatedAt: cursor.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Id:        cursor.Id,
+		Direction: cursor.Direction,
+	})
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded))
+}
+
+func (c *Codec) Decode(value string) (*Cursor, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, c.sign(encoded)) {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var p payload
+	if err = json.Unmarshal(data, &p); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, p.CreatedAt)
+	if err != nil || p.Id == "" || (p.Direction != Next && p.Direction != Prev) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{
+		Key:       Key{Rank: p.Rank, CreatedAt: createdAt, Id: p.Id},
+		Direction: p.Direction,
+	}, nil
+}
+
+func (c *Codec) sign(value string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)[:16]
+}
@@ -0,0 +1,61 @@
+package pagination
+
+import (
+	"content/internal/lib/config"
+	"os"
+)
+
+type Settings struct {
+	Secret          string
+	DefaultPageSize int
+	MaxPageSize     int
+}
+
+func MustLoadSettings() Settings {
+	// the cursor key is not shared with tokens, a leaked key must not allow forging access tokens
+	secret := os.Getenv("PAGINATION__CURSOR_SECRET")
+	if secret == "" {
+		panic("PAGINATION__CURSOR_SECRET is required")
+	}
+
+	return Settings{
+		Secret:          secret,
+		DefaultPageSize: config.MustGetInt("PAGINATION__DEFAULT_PAGE_SIZE", 20),
+		MaxPageSize:     config.MustGetInt("PAGINATION__MAX_PAGE_SIZE", 100),
+	}
+}
+
+// Paginator parses page requests and builds response cursors
+type Paginator struct {
+	Codec    *Codec
+	settings Settings
+}
+
+func NewPaginator(settings Settings) *Paginator {
+	return &Paginator{
+		Codec:    NewCodec(settings.Secret),
+		settings: settings,
+	}
+}
+
+// Parse decodes the cursor and applies the default and the maximal page size
+func (p *Paginator) Parse(cursor string, limit int) (Page, error) {
+	page := Page{Limit: limit}
+
+	if page.Limit <= 0 {
+		page.Limit = p.settings.DefaultPageSize
+	}
+
+	page.Limit = min(page.Limit, p.settings.MaxPageSize)
+
+	if cursor != "" {
+		decoded, err := p.Codec.Decode(cursor)
+		if err != nil {
+			return Page{}, err
+		}
+
+		page.Cursor = decoded
+	}
+
+	return page, nil
+}
@@ -0,0 +1,80 @@
+package visibility
+
+import (
+	"context"
+	"slices"
+)
+
+// Visibility levels of content and folders
+const (
+	// Private items are available only to the owner
+	Private = "private"
+	// Unlisted items are available by id or link but never returned in listings
+	Unlisted = "unlisted"
+	// Followers items are available to users following the owner
+	Followers = "followers"
+	// Public items are available to everyone
+	Public = "public"
+)
+
+var levels = []string{Private, Unlisted, Followers, Public}
+
+func IsValid(value string) bool {
+	return slices.Contains(levels, value)
+}
+
+// Follows reports whether the follower follows the followee
+type Follows interface {
+	IsFollowing(ctx context.Context, followerId string, followeeId string) (bool, error)
+}
+
+// Policy decides which items the viewer may read
+type Policy struct {
+	follows Follows
+}
+
+func NewPolicy(follows Follows) *Policy {
+	return &Policy{follows: follows}
+}
+
+// CanView reports whether the viewer may read the item by its id, empty viewerId means anonymous viewer
+func (p *Policy) CanView(ctx context.Context, visibility string, ownerId string, viewerId string) (bool, error) {
+	switch {
+	case viewerId != "" && viewerId == ownerId:
+		return true, nil
+	case visibility == Public || visibility == Unlisted:
+		return true, nil
+	case visibility == Followers && viewerId != "":
+		return p.follows.IsFollowing(ctx, viewerId, ownerId)
+	default:
+		return false, nil
+	}
+}
+
+// CanList reports whether the item may be returned in listings for the viewer
+func (p *Policy) CanList(ctx context.Context, visibility string, ownerId string, viewerId string) (bool, error) {
+	if visibility == Unlisted && viewerId != ownerId {
+		return false, nil
+	}
+
+	return p.CanView(ctx, visibility, ownerId, viewerId)
+}
+
+// ListCondition returns the sql condition selecting rows the viewer may see in listings,
+// the viewer id is passed as :viewer_id named parameter
+func ListCondition(ownerColumn string, visibilityColumn string) string {
+	return `(` + ownerColumn + ` = CAST(NULLIF(:viewer_id, '') AS uuid)
+		OR ` + visibilityColumn + ` = '` + Public + `'
+		OR (` + visibilityColumn + ` = '` + Followers + `' AND EXISTS (
+			SELECT 1 FROM content.follows fl
+			WHERE fl.follower_id = CAST(NULLIF(:viewer_id, '') AS uuid) AND fl.followee_id = ` + ownerColumn + `
+		)))`
+}
+
+// Viewer is the reader of content, anonymous when UserId is empty
+type Viewer struct {
+	UserId string
+	// ShareToken and SharePassword grant access to a shared item or folder
+	ShareToken    string
+	SharePassword string
+}
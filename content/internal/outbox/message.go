@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Message is an event saved in the transaction of the state change and published to Kafka by the Relay
+type Message struct {
+	Topic string
+	// Key identifies the aggregate, messages with the same key are published in the order they were written
+	Key     string
+	Payload any
+}
+
+// Write saves the messages with the exec function of the transaction changing the state
+func Write(exec func(query string, args ...any) (sql.Result, error), messages ...Message) error {
+	query := `
+		INSERT INTO content.outbox (aggregate_id, topic, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, CAST($3 AS jsonb), $4, $4)`
+
+	now := time.Now().UTC()
+
+	for _, message := range messages {
+		payload, err := json.Marshal(message.Payload)
+		if err != nil {
+			return err
+		}
+
+		if _, err = exec(query, message.Key, message.Topic, string(payload), now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files by keys like "media/<user id>/<media id>"
+type BlobStore interface {
+	// Put stores exactly size bytes of r, size -1 means unknown size
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object, returned readers are seekable to serve range requests
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the object, missing objects are not an error
+	Delete(ctx context.Context, key string) error
+}
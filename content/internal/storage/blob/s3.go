@@ -0,0 +1,82 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store works with any S3 compatible storage, e.g. MinIO, the bucket is created if missing
+func NewS3Store(ctx context.Context, settings S3Settings) (BlobStore, error) {
+	client, err := minio.New(settings.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(settings.AccessKey, settings.SecretKey, ""),
+		Secure: settings.UseSSL,
+		Region: settings.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, settings.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, settings.Bucket, minio.MakeBucketOptions{Region: settings.Region})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &s3Store{client: client, bucket: settings.Bucket}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	// GetObject is lazy, stat reports missing objects right away
+	if _, err = object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, mapError(err)
+	}
+
+	return object, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err = mapError(err); err == ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,72 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+type Settings struct {
+	Driver    string
+	LocalPath string
+	S3        S3Settings
+}
+
+type S3Settings struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+}
+
+func MustLoadSettings() Settings {
+	settings := Settings{
+		Driver:    os.Getenv("BLOB__DRIVER"),
+		LocalPath: os.Getenv("BLOB__LOCAL_PATH"),
+		S3: S3Settings{
+			Endpoint:  os.Getenv("BLOB__S3_ENDPOINT"),
+			AccessKey: os.Getenv("BLOB__S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("BLOB__S3_SECRET_KEY"),
+			Bucket:    os.Getenv("BLOB__S3_BUCKET"),
+			Region:    os.Getenv("BLOB__S3_REGION"),
+			UseSSL:    os.Getenv("BLOB__S3_USE_SSL") == "true",
+		},
+	}
+
+	if settings.Driver == "" {
+		settings.Driver = DriverLocal
+	}
+
+	if settings.LocalPath == "" {
+		settings.LocalPath = "data/media"
+	}
+
+	if settings.S3.Bucket == "" {
+		settings.S3.Bucket = "content"
+	}
+
+	if settings.Driver == DriverS3 && settings.S3.Endpoint == "" {
+		panic("BLOB__S3_ENDPOINT is required for s3 driver")
+	}
+
+	return settings
+}
+
+// NewBlobStore creates the store selected by BLOB__DRIVER
+func NewBlobStore(ctx context.Context, settings Settings) (BlobStore, error) {
+	switch settings.Driver {
+	case DriverLocal:
+		return NewLocalStore(settings.LocalPath)
+	case DriverS3:
+		return NewS3Store(ctx, settings.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob driver %q", settings.Driver)
+	}
+}
@@ -0,0 +1,58 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Exec runs fn with an exec function bound either to a new transaction or to the db.
+// The transaction is committed when fn returns nil and rolled back on error or panic.
+func Exec(ctx context.Context, db *sqlx.DB, useTransaction bool, fn func(exec func(query string, args ...any) (sql.Result, error)) error) (err error) {
+	if useTransaction {
+		tran, tranErr := db.BeginTx(ctx, &sql.TxOptions{})
+		if tranErr != nil {
+			return tranErr
+		}
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				_ = tran.Rollback()
+				panic(rec)
+			} else if err != nil {
+				_ = tran.Rollback()
+			} else {
+				err = tran.Commit()
+			}
+		}()
+
+		err = fn(tran.Exec)
+	} else {
+		err = fn(db.Exec)
+	}
+
+	return
+}
+
+// Transaction runs fn in a new transaction for queries that need to read rows,
+// the transaction is committed when fn returns nil and rolled back on error or panic.
+func Transaction(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) (err error) {
+	tran, err := db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			_ = tran.Rollback()
+			panic(rec)
+		} else if err != nil {
+			_ = tran.Rollback()
+		} else {
+			err = tran.Commit()
+		}
+	}()
+
+	return fn(tran)
+}
@@ -0,0 +1,22 @@
+package trash
+
+import "expvar"
+
+// metrics are published on /debug/vars under the "trash" key
+var metrics = expvar.NewMap("trash")
+
+const (
+	metricRuns               = "runs"
+	metricSkipped            = "skipped_not_leader"
+	metricErrors             = "errors"
+	metricContentPurged      = "content_purged"
+	metricMediaReleased      = "media_released"
+	metricMediaPurged        = "media_purged"
+	metricLastRunUnixSeconds = "last_run_unix"
+)
+
+func intVar(value int64) *expvar.Int {
+	result := new(expvar.Int)
+	result.Set(value)
+	return result
+}
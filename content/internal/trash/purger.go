@@ -0,0 +1,214 @@
+package trash
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/handlers/media"
+	"content/internal/storage/blob"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Purger periodically deletes items kept in the trash longer than the retention period
+// and removes files of media no longer used by any item, of abandoned uploads and of uploaded media never used by content.
+// All replicas run the loop, but a run is performed only by the one holding the Postgres advisory lock.
+type Purger struct {
+	db         *sqlx.DB
+	repository *repository
+	content    content.Repository
+	media      media.Repository
+	store      blob.BlobStore
+	settings   Settings
+	logger     *slog.Logger
+}
+
+func NewPurger(db *sqlx.DB, content content.Repository, media media.Repository, store blob.BlobStore, settings Settings, logger *slog.Logger) *Purger {
+	return &Purger{
+		db:         db,
+		repository: &repository{},
+		content:    content,
+		media:      media,
+		store:      store,
+		settings:   settings,
+		logger:     logger.With(slog.String("caller", "trash")),
+	}
+}
+
+// Run blocks until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) runOnce(ctx context.Context) {
+	conn, err := p.db.Connx(ctx)
+	if err != nil {
+		metrics.Add(metricErrors, 1)
+		p.logger.Error("failed to get connection", slog.String("error", err.Error()))
+		return
+	}
+
+	defer func(conn *sqlx.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	locked, err := p.repository.tryLock(ctx, conn)
+	if err != nil {
+		metrics.Add(metricErrors, 1)
+		p.logger.Error("failed to take advisory lock", slog.String("error", err.Error()))
+		return
+	}
+
+	if !locked {
+		metrics.Add(metricSkipped, 1)
+		p.logger.Debug("purge is running on another replica")
+		return
+	}
+
+	defer func() {
+		if unlockErr := p.repository.unlock(context.Background(), conn); unlockErr != nil {
+			p.logger.Warn("failed to release advisory lock", slog.String("error", unlockErr.Error()))
+		}
+	}()
+
+	started := time.Now()
+	deletedBefore := started.UTC().Add(-p.settings.Retention)
+
+	var contentPurged int64
+
+	for ctx.Err() == nil {
+		deleted, purgeErr := p.content.PurgeExpired(ctx, deletedBefore, p.settings.BatchSize)
+		if purgeErr != nil {
+			metrics.Add(metricErrors, 1)
+			p.logger.Error("failed to purge content", slog.String("error", purgeErr.Error()))
+			break
+		}
+
+		contentPurged += deleted
+		if deleted < int64(p.settings.BatchSize) {
+			break
+		}
+	}
+
+	mediaReleased := p.releaseAbandonedMedia(ctx, started.UTC())
+	mediaPurged := p.purgeMedia(ctx)
+
+	metrics.Add(metricRuns, 1)
+	metrics.Add(metricContentPurged, contentPurged)
+	metrics.Add(metricMediaReleased, mediaReleased)
+	metrics.Add(metricMediaPurged, mediaPurged)
+	metrics.Set(metricLastRunUnixSeconds, intVar(started.Unix()))
+
+	p.logger.Info("trash purge finished",
+		slog.Int64("content_purged", contentPurged),
+		slog.Int64("media_released", mediaReleased),
+		slog.Int64("media_purged", mediaPurged),
+		slog.Duration("duration", time.Since(started)),
+	)
+}
+
+// releaseAbandonedMedia marks deleted uploads not completed in time and completed media never used by content,
+// their files and parts are removed by purgeMedia
+func (p *Purger) releaseAbandonedMedia(ctx context.Context, now time.Time) int64 {
+	var total int64
+
+	for ctx.Err() == nil {
+		released, err := p.media.ReleaseAbandoned(ctx, now.Add(-p.settings.PendingUploadMaxAge), now.Add(-p.settings.UnattachedMediaMaxAge), p.settings.BatchSize)
+		if err != nil {
+			metrics.Add(metricErrors, 1)
+			p.logger.Error("failed to release abandoned media", slog.String("error", err.Error()))
+			break
+		}
+
+		total += released
+		if released < int64(p.settings.BatchSize) {
+			break
+		}
+	}
+
+	return total
+}
+
+// purgeMedia removes files of released media with their derivatives, then the media itself.
+// Media whose files could not be removed stays for the next run.
+func (p *Purger) purgeMedia(ctx context.Context) int64 {
+	var total int64
+
+	for ctx.Err() == nil {
+		list, err := p.media.GetDeleted(ctx, p.settings.BatchSize)
+		if err != nil {
+			metrics.Add(metricErrors, 1)
+			p.logger.Error("failed to get deleted media", slog.String("error", err.Error()))
+			break
+		}
+
+		failed := false
+
+		for _, item := range list {
+			if err = p.deleteFiles(ctx, item); err != nil {
+				failed = true
+				metrics.Add(metricErrors, 1)
+				p.logger.Error("failed to delete media files", slog.String("error", err.Error()), slog.String("mediaId", item.Id))
+				continue
+			}
+
+			if err = p.media.Delete(ctx, item.Id); err != nil {
+				failed = true
+				metrics.Add(metricErrors, 1)
+				p.logger.Error("failed to delete media", slog.String("error", err.Error()), slog.String("mediaId", item.Id))
+				continue
+			}
+
+			total++
+		}
+
+		// failed items would be selected again, so they are retried on the next run
+		if failed || len(list) < p.settings.BatchSize {
+			break
+		}
+	}
+
+	return total
+}
+
+func (p *Purger) deleteFiles(ctx context.Context, item *media.Media) error {
+	var keys []string
+	if item.StorageKey != "" {
+		keys = append(keys, item.StorageKey)
+	}
+
+	for _, derivative := range media.ParseDerivatives(item.Derivatives) {
+		keys = append(keys, derivative.StorageKey)
+	}
+
+	parts, err := p.media.GetParts(ctx, item.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		keys = append(keys, part.StorageKey)
+	}
+
+	var result error
+	for _, key := range keys {
+		if deleteErr := p.store.Delete(ctx, key); deleteErr != nil && !errors.Is(deleteErr, blob.ErrNotFound) {
+			result = errors.Join(result, deleteErr)
+		}
+	}
+
+	return result
+}
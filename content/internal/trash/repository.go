@@ -0,0 +1,21 @@
+package trash
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type repository struct{}
+
+// tryLock takes a session level advisory lock, only the replica holding it purges the trash
+func (r *repository) tryLock(ctx context.Context, conn *sqlx.Conn) (bool, error) {
+	var locked bool
+	err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock(hashtext('content.trash'))`)
+	return locked, err
+}
+
+func (r *repository) unlock(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext('content.trash'))`)
+	return err
+}
@@ -0,0 +1,36 @@
+package trash
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	Interval time.Duration
+	// Retention is how long deleted items stay in the trash before they are purged
+	Retention time.Duration
+	// PendingUploadMaxAge is how long an upload may stay not completed before it and its parts are purged
+	PendingUploadMaxAge time.Duration
+	// UnattachedMediaMaxAge is how long completed media may stay not used by content before it is purged
+	UnattachedMediaMaxAge time.Duration
+	BatchSize             int
+}
+
+const (
+	defaultIntervalMinutes = 60
+	defaultRetentionDays   = 30
+	defaultBatchSize       = 100
+	// uploads are resumable, so a client may come back to a pending upload within a day
+	defaultPendingUploadMaxAgeHours  = 24
+	defaultUnattachedMediaMaxAgeDays = 7
+)
+
+func MustLoadSettings() Settings {
+	return Settings{
+		Interval:              time.Duration(config.MustGetPositiveInt("TRASH__PURGE_INTERVAL_MINUTES", defaultIntervalMinutes)) * time.Minute,
+		Retention:             time.Duration(config.MustGetInt("TRASH__RETENTION_DAYS", defaultRetentionDays)) * 24 * time.Hour,
+		PendingUploadMaxAge:   time.Duration(config.MustGetPositiveInt("TRASH__PENDING_UPLOAD_MAX_AGE_HOURS", defaultPendingUploadMaxAgeHours)) * time.Hour,
+		UnattachedMediaMaxAge: time.Duration(config.MustGetPositiveInt("TRASH__UNATTACHED_MEDIA_MAX_AGE_DAYS", defaultUnattachedMediaMaxAgeDays)) * 24 * time.Hour,
+		BatchSize:             config.MustGetPositiveInt("TRASH__BATCH_SIZE", defaultBatchSize),
+	}
+}
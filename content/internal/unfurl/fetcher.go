@@ -0,0 +1,182 @@
+package unfurl
+
+import (
+	"content/internal/handlers/content"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"golang.org/x/net/html/charset"
+)
+
+var (
+	errForbiddenAddress = errors.New("address is not public")
+	errForbiddenUrl     = errors.New("only http and https urls are fetched")
+	errTooManyRedirects = errors.New("too many redirects")
+	errUnsupported      = errors.New("unsupported content type")
+)
+
+// reservedPrefixes are special purpose ranges not covered by the netip checks
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// Fetcher downloads pages of user supplied urls, it connects only to public addresses
+// checked after name resolution, so redirects and DNS rebinding cannot reach internal services
+type Fetcher struct {
+	client   *http.Client
+	settings Settings
+}
+
+func NewFetcher(settings Settings) *Fetcher {
+	return newFetcher(settings, func(address netip.AddrPort) bool {
+		return isPublic(address.Addr())
+	})
+}
+
+// newFetcher connects only to the resolved addresses accepted by allow
+func newFetcher(settings Settings, allow func(address netip.AddrPort) bool) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: settings.Timeout,
+		Control: checkAddress(allow),
+	}
+
+	transport := &http.Transport{
+		// proxies from the environment would connect on behalf of the worker and bypass the address check
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    settings.Timeout,
+		ResponseHeaderTimeout:  settings.Timeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		DisableKeepAlives:      true,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   settings.Timeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) > settings.MaxRedirects {
+				return errTooManyRedirects
+			}
+
+			return checkUrl(request.URL)
+		},
+	}
+
+	return &Fetcher{client: client, settings: settings}
+}
+
+// Fetch downloads the page and extracts its preview
+func (f *Fetcher) Fetch(ctx context.Context, rawUrl string) (*content.LinkPreview, error) {
+	target, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkUrl(target); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("User-Agent", f.settings.UserAgent)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,image/*;q=0.8")
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	// the url after redirects, relative links of the page are resolved against it
+	final := response.Request.URL
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		body, err := charset.NewReader(io.LimitReader(response.Body, f.settings.MaxBodySize), response.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+
+		return parse(body, final), nil
+	case strings.HasPrefix(mediaType, "image/"):
+		// direct links to images are previewed by the image itself
+		return &content.LinkPreview{
+			Url:        final.String(),
+			SiteName:   final.Hostname(),
+			ImageUrl:   final.String(),
+			FaviconUrl: defaultFavicon(final),
+		}, nil
+	default:
+		return nil, errUnsupported
+	}
+}
+
+func checkUrl(target *url.URL) error {
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errForbiddenUrl
+	}
+
+	return nil
+}
+
+// checkAddress returns the dial control called for every connection with the resolved address
+func checkAddress(allow func(address netip.AddrPort) bool) func(network string, address string, _ syscall.RawConn) error {
+	return func(network string, address string, _ syscall.RawConn) error {
+		if network != "tcp4" && network != "tcp6" {
+			return errForbiddenAddress
+		}
+
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil || !allow(netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port())) {
+			return errForbiddenAddress
+		}
+
+		return nil
+	}
+}
+
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
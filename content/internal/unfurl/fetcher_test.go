@@ -0,0 +1,246 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSettings = Settings{
+	Timeout:      2 * time.Second,
+	MaxRedirects: 2,
+	MaxBodySize:  1 << 10,
+	UserAgent:    "test",
+}
+
+// newTestServer serves pages used by the tests, the fetcher is allowed to connect only to it
+func newTestServer(t *testing.T) (*httptest.Server, func(address netip.AddrPort) bool) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>Page</title><link rel="icon" href="/icon.png"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/redirect/{count}", func(w http.ResponseWriter, r *http.Request) {
+		count, _ := strconv.Atoi(r.PathValue("count"))
+		if count <= 1 {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+
+		http.Redirect(w, r, "/redirect/"+strconv.Itoa(count-1), http.StatusFound)
+	})
+	mux.HandleFunc("/to", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("url"), http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><meta property="og:description" content="Early"><!--` + strings.Repeat("x", 2<<10) + `--><title>Late</title></head>`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	mux.HandleFunc("/type", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		_, _ = w.Write([]byte(r.URL.Query().Get("body")))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	serverAddress := netip.MustParseAddrPort(server.Listener.Addr().String())
+
+	return server, func(address netip.AddrPort) bool {
+		return address == serverAddress
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server, allow := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		timeout  time.Duration
+		err      error
+		title    string
+		url      string
+		imageUrl string
+		favicon  string
+	}{
+		{name: "html", path: "/page", title: "Page", url: "/page", favicon: "/icon.png"},
+		{name: "redirects under the limit", path: "/redirect/2", title: "Page", url: "/page", favicon: "/icon.png"},
+		{name: "redirects over the limit", path: "/redirect/3", err: errTooManyRedirects},
+		{name: "redirect to loopback", path: "/to?url=" + url.QueryEscape("http://127.0.0.1:1/"), err: errForbiddenAddress},
+		{name: "redirect to metadata service", path: "/to?url=" + url.QueryEscape("http://169.254.169.254/latest/meta-data/"), err: errForbiddenAddress},
+		{name: "redirect to ipv6 loopback", path: "/to?url=" + url.QueryEscape("http://[::1]:1/"), err: errForbiddenAddress},
+		{name: "redirect to other scheme", path: "/to?url=" + url.QueryEscape("file:///etc/passwd"), err: errForbiddenUrl},
+		{name: "xhtml", path: "/type?type=application/xhtml%2Bxml&body=" + url.QueryEscape("<title>Xhtml</title>"), title: "Xhtml", favicon: "/favicon.ico"},
+		{name: "image", path: "/type?type=image/png", imageUrl: "/type?type=image/png", favicon: "/favicon.ico"},
+		{name: "json", path: "/type?type=application/json&body=%7B%7D", err: errUnsupported},
+		{name: "plain text", path: "/type?type=text/plain&body=text", err: errUnsupported},
+		{name: "no content type", path: "/type", err: errUnsupported},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := testSettings
+			if test.timeout > 0 {
+				settings.Timeout = test.timeout
+			}
+
+			preview, err := newFetcher(settings, allow).Fetch(context.Background(), server.URL+test.path)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want %v", err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+
+			if preview.Title != test.title {
+				t.Errorf("title = %q, want %q", preview.Title, test.title)
+			}
+
+			if test.url != "" && preview.Url != server.URL+test.url {
+				t.Errorf("url = %q, want %q", preview.Url, server.URL+test.url)
+			}
+
+			if test.imageUrl != "" && preview.ImageUrl != server.URL+test.imageUrl {
+				t.Errorf("image url = %q, want %q", preview.ImageUrl, server.URL+test.imageUrl)
+			}
+
+			if preview.FaviconUrl != server.URL+test.favicon {
+				t.Errorf("favicon = %q, want %q", preview.FaviconUrl, server.URL+test.favicon)
+			}
+		})
+	}
+}
+
+func TestFetchReadsPartOfBody(t *testing.T) {
+	server, allow := newTestServer(t)
+
+	preview, err := newFetcher(testSettings, allow).Fetch(context.Background(), server.URL+"/large")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	// the title follows the part read
+	if preview.Description != "Early" || preview.Title != "" {
+		t.Fatalf("description = %q, title = %q, want only the description", preview.Description, preview.Title)
+	}
+}
+
+func TestFetchDecodesCharset(t *testing.T) {
+	server, allow := newTestServer(t)
+
+	page := url.QueryEscape("<title>\xcf\xf0\xe8\xe2\xe5\xf2</title>")
+	preview, err := newFetcher(testSettings, allow).Fetch(context.Background(), server.URL+"/type?type="+url.QueryEscape("text/html; charset=windows-1251")+"&body="+page)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	if preview.Title != "Привет" {
+		t.Fatalf("title = %q, want %q", preview.Title, "Привет")
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server, allow := newTestServer(t)
+
+	settings := testSettings
+	settings.Timeout = 100 * time.Millisecond
+
+	started := time.Now()
+	_, err := newFetcher(settings, allow).Fetch(context.Background(), server.URL+"/slow")
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("fetch took %v", elapsed)
+	}
+}
+
+func TestFetchStatus(t *testing.T) {
+	server, allow := newTestServer(t)
+
+	_, err := newFetcher(testSettings, allow).Fetch(context.Background(), server.URL+"/missing")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("err = %v, want unexpected status", err)
+	}
+}
+
+func TestFetchRejectsUrls(t *testing.T) {
+	fetcher := NewFetcher(testSettings)
+
+	for _, rawUrl := range []string{"file:///etc/passwd", "javascript:alert(1)", "ftp://example.com/file", "http:///path", "//example.com"} {
+		t.Run(rawUrl, func(t *testing.T) {
+			if _, err := fetcher.Fetch(context.Background(), rawUrl); !errors.Is(err, errForbiddenUrl) {
+				t.Fatalf("err = %v, want %v", err, errForbiddenUrl)
+			}
+		})
+	}
+}
+
+// TestNewFetcherRefusesLocalServer checks the policy used outside of tests, the server listens on loopback
+func TestNewFetcherRefusesLocalServer(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	_, err := NewFetcher(testSettings).Fetch(context.Background(), server.URL+"/page")
+	if !errors.Is(err, errForbiddenAddress) {
+		t.Fatalf("err = %v, want %v", err, errForbiddenAddress)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{address: "93.184.216.34", public: true},
+		{address: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{address: "127.0.0.1"},
+		{address: "::1"},
+		{address: "::ffff:127.0.0.1"},
+		{address: "169.254.169.254"},
+		{address: "fe80::1"},
+		{address: "10.0.0.1"},
+		{address: "172.16.0.1"},
+		{address: "192.168.1.1"},
+		{address: "fc00::1"},
+		{address: "100.64.0.1"},
+		{address: "0.0.0.0"},
+		{address: "::"},
+		{address: "224.0.0.1"},
+		{address: "198.18.0.1"},
+		{address: "64:ff9b::7f00:1"},
+		{address: "2002:7f00:1::"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			if got := isPublic(netip.MustParseAddr(test.address)); got != test.public {
+				t.Fatalf("isPublic = %v, want %v", got, test.public)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package unfurl
+
+import (
+	"content/internal/handlers/content"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxSiteNameLength    = 100
+)
+
+// parse reads OpenGraph and Twitter card metadata, the title and the icon from the head of the page
+func parse(body io.Reader, base *url.URL) *content.LinkPreview {
+	tokenizer := html.NewTokenizer(body)
+	meta := make(map[string]string)
+	var title, icon string
+	inTitle := false
+
+	for {
+		tokenType := tokenizer.Next()
+
+		switch tokenType {
+		case html.ErrorToken:
+			// the end of the page or of the part read
+			return build(base, meta, title, icon)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttributes := tokenizer.TagName()
+
+			switch string(name) {
+			case "body":
+				return build(base, meta, title, icon)
+			case "title":
+				inTitle = tokenType == html.StartTagToken
+			case "meta":
+				if hasAttributes {
+					key, value := metaAttributes(tokenizer)
+					if _, ok := meta[key]; key != "" && !ok {
+						meta[key] = value
+					}
+				}
+			case "link":
+				if hasAttributes && icon == "" {
+					icon = iconHref(tokenizer)
+				}
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return build(base, meta, title, icon)
+			}
+		}
+	}
+}
+
+func build(base *url.URL, meta map[string]string, title string, icon string) *content.LinkPreview {
+	preview := &content.LinkPreview{
+		Url:         base.String(),
+		Title:       clean(first(meta["og:title"], meta["twitter:title"], title), maxTitleLength),
+		Description: clean(first(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+		SiteName:    clean(first(meta["og:site_name"], base.Hostname()), maxSiteNameLength),
+		ImageUrl:    resolve(base, first(meta["og:image:secure_url"], meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"])),
+		FaviconUrl:  resolve(base, icon),
+	}
+
+	if canonical := resolve(base, meta["og:url"]); canonical != "" {
+		preview.Url = canonical
+	}
+
+	if preview.FaviconUrl == "" {
+		preview.FaviconUrl = defaultFavicon(base)
+	}
+
+	return preview
+}
+
+// metaAttributes returns the lower case property or name of the meta tag and its content
+func metaAttributes(tokenizer *html.Tokenizer) (string, string) {
+	var key, value string
+
+	for {
+		name, attributeValue, more := tokenizer.TagAttr()
+
+		switch string(name) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(string(attributeValue)))
+			}
+		case "content":
+			value = string(attributeValue)
+		}
+
+		if !more {
+			return key, value
+		}
+	}
+}
+
+// iconHref returns the href of the link tag if it is an icon of the page
+func iconHref(tokenizer *html.Tokenizer) string {
+	var rel, href string
+
+	for {
+		name, value, more := tokenizer.TagAttr()
+
+		switch string(name) {
+		case "rel":
+			rel = strings.ToLower(string(value))
+		case "href":
+			href = string(value)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	for _, item := range strings.Fields(rel) {
+		if item == "icon" || item == "apple-touch-icon" {
+			return href
+		}
+	}
+
+	return ""
+}
+
+// resolve makes the reference absolute, only http and https urls are returned
+func resolve(base *url.URL, reference string) string {
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return ""
+	}
+
+	parsed, err := base.Parse(reference)
+	if err != nil || checkUrl(parsed) != nil {
+		return ""
+	}
+
+	return parsed.String()
+}
+
+func defaultFavicon(base *url.URL) string {
+	return (&url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/favicon.ico"}).String()
+}
+
+// clean collapses whitespace and cuts the value to max characters
+func clean(value string, max int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if !utf8.ValidString(value) {
+		value = strings.ToValidUTF8(value, "")
+	}
+
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+
+	return string([]rune(value)[:max-1]) + "…"
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,138 @@
+package unfurl
+
+import (
+	"content/internal/handlers/content"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/articles/1")
+
+	tests := []struct {
+		name string
+		page string
+		want content.LinkPreview
+	}{
+		{
+			name: "open graph",
+			page: `<html><head>
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="OG description">
+				<meta property="og:site_name" content="Example">
+				<meta property="og:image" content="/images/cover.png">
+				<meta property="og:url" content="https://example.com/a/1">
+				<meta name="twitter:title" content="Twitter title">
+				<title>Page title</title>
+				</head></html>`,
+			want: content.LinkPreview{
+				Url:         "https://example.com/a/1",
+				Title:       "OG title",
+				Description: "OG description",
+				SiteName:    "Example",
+				ImageUrl:    "https://example.com/images/cover.png",
+				FaviconUrl:  "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "twitter card",
+			page: `<head>
+				<meta name="twitter:title" content="Twitter title">
+				<meta name="twitter:description" content="Twitter description">
+				<meta name="twitter:image" content="https://cdn.example.com/card.jpg">
+				<meta name="description" content="Description">
+				<title>Page title</title>
+				</head>`,
+			want: content.LinkPreview{
+				Url:         "https://example.com/articles/1",
+				Title:       "Twitter title",
+				Description: "Twitter description",
+				SiteName:    "example.com",
+				ImageUrl:    "https://cdn.example.com/card.jpg",
+				FaviconUrl:  "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "title and description",
+			page: "<head><title>\n  Page \t title </title><meta name=\"Description\" content=\"Plain description\"></head>",
+			want: content.LinkPreview{
+				Url:         "https://example.com/articles/1",
+				Title:       "Page title",
+				Description: "Plain description",
+				SiteName:    "example.com",
+				FaviconUrl:  "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "first value wins",
+			page: `<head><meta property="og:title" content="First"><meta property="og:title" content="Second"></head>`,
+			want: content.LinkPreview{
+				Url:        "https://example.com/articles/1",
+				Title:      "First",
+				SiteName:   "example.com",
+				FaviconUrl: "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "relative favicon",
+			page: `<head><link rel="stylesheet" href="/style.css"><link rel="Shortcut Icon" href="../static/icon.png"></head>`,
+			want: content.LinkPreview{
+				Url:        "https://example.com/articles/1",
+				SiteName:   "example.com",
+				FaviconUrl: "https://example.com/static/icon.png",
+			},
+		},
+		{
+			name: "apple touch icon",
+			page: `<head><link rel="apple-touch-icon" href="//cdn.example.com/touch.png"></head>`,
+			want: content.LinkPreview{
+				Url:        "https://example.com/articles/1",
+				SiteName:   "example.com",
+				FaviconUrl: "https://cdn.example.com/touch.png",
+			},
+		},
+		{
+			name: "scripts in urls",
+			page: `<head>
+				<meta property="og:image" content="javascript:alert(1)">
+				<meta property="og:url" content="data:text/html,<script>alert(1)</script>">
+				<link rel="icon" href="javascript:alert(1)">
+				</head>`,
+			want: content.LinkPreview{
+				Url:        "https://example.com/articles/1",
+				SiteName:   "example.com",
+				FaviconUrl: "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "metadata of the body",
+			page: `<head><title>Head</title></head><body><meta property="og:title" content="Body"></body>`,
+			want: content.LinkPreview{
+				Url:        "https://example.com/articles/1",
+				Title:      "Head",
+				SiteName:   "example.com",
+				FaviconUrl: "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "long title",
+			page: "<title>" + strings.Repeat("a", maxTitleLength+10) + "</title>",
+			want: content.LinkPreview{
+				Url:        "https://example.com/articles/1",
+				Title:      strings.Repeat("a", maxTitleLength-1) + "…",
+				SiteName:   "example.com",
+				FaviconUrl: "https://example.com/favicon.ico",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parse(strings.NewReader(test.page), base)
+			if *got != test.want {
+				t.Fatalf("preview = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package unfurl
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/handlers/types"
+	"content/internal/storage/postgresql"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	StatusReady = "ready"
+	// StatusFailed marks urls that could not be unfurled, they are not fetched again until the cache expires
+	StatusFailed = "failed"
+)
+
+// entry is the cached preview of a url
+type entry struct {
+	Url       string    `db:"url"`
+	Status    string    `db:"status"`
+	Preview   string    `db:"preview"`
+	FetchedAt time.Time `db:"fetched_at"`
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+func newRepository(db *sqlx.DB) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Get(ctx context.Context, url string) (*entry, error) {
+	var result entry
+
+	query := `SELECT url, status, COALESCE(CAST(preview AS text), '') AS preview, fetched_at FROM content.link_previews WHERE url = $1`
+
+	err := r.db.GetContext(ctx, &result, query, url)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Save caches the preview and copies it to link items with the url,
+// a failed refresh keeps the preview fetched before
+func (r *repository) Save(ctx context.Context, url string, status string, preview *content.LinkPreview, fetchedAt time.Time) error {
+	value, err := marshal(preview)
+	if err != nil {
+		return err
+	}
+
+	return postgresql.Exec(ctx, r.db, true, func(exec func(query string, args ...any) (sql.Result, error)) error {
+		_, err := exec(`
+			INSERT INTO content.link_previews (url, status, preview, fetched_at)
+			VALUES ($1, $2, CAST($3 AS jsonb), $4)
+			ON CONFLICT (url) DO UPDATE SET
+				status = excluded.status,
+				preview = COALESCE(excluded.preview, content.link_previews.preview),
+				fetched_at = excluded.fetched_at`,
+			url, status, value, fetchedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = exec(`
+			UPDATE content.content c SET link_preview = lp.preview
+			FROM content.link_previews lp
+			WHERE lp.url = $1 AND lp.preview IS NOT NULL AND c.media_url = lp.url AND c.type = $2`,
+			url, types.TypeLink)
+
+		return err
+	})
+}
+
+// Apply copies the cached preview to the item
+func (r *repository) Apply(ctx context.Context, contentId string, cached *entry) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE content.content SET link_preview = CAST(NULLIF($1, '') AS jsonb) WHERE id = $2`,
+		cached.Preview, contentId)
+
+	return err
+}
+
+func marshal(preview *content.LinkPreview) (sql.NullString, error) {
+	if preview == nil {
+		return sql.NullString{}, nil
+	}
+
+	value, err := json.Marshal(preview)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(value), Valid: true}, nil
+}
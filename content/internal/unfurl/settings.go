@@ -0,0 +1,27 @@
+package unfurl
+
+import (
+	"content/internal/lib/config"
+	"time"
+)
+
+type Settings struct {
+	// Timeout limits the whole fetch of a page including redirects
+	Timeout      time.Duration
+	MaxRedirects int
+	// MaxBodySize is the number of bytes of the page read for metadata, the rest is ignored
+	MaxBodySize int64
+	// CacheLifetime is the age after which previews of a url are fetched again
+	CacheLifetime time.Duration
+	UserAgent     string
+}
+
+func MustLoadSettings() Settings {
+	return Settings{
+		Timeout:       time.Duration(config.MustGetInt("UNFURL__TIMEOUT_SECONDS", 5)) * time.Second,
+		MaxRedirects:  config.MustGetInt("UNFURL__MAX_REDIRECTS", 3),
+		MaxBodySize:   int64(config.MustGetInt("UNFURL__MAX_BODY_KB", 512)) << 10,
+		CacheLifetime: time.Duration(config.MustGetInt("UNFURL__CACHE_HOURS", 24)) * time.Hour,
+		UserAgent:     config.GetString("UNFURL__USER_AGENT", "ProfileShareBot/1.0 (+link preview)"),
+	}
+}
@@ -0,0 +1,88 @@
+package unfurl
+
+import (
+	"content/internal/handlers/content"
+	"content/internal/handlers/types"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/flores666/profileshare-lib/eventBus"
+	"github.com/jmoiron/sqlx"
+)
+
+// Worker fetches previews of link items consuming content.ContentCreatedTopic,
+// previews of a url are cached for all items sharing it
+type Worker struct {
+	consumer   eventBus.Consumer
+	repository *repository
+	fetcher    *Fetcher
+	settings   Settings
+	logger     *slog.Logger
+}
+
+func NewWorker(consumer eventBus.Consumer, db *sqlx.DB, settings Settings, logger *slog.Logger) *Worker {
+	return &Worker{
+		consumer:   consumer,
+		repository: newRepository(db),
+		fetcher:    NewFetcher(settings),
+		settings:   settings,
+		logger:     logger.With(slog.String("caller", "unfurl.worker")),
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	return w.consumer.Consume(ctx, func(data []byte) error {
+		var event content.ContentEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			w.logger.Error("unmarshal error", slog.String("error", err.Error()))
+			return nil
+		}
+
+		if event.After == nil || event.After.Type != types.TypeLink || event.After.MediaUrl == "" {
+			return nil
+		}
+
+		return w.handle(ctx, event.ContentId, event.After.MediaUrl)
+	})
+}
+
+// handle returns errors only for failures worth retrying, pages that cannot be unfurled are cached as failed
+func (w *Worker) handle(ctx context.Context, contentId string, url string) error {
+	cached, err := w.repository.Get(ctx, url)
+	if err != nil {
+		w.logger.Error("could not get cached preview", slog.String("error", err.Error()), slog.String("url", url))
+		return err
+	}
+
+	if cached != nil && time.Since(cached.FetchedAt) < w.settings.CacheLifetime {
+		if err = w.repository.Apply(ctx, contentId, cached); err != nil {
+			w.logger.Error("could not apply cached preview", slog.String("error", err.Error()), slog.String("id", contentId))
+			return err
+		}
+
+		return nil
+	}
+
+	status := StatusReady
+	preview, err := w.fetcher.Fetch(ctx, url)
+	if err != nil {
+		// the worker is stopping, the event is handled again after restart
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		w.logger.Warn("could not unfurl url", slog.String("error", err.Error()), slog.String("url", url))
+		status = StatusFailed
+	}
+
+	if err = w.repository.Save(ctx, url, status, preview, time.Now().UTC()); err != nil {
+		w.logger.Error("could not save preview", slog.String("error", err.Error()), slog.String("url", url))
+		return err
+	}
+
+	w.logger.Debug("url unfurled", slog.String("url", url), slog.String("status", status))
+
+	return nil
+}
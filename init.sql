@@ -15,32 +15,143 @@ create index IF not exists mails_index_0 on mailer.mails using btree (idempotenc
 
 create schema content;
 
+create extension IF not exists pg_trgm;
+
 create table content.folders (
                                  id uuid not null,
+                                 user_id uuid not null,
+                                 parent_id uuid null,
                                  name character varying(255) not null,
                                  display_name character varying(255) not null,
-                                 constraint folders_pkey primary key (id)
+                                 visibility character varying(16) not null default 'public',
+                                 created_at timestamp with time zone not null,
+                                 constraint folders_pkey primary key (id),
+                                 constraint folders_visibility_check check (visibility in ('private', 'unlisted', 'followers', 'public')),
+                                 constraint folders_parent_id_fkey foreign KEY (parent_id) references content.folders (id) on update CASCADE,
+                                 constraint folders_parent_id_check check (parent_id <> id)
 );
 
+create index IF not exists folders_index_0 on content.folders using btree (user_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists folders_index_1 on content.folders using btree (parent_id) TABLESPACE pg_default;
+-- folder names are unique among siblings
+create unique index IF not exists folders_index_2 on content.folders using btree (user_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), name) TABLESPACE pg_default;
+
+-- kinds of content with rules for their items and uploads, built-in ones are referenced by the code
 create table content.content_types (
                                        name character varying(255) not null,
-                                       constraint content_types_pkey primary key (name)
+                                       display_name character varying(255) not null,
+                                       mime_types text not null default '',
+                                       max_size bigint not null default 0,
+                                       required_fields character varying(255) not null default '',
+                                       text_allowed boolean not null default true,
+                                       built_in boolean not null default false,
+                                       created_at timestamp with time zone not null,
+                                       updated_at timestamp with time zone not null,
+                                       constraint content_types_pkey primary key (name),
+                                       constraint content_types_max_size_check check (max_size >= 0)
+);
+
+insert into content.content_types (name, display_name, mime_types, max_size, required_fields, text_allowed, built_in, created_at, updated_at) values
+    ('photo', 'Фото', 'image/jpeg,image/png,image/webp,image/gif', 20971520, 'mediaId', true, true, now(), now()),
+    ('video', 'Видео', 'video/mp4,video/webm,video/quicktime', 1073741824, 'mediaId', true, true, now(), now()),
+    ('audio', 'Аудио', 'audio/mpeg,audio/wave,audio/aiff,application/ogg', 104857600, 'mediaId', true, true, now(), now()),
+    ('document', 'Документ', 'application/pdf,application/zip,text/plain', 52428800, 'mediaId', true, true, now(), now()),
+    ('link', 'Ссылка', '', 0, 'mediaUrl', true, true, now(), now()),
+    ('text', 'Текст', '', 0, 'text', true, true, now(), now()) on conflict do nothing;
+
+create table content.media (
+                               id uuid not null,
+                               user_id uuid not null,
+                               type character varying(255) not null,
+                               status character varying(32) not null,
+                               file_name character varying(255) not null,
+                               content_type character varying(255) null,
+                               size bigint not null,
+                               upload_offset bigint not null default 0,
+                               checksum character varying(64) null,
+                               storage_key character varying(255) null,
+                               created_at timestamp with time zone not null,
+                               completed_at timestamp with time zone null,
+                               processing_status character varying(32) null,
+                               width integer null,
+                               height integer null,
+                               blurhash character varying(64) null,
+                               derivatives jsonb null,
+                               constraint media_pkey primary key (id),
+                               constraint media_type_fkey foreign KEY (type) references content.content_types (name),
+                               constraint media_offset_check check (upload_offset >= 0 and upload_offset <= size)
+);
+
+create index IF not exists media_index_0 on content.media using btree (user_id, created_at desc) TABLESPACE pg_default;
+-- media released by purged content waiting for removal of its files
+create index IF not exists media_index_1 on content.media using btree (created_at) TABLESPACE pg_default where status = 'deleted';
+-- abandoned uploads and media never used by content are released by the trash purger
+create index IF not exists media_index_2 on content.media using btree (created_at) TABLESPACE pg_default where status = 'pending';
+create index IF not exists media_index_3 on content.media using btree (completed_at) TABLESPACE pg_default where status = 'ready';
+
+create table content.media_parts (
+                                     media_id uuid not null,
+                                     upload_offset bigint not null,
+                                     size bigint not null,
+                                     storage_key character varying(255) not null,
+                                     constraint media_parts_pkey primary key (media_id, upload_offset),
+                                     constraint media_parts_media_id_fkey foreign KEY (media_id) references content.media (id) on delete CASCADE
 );
 
 create table content.content (
                                  id uuid not null,
                                  user_id uuid not null,
                                  display_name character varying(255) not null,
-                                 text character varying(255) null,
-                                 media_url character varying(255) not null,
+                                 text text null,
+                                 -- format of the text source and the text rendered to sanitized HTML
+                                 text_format character varying(16) not null default 'plain',
+                                 text_html text not null default '',
+                                 media_url character varying(2048) null,
+                                 media_id uuid null,
+                                 width integer null,
+                                 height integer null,
+                                 blurhash character varying(64) null,
+                                 thumbnails jsonb null,
+                                 link_preview jsonb null,
                                  type character varying(255) not null,
+                                 visibility character varying(16) not null default 'public',
+                                 reaction_counts jsonb not null default '{}',
+                                 comments_enabled boolean not null default true,
+                                 version integer not null default 1,
                                  deleted_at timestamp with time zone null,
+                                 blocked_at timestamp with time zone null,
                                  created_at timestamp with time zone not null,
+                                 search_vector tsvector generated always as (
+                                     setweight(to_tsvector('russian', display_name), 'A') ||
+                                     setweight(to_tsvector('english', display_name), 'A') ||
+                                     setweight(to_tsvector('russian', COALESCE(text, '')), 'B') ||
+                                     setweight(to_tsvector('english', COALESCE(text, '')), 'B')
+                                 ) stored,
                                  constraint content_pkey primary key (id),
-                                 constraint content_type_fkey foreign KEY (type) references content.content_types (name)
+                                 constraint content_visibility_check check (visibility in ('private', 'unlisted', 'followers', 'public')),
+                                 constraint content_text_format_check check (text_format in ('plain', 'markdown')),
+                                 constraint content_type_fkey foreign KEY (type) references content.content_types (name),
+                                 constraint content_media_id_fkey foreign KEY (media_id) references content.media (id)
 );
 
-create index IF not exists content_index_0 on content.content using btree (user_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists content_index_0 on content.content using btree (user_id, created_at desc, id desc) TABLESPACE pg_default;
+create index IF not exists content_index_1 on content.content using gin (search_vector) TABLESPACE pg_default;
+create index IF not exists content_index_2 on content.content using gin (display_name gin_trgm_ops) TABLESPACE pg_default;
+-- trash listing and purge of expired items
+create index IF not exists content_index_3 on content.content using btree (user_id, deleted_at desc, id desc) TABLESPACE pg_default where deleted_at is not null;
+create index IF not exists content_index_4 on content.content using btree (deleted_at) TABLESPACE pg_default where deleted_at is not null;
+create index IF not exists content_index_5 on content.content using btree (media_id) TABLESPACE pg_default where media_id is not null;
+-- link items receiving the preview of their url
+create index IF not exists content_index_6 on content.content using btree (media_url) TABLESPACE pg_default where type = 'link';
+
+-- previews of urls shared by link items, fetched again after UNFURL__CACHE_HOURS
+create table content.link_previews (
+                                       url character varying(2048) not null,
+                                       status character varying(32) not null,
+                                       preview jsonb null,
+                                       fetched_at timestamp with time zone not null,
+                                       constraint link_previews_pkey primary key (url)
+);
 
 create table content.folders_contents (
                                           folder_id uuid not null,
@@ -51,6 +162,196 @@ create table content.folders_contents (
                                           constraint folders_contents_folder_id_fkey foreign KEY (folder_id) references content.folders (id) on update CASCADE on delete CASCADE
 );
 
+create index IF not exists folders_contents_index_0 on content.folders_contents using btree (folder_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists folders_contents_index_1 on content.folders_contents using btree (content_id) TABLESPACE pg_default;
+
+create table content.content_revisions (
+                                           id uuid not null,
+                                           content_id uuid not null,
+                                           number integer not null,
+                                           user_id uuid not null,
+                                           snapshot jsonb not null,
+                                           changes jsonb not null,
+                                           restored_from integer null,
+                                           created_at timestamp with time zone not null,
+                                           constraint content_revisions_pkey primary key (id),
+                                           constraint content_revisions_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE
+);
+
+create unique index IF not exists content_revisions_index_0 on content.content_revisions using btree (content_id, number desc) TABLESPACE pg_default;
+-- media kept by revisions is not released by the trash purger
+create index IF not exists content_revisions_index_1 on content.content_revisions using btree ((snapshot->>'mediaId')) TABLESPACE pg_default;
+
+create table content.follows (
+                                 follower_id uuid not null,
+                                 followee_id uuid not null,
+                                 created_at timestamp with time zone not null,
+                                 constraint follows_pkey primary key (follower_id, followee_id),
+                                 constraint follows_self_check check (follower_id <> followee_id)
+);
+
+create index IF not exists follows_index_0 on content.follows using btree (followee_id) TABLESPACE pg_default;
+
+create table content.share_links (
+                                     id uuid not null,
+                                     user_id uuid not null,
+                                     content_id uuid null,
+                                     folder_id uuid null,
+                                     token_hash character varying(64) not null,
+                                     password_hash character varying(255) null,
+                                     expires_at timestamp with time zone null,
+                                     revoked_at timestamp with time zone null,
+                                     created_at timestamp with time zone not null,
+                                     constraint share_links_pkey primary key (id),
+                                     constraint share_links_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE,
+                                     constraint share_links_folder_id_fkey foreign KEY (folder_id) references content.folders (id) on delete CASCADE,
+                                     constraint share_links_target_check check ((content_id is null) <> (folder_id is null))
+);
+
+create unique index IF not exists share_links_index_0 on content.share_links using btree (token_hash) TABLESPACE pg_default;
+create index IF not exists share_links_index_1 on content.share_links using btree (user_id, created_at desc) TABLESPACE pg_default;
+
+create table content.share_link_attempts (
+                                             link_id uuid not null,
+                                             created_at timestamp with time zone not null,
+                                             constraint share_link_attempts_link_id_fkey foreign KEY (link_id) references content.share_links (id) on delete CASCADE
+);
+
+create index IF not exists share_link_attempts_index_0 on content.share_link_attempts using btree (link_id, created_at) TABLESPACE pg_default;
+
+create table content.tags (
+                              id uuid not null,
+                              name character varying(50) not null,
+                              constraint tags_pkey primary key (id)
+);
+
+create unique index IF not exists tags_index_0 on content.tags using btree (name) TABLESPACE pg_default;
+create index IF not exists tags_index_1 on content.tags using btree (name text_pattern_ops) TABLESPACE pg_default;
+
+create table content.content_tags (
+                                      content_id uuid not null,
+                                      tag_id uuid not null,
+                                      created_at timestamp with time zone not null,
+                                      constraint content_tags_pkey primary key (content_id, tag_id),
+                                      constraint content_tags_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE,
+                                      constraint content_tags_tag_id_fkey foreign KEY (tag_id) references content.tags (id) on delete CASCADE
+);
+
+create index IF not exists content_tags_index_0 on content.content_tags using btree (tag_id, content_id) TABLESPACE pg_default;
+
+create table content.reactions (
+                                   content_id uuid not null,
+                                   user_id uuid not null,
+                                   reaction character varying(16) not null,
+                                   created_at timestamp with time zone not null,
+                                   constraint reactions_pkey primary key (content_id, user_id),
+                                   constraint reactions_reaction_check check (reaction in ('like', 'love', 'laugh', 'wow', 'sad', 'fire')),
+                                   constraint reactions_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE
+);
+
+create table content.comments (
+                                  id uuid not null,
+                                  content_id uuid not null,
+                                  user_id uuid not null,
+                                  parent_id uuid null,
+                                  text text not null,
+                                  hidden boolean not null default false,
+                                  blocked_at timestamp with time zone null,
+                                  edited_at timestamp with time zone null,
+                                  deleted_at timestamp with time zone null,
+                                  created_at timestamp with time zone not null,
+                                  constraint comments_pkey primary key (id),
+                                  constraint comments_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE,
+                                  constraint comments_parent_id_fkey foreign KEY (parent_id) references content.comments (id) on delete CASCADE
+);
+
+create index IF not exists comments_index_0 on content.comments using btree (content_id, created_at desc, id desc) TABLESPACE pg_default where parent_id is null;
+create index IF not exists comments_index_1 on content.comments using btree (parent_id, created_at desc, id desc) TABLESPACE pg_default where parent_id is not null;
+create index IF not exists comments_index_2 on content.comments using btree (user_id, created_at desc) TABLESPACE pg_default;
+
+create table content.feed_inbox (
+                                user_id uuid not null,
+                                content_id uuid not null,
+                                author_id uuid not null,
+                                created_at timestamp with time zone not null,
+                                constraint feed_inbox_pkey primary key (user_id, content_id),
+                                constraint feed_inbox_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE
+);
+
+create index IF not exists feed_inbox_index_0 on content.feed_inbox using btree (user_id, created_at desc, content_id desc) TABLESPACE pg_default;
+create index IF not exists feed_inbox_index_1 on content.feed_inbox using btree (user_id, author_id) TABLESPACE pg_default;
+create index IF not exists feed_inbox_index_2 on content.feed_inbox using btree (created_at) TABLESPACE pg_default;
+
+create table content.moderation_cases (
+                                       id uuid not null,
+                                       target_type character varying(16) not null,
+                                       target_id uuid not null,
+                                       owner_id uuid not null,
+                                       status character varying(16) not null default 'open',
+                                       reports_count integer not null default 0,
+                                       auto_hidden boolean not null default false,
+                                       claimed_by uuid null,
+                                       claimed_at timestamp with time zone null,
+                                       resolution character varying(16) null,
+                                       resolved_by uuid null,
+                                       resolved_at timestamp with time zone null,
+                                       created_at timestamp with time zone not null,
+                                       updated_at timestamp with time zone not null,
+                                       constraint moderation_cases_pkey primary key (id),
+                                       constraint moderation_cases_target_type_check check (target_type in ('content', 'comment')),
+                                       constraint moderation_cases_status_check check (status in ('open', 'claimed', 'resolved'))
+);
+
+create table content.reports (
+                              id uuid not null,
+                              case_id uuid not null,
+                              reporter_id uuid not null,
+                              reason character varying(32) not null,
+                              details text null,
+                              created_at timestamp with time zone not null,
+                              constraint reports_pkey primary key (id),
+                              constraint reports_case_id_fkey foreign KEY (case_id) references content.moderation_cases (id) on delete CASCADE
+);
+
+create table content.moderation_decisions (
+                                           id uuid not null,
+                                           case_id uuid not null,
+                                           target_type character varying(16) not null,
+                                           target_id uuid not null,
+                                           owner_id uuid not null,
+                                           moderator_id uuid null,
+                                           action character varying(16) not null,
+                                           reason text null,
+                                           banned_before timestamp with time zone null,
+                                           created_at timestamp with time zone not null,
+                                           constraint moderation_decisions_pkey primary key (id),
+                                           constraint moderation_decisions_case_id_fkey foreign KEY (case_id) references content.moderation_cases (id) on delete CASCADE
+);
+
+-- a single not resolved case per item collects its reports
+create unique index IF not exists moderation_cases_index_0 on content.moderation_cases using btree (target_type, target_id) TABLESPACE pg_default where status <> 'resolved';
+create index IF not exists moderation_cases_index_1 on content.moderation_cases using btree (status, created_at desc, id desc) TABLESPACE pg_default;
+create unique index IF not exists reports_index_0 on content.reports using btree (case_id, reporter_id) TABLESPACE pg_default;
+create index IF not exists moderation_decisions_index_0 on content.moderation_decisions using btree (target_type, target_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists moderation_decisions_index_1 on content.moderation_decisions using btree (owner_id, created_at desc, id desc) TABLESPACE pg_default;
+
+-- events saved with the state changes, published to Kafka and deleted by the outbox relay
+create table content.outbox (
+                                id bigint generated always as identity,
+                                aggregate_id character varying(64) not null,
+                                topic character varying(255) not null,
+                                payload jsonb not null,
+                                attempts integer not null default 0,
+                                last_error text null,
+                                next_attempt_at timestamp with time zone not null,
+                                locked_until timestamp with time zone null,
+                                created_at timestamp with time zone not null,
+                                constraint outbox_pkey primary key (id)
+);
+
+create index IF not exists outbox_index_0 on content.outbox using btree (aggregate_id, id) TABLESPACE pg_default;
+create index IF not exists outbox_index_1 on content.outbox using btree (next_attempt_at, id) TABLESPACE pg_default;
+
 create schema authorization_service;
 
 create table authorization_service.roles (
@@ -90,3 +391,52 @@ create table authorization_service.users (
                                              constraint users_role_id_fkey foreign KEY (role_id) references authorization_service.roles (id)
 );
 
+
+create table authorization_service.audit_log (
+                                                 id uuid not null,
+                                                 actor_id uuid not null,
+                                                 target_id uuid null,
+                                                 action character varying(255) not null,
+                                                 details text null,
+                                                 ip character varying(64) null,
+                                                 created_at timestamp with time zone not null,
+                                                 constraint audit_log_pkey primary key (id)
+);
+
+create index IF not exists audit_log_index_0 on authorization_service.audit_log using btree (actor_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists audit_log_index_1 on authorization_service.audit_log using btree (target_id, created_at desc) TABLESPACE pg_default;
+
+create table authorization_service.access_token_denylist (
+                                                             jti character varying(64) not null,
+                                                             user_id uuid not null,
+                                                             expires_at timestamp with time zone not null,
+                                                             created_at timestamp with time zone not null,
+                                                             constraint access_token_denylist_pkey primary key (jti)
+);
+
+create index IF not exists access_token_denylist_index_0 on authorization_service.access_token_denylist using btree (expires_at) TABLESPACE pg_default;
+
+-- events saved with the state changes, published to Kafka and deleted by the outbox relay
+create table authorization_service.outbox (
+                                              id bigint generated always as identity,
+                                              aggregate_id character varying(64) not null,
+                                              topic character varying(255) not null,
+                                              payload jsonb not null,
+                                              attempts integer not null default 0,
+                                              last_error text null,
+                                              next_attempt_at timestamp with time zone not null,
+                                              locked_until timestamp with time zone null,
+                                              created_at timestamp with time zone not null,
+                                              constraint outbox_pkey primary key (id)
+);
+
+create index IF not exists outbox_index_0 on authorization_service.outbox using btree (aggregate_id, id) TABLESPACE pg_default;
+create index IF not exists outbox_index_1 on authorization_service.outbox using btree (next_attempt_at, id) TABLESPACE pg_default;
+
+-- tokens.token and users.code store HMAC-SHA256 hashes (see SECURITY__TOKEN_PEPPER)
+create unique index IF not exists tokens_index_0 on authorization_service.tokens using btree (token) TABLESPACE pg_default;
+
+create index IF not exists tokens_index_1 on authorization_service.tokens using btree (expires_at) TABLESPACE pg_default;
+create index IF not exists tokens_index_2 on authorization_service.tokens using btree (revoked_at) where revoked_at is not null;
+create index IF not exists users_index_0 on authorization_service.users using btree (created_at) where is_confirmed = false;
+create index IF not exists users_index_1 on authorization_service.users using btree (created_at desc, id desc) TABLESPACE pg_default;
@@ -0,0 +1,13 @@
+create table IF not exists authorization_service.audit_log (
+                                                               id uuid not null,
+                                                               actor_id uuid not null,
+                                                               target_id uuid null,
+                                                               action character varying(255) not null,
+                                                               details text null,
+                                                               ip character varying(64) null,
+                                                               created_at timestamp with time zone not null,
+                                                               constraint audit_log_pkey primary key (id)
+);
+
+create index IF not exists audit_log_index_0 on authorization_service.audit_log using btree (actor_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists audit_log_index_1 on authorization_service.audit_log using btree (target_id, created_at desc) TABLESPACE pg_default;
@@ -0,0 +1,9 @@
+create table IF not exists authorization_service.access_token_denylist (
+                                                                           jti character varying(64) not null,
+                                                                           user_id uuid not null,
+                                                                           expires_at timestamp with time zone not null,
+                                                                           created_at timestamp with time zone not null,
+                                                                           constraint access_token_denylist_pkey primary key (jti)
+);
+
+create index IF not exists access_token_denylist_index_0 on authorization_service.access_token_denylist using btree (expires_at) TABLESPACE pg_default;
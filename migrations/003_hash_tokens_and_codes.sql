@@ -0,0 +1,18 @@
+-- Replaces plaintext refresh tokens and confirmation codes with HMAC-SHA256 hashes.
+-- Run with the same pepper as SECURITY__TOKEN_PEPPER:
+--   psql "$DB__CONNECTION_STRING" -v pepper="$SECURITY__TOKEN_PEPPER" -f migrations/003_hash_tokens_and_codes.sql
+-- Already hashed values (64 hex characters) are skipped, so the script can be re-run safely.
+
+create extension IF not exists pgcrypto;
+
+update authorization_service.tokens
+set token = encode(hmac(token, :'pepper', 'sha256'), 'hex')
+where token !~ '^[0-9a-f]{64}$';
+
+update authorization_service.users
+set code = encode(hmac(code, :'pepper', 'sha256'), 'hex')
+where code is not null
+  and code <> ''
+  and code !~ '^[0-9a-f]{64}$';
+
+create unique index IF not exists tokens_index_0 on authorization_service.tokens using btree (token) TABLESPACE pg_default;
@@ -0,0 +1,3 @@
+create index IF not exists tokens_index_1 on authorization_service.tokens using btree (expires_at) TABLESPACE pg_default;
+create index IF not exists tokens_index_2 on authorization_service.tokens using btree (revoked_at) where revoked_at is not null;
+create index IF not exists users_index_0 on authorization_service.users using btree (created_at) where is_confirmed = false;
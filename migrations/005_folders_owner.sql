@@ -0,0 +1,54 @@
+-- Folders get an owner. Existing folders take the owner of their content, empty folders without owner are removed.
+
+alter table content.folders add column IF not exists user_id uuid null;
+alter table content.folders add column IF not exists created_at timestamp with time zone null;
+
+update content.folders f
+set user_id = (
+    select c.user_id
+    from content.folders_contents fc
+    join content.content c on c.id = fc.content_id
+    where fc.folder_id = f.id
+    order by fc.created_at
+    limit 1
+)
+where f.user_id is null;
+
+update content.folders f
+set created_at = coalesce(
+    (select min(fc.created_at) from content.folders_contents fc where fc.folder_id = f.id),
+    now()
+)
+where f.created_at is null;
+
+delete from content.folders where user_id is null;
+
+alter table content.folders alter column user_id set not null;
+alter table content.folders alter column created_at set not null;
+
+-- names are normalized like folders.normalizeName: whitespace is trimmed and collapsed, letters are lower cased
+update content.folders
+set name = lower(btrim(regexp_replace(display_name, '[[:space:]]+', ' ', 'g')));
+
+-- folders of a user differing only in case or whitespace are renamed, the oldest keeps its name
+with duplicates as (
+    select id, row_number() over (partition by user_id, name order by created_at, id) as number
+    from content.folders
+), renamed as (
+    select f.id, left(f.display_name, 244) || ' (' || left(CAST(f.id AS text), 8) || ')' as display_name
+    from content.folders f
+    join duplicates d on d.id = f.id
+    where d.number > 1
+)
+update content.folders f
+set display_name = r.display_name,
+    name = lower(btrim(regexp_replace(r.display_name, '[[:space:]]+', ' ', 'g')))
+from renamed r
+where r.id = f.id;
+
+alter table content.folders drop constraint IF exists folders_user_id_name_key;
+alter table content.folders add constraint folders_user_id_name_key unique (user_id, name);
+
+create index IF not exists folders_index_0 on content.folders using btree (user_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists folders_contents_index_0 on content.folders_contents using btree (folder_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists folders_contents_index_1 on content.folders_contents using btree (content_id) TABLESPACE pg_default;
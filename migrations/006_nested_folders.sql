@@ -0,0 +1,13 @@
+alter table content.folders add column IF not exists parent_id uuid null;
+
+alter table content.folders drop constraint IF exists folders_parent_id_fkey;
+alter table content.folders add constraint folders_parent_id_fkey foreign KEY (parent_id) references content.folders (id) on update CASCADE;
+
+alter table content.folders drop constraint IF exists folders_parent_id_check;
+alter table content.folders add constraint folders_parent_id_check check (parent_id <> id);
+
+-- folder names are unique among siblings
+alter table content.folders drop constraint IF exists folders_user_id_name_key;
+create unique index IF not exists folders_index_2 on content.folders using btree (user_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), name) TABLESPACE pg_default;
+
+create index IF not exists folders_index_1 on content.folders using btree (parent_id) TABLESPACE pg_default;
@@ -0,0 +1,5 @@
+-- keyset pagination orders lists by (created_at, id)
+drop index IF exists content.content_index_0;
+create index IF not exists content_index_0 on content.content using btree (user_id, created_at desc, id desc) TABLESPACE pg_default;
+
+create index IF not exists users_index_1 on authorization_service.users using btree (created_at desc, id desc) TABLESPACE pg_default;
@@ -0,0 +1,11 @@
+create extension IF not exists pg_trgm;
+
+alter table content.content add column IF not exists search_vector tsvector generated always as (
+    setweight(to_tsvector('russian', display_name), 'A') ||
+    setweight(to_tsvector('english', display_name), 'A') ||
+    setweight(to_tsvector('russian', COALESCE(text, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(text, '')), 'B')
+) stored;
+
+create index IF not exists content_index_1 on content.content using gin (search_vector) TABLESPACE pg_default;
+create index IF not exists content_index_2 on content.content using gin (display_name gin_trgm_ops) TABLESPACE pg_default;
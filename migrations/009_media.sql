@@ -0,0 +1,37 @@
+insert into content.content_types (name) values ('photo'), ('video') on conflict do nothing;
+
+create table IF not exists content.media (
+    id uuid not null,
+    user_id uuid not null,
+    type character varying(255) not null,
+    status character varying(32) not null,
+    file_name character varying(255) not null,
+    content_type character varying(255) null,
+    size bigint not null,
+    upload_offset bigint not null default 0,
+    checksum character varying(64) null,
+    storage_key character varying(255) null,
+    created_at timestamp with time zone not null,
+    completed_at timestamp with time zone null,
+    constraint media_pkey primary key (id),
+    constraint media_type_fkey foreign KEY (type) references content.content_types (name),
+    constraint media_offset_check check (upload_offset >= 0 and upload_offset <= size)
+);
+
+create index IF not exists media_index_0 on content.media using btree (user_id, created_at desc) TABLESPACE pg_default;
+
+create table IF not exists content.media_parts (
+    media_id uuid not null,
+    upload_offset bigint not null,
+    size bigint not null,
+    storage_key character varying(255) not null,
+    constraint media_parts_pkey primary key (media_id, upload_offset),
+    constraint media_parts_media_id_fkey foreign KEY (media_id) references content.media (id) on delete CASCADE
+);
+
+-- existing rows keep raw links, new rows reference uploaded media
+alter table content.content alter column media_url drop not null;
+alter table content.content add column IF not exists media_id uuid null;
+
+alter table content.content drop constraint IF exists content_media_id_fkey;
+alter table content.content add constraint content_media_id_fkey foreign KEY (media_id) references content.media (id);
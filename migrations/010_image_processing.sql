@@ -0,0 +1,13 @@
+alter table content.media add column IF not exists processing_status character varying(32) null;
+alter table content.media add column IF not exists width integer null;
+alter table content.media add column IF not exists height integer null;
+alter table content.media add column IF not exists blurhash character varying(64) null;
+alter table content.media add column IF not exists derivatives jsonb null;
+
+alter table content.content add column IF not exists width integer null;
+alter table content.content add column IF not exists height integer null;
+alter table content.content add column IF not exists blurhash character varying(64) null;
+alter table content.content add column IF not exists thumbnails jsonb null;
+
+-- photos uploaded before the worker existed are processed after republishing content.media_uploaded
+update content.media set processing_status = 'pending' where processing_status is null and type = 'photo' and status = 'ready';
@@ -0,0 +1,46 @@
+-- existing content and folders stay public
+alter table content.folders add column IF not exists visibility character varying(16) not null default 'public';
+alter table content.content add column IF not exists visibility character varying(16) not null default 'public';
+
+alter table content.folders drop constraint IF exists folders_visibility_check;
+alter table content.folders add constraint folders_visibility_check check (visibility in ('private', 'unlisted', 'followers', 'public'));
+alter table content.content drop constraint IF exists content_visibility_check;
+alter table content.content add constraint content_visibility_check check (visibility in ('private', 'unlisted', 'followers', 'public'));
+
+create table IF not exists content.follows (
+    follower_id uuid not null,
+    followee_id uuid not null,
+    created_at timestamp with time zone not null,
+    constraint follows_pkey primary key (follower_id, followee_id),
+    constraint follows_self_check check (follower_id <> followee_id)
+);
+
+create index IF not exists follows_index_0 on content.follows using btree (followee_id) TABLESPACE pg_default;
+
+create table IF not exists content.share_links (
+    id uuid not null,
+    user_id uuid not null,
+    content_id uuid null,
+    folder_id uuid null,
+    token_hash character varying(64) not null,
+    password_hash character varying(255) null,
+    expires_at timestamp with time zone null,
+    revoked_at timestamp with time zone null,
+    created_at timestamp with time zone not null,
+    constraint share_links_pkey primary key (id),
+    constraint share_links_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE,
+    constraint share_links_folder_id_fkey foreign KEY (folder_id) references content.folders (id) on delete CASCADE,
+    constraint share_links_target_check check ((content_id is null) <> (folder_id is null))
+);
+
+create unique index IF not exists share_links_index_0 on content.share_links using btree (token_hash) TABLESPACE pg_default;
+create index IF not exists share_links_index_1 on content.share_links using btree (user_id, created_at desc) TABLESPACE pg_default;
+
+-- failed password attempts of share links, guesses are limited per link
+create table IF not exists content.share_link_attempts (
+    link_id uuid not null,
+    created_at timestamp with time zone not null,
+    constraint share_link_attempts_link_id_fkey foreign KEY (link_id) references content.share_links (id) on delete CASCADE
+);
+
+create index IF not exists share_link_attempts_index_0 on content.share_link_attempts using btree (link_id, created_at) TABLESPACE pg_default;
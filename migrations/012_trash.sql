@@ -0,0 +1,7 @@
+create index IF not exists content_index_3 on content.content using btree (user_id, deleted_at desc, id desc) TABLESPACE pg_default where deleted_at is not null;
+create index IF not exists content_index_4 on content.content using btree (deleted_at) TABLESPACE pg_default where deleted_at is not null;
+create index IF not exists media_index_1 on content.media using btree (created_at) TABLESPACE pg_default where status = 'deleted';
+-- abandoned uploads and media never used by content are released by the trash purger
+create index IF not exists media_index_2 on content.media using btree (created_at) TABLESPACE pg_default where status = 'pending';
+create index IF not exists media_index_3 on content.media using btree (completed_at) TABLESPACE pg_default where status = 'ready';
+create index IF not exists content_index_5 on content.content using btree (media_id) TABLESPACE pg_default where media_id is not null;
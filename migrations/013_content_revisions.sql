@@ -0,0 +1,16 @@
+create table IF not exists content.content_revisions (
+    id uuid not null,
+    content_id uuid not null,
+    number integer not null,
+    user_id uuid not null,
+    snapshot jsonb not null,
+    changes jsonb not null,
+    restored_from integer null,
+    created_at timestamp with time zone not null,
+    constraint content_revisions_pkey primary key (id),
+    constraint content_revisions_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE
+);
+
+create unique index IF not exists content_revisions_index_0 on content.content_revisions using btree (content_id, number desc) TABLESPACE pg_default;
+-- media kept by revisions is not released by the trash purger
+create index IF not exists content_revisions_index_1 on content.content_revisions using btree ((snapshot->>'mediaId')) TABLESPACE pg_default;
@@ -0,0 +1 @@
+alter table content.content add column IF not exists version integer not null default 1;
@@ -0,0 +1,20 @@
+create table IF not exists content.tags (
+    id uuid not null,
+    name character varying(50) not null,
+    constraint tags_pkey primary key (id)
+);
+
+create unique index IF not exists tags_index_0 on content.tags using btree (name) TABLESPACE pg_default;
+-- prefix search of autocomplete
+create index IF not exists tags_index_1 on content.tags using btree (name text_pattern_ops) TABLESPACE pg_default;
+
+create table IF not exists content.content_tags (
+    content_id uuid not null,
+    tag_id uuid not null,
+    created_at timestamp with time zone not null,
+    constraint content_tags_pkey primary key (content_id, tag_id),
+    constraint content_tags_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE,
+    constraint content_tags_tag_id_fkey foreign KEY (tag_id) references content.tags (id) on delete CASCADE
+);
+
+create index IF not exists content_tags_index_0 on content.content_tags using btree (tag_id, content_id) TABLESPACE pg_default;
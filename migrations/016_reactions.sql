@@ -0,0 +1,12 @@
+-- counts are maintained by the service together with content.reactions
+alter table content.content add column IF not exists reaction_counts jsonb not null default '{}';
+
+create table IF not exists content.reactions (
+    content_id uuid not null,
+    user_id uuid not null,
+    reaction character varying(16) not null,
+    created_at timestamp with time zone not null,
+    constraint reactions_pkey primary key (content_id, user_id),
+    constraint reactions_reaction_check check (reaction in ('like', 'love', 'laugh', 'wow', 'sad', 'fire')),
+    constraint reactions_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE
+);
@@ -0,0 +1,21 @@
+alter table content.content add column IF not exists comments_enabled boolean not null default true;
+
+create table IF not exists content.comments (
+    id uuid not null,
+    content_id uuid not null,
+    user_id uuid not null,
+    parent_id uuid null,
+    text text not null,
+    hidden boolean not null default false,
+    edited_at timestamp with time zone null,
+    deleted_at timestamp with time zone null,
+    created_at timestamp with time zone not null,
+    constraint comments_pkey primary key (id),
+    constraint comments_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE,
+    constraint comments_parent_id_fkey foreign KEY (parent_id) references content.comments (id) on delete CASCADE
+);
+
+create index IF not exists comments_index_0 on content.comments using btree (content_id, created_at desc, id desc) TABLESPACE pg_default where parent_id is null;
+create index IF not exists comments_index_1 on content.comments using btree (parent_id, created_at desc, id desc) TABLESPACE pg_default where parent_id is not null;
+-- rate limit of comments per user
+create index IF not exists comments_index_2 on content.comments using btree (user_id, created_at desc) TABLESPACE pg_default;
@@ -0,0 +1,13 @@
+-- materialized feeds of users following many accounts, filled by the feed worker
+create table IF not exists content.feed_inbox (
+    user_id uuid not null,
+    content_id uuid not null,
+    author_id uuid not null,
+    created_at timestamp with time zone not null,
+    constraint feed_inbox_pkey primary key (user_id, content_id),
+    constraint feed_inbox_content_id_fkey foreign KEY (content_id) references content.content (id) on delete CASCADE
+);
+
+create index IF not exists feed_inbox_index_0 on content.feed_inbox using btree (user_id, created_at desc, content_id desc) TABLESPACE pg_default;
+create index IF not exists feed_inbox_index_1 on content.feed_inbox using btree (user_id, author_id) TABLESPACE pg_default;
+create index IF not exists feed_inbox_index_2 on content.feed_inbox using btree (created_at) TABLESPACE pg_default;
@@ -0,0 +1,56 @@
+-- items hidden by moderators are shown only to their authors
+alter table content.content add column IF not exists blocked_at timestamp with time zone null;
+alter table content.comments add column IF not exists blocked_at timestamp with time zone null;
+
+create table IF not exists content.moderation_cases (
+    id uuid not null,
+    target_type character varying(16) not null,
+    target_id uuid not null,
+    owner_id uuid not null,
+    status character varying(16) not null default 'open',
+    reports_count integer not null default 0,
+    auto_hidden boolean not null default false,
+    claimed_by uuid null,
+    claimed_at timestamp with time zone null,
+    resolution character varying(16) null,
+    resolved_by uuid null,
+    resolved_at timestamp with time zone null,
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone not null,
+    constraint moderation_cases_pkey primary key (id),
+    constraint moderation_cases_target_type_check check (target_type in ('content', 'comment')),
+    constraint moderation_cases_status_check check (status in ('open', 'claimed', 'resolved'))
+);
+
+create table IF not exists content.reports (
+    id uuid not null,
+    case_id uuid not null,
+    reporter_id uuid not null,
+    reason character varying(32) not null,
+    details text null,
+    created_at timestamp with time zone not null,
+    constraint reports_pkey primary key (id),
+    constraint reports_case_id_fkey foreign KEY (case_id) references content.moderation_cases (id) on delete CASCADE
+);
+
+create table IF not exists content.moderation_decisions (
+    id uuid not null,
+    case_id uuid not null,
+    target_type character varying(16) not null,
+    target_id uuid not null,
+    owner_id uuid not null,
+    moderator_id uuid null,
+    action character varying(16) not null,
+    reason text null,
+    banned_before timestamp with time zone null,
+    created_at timestamp with time zone not null,
+    constraint moderation_decisions_pkey primary key (id),
+    constraint moderation_decisions_case_id_fkey foreign KEY (case_id) references content.moderation_cases (id) on delete CASCADE
+);
+
+-- a single not resolved case per item collects its reports
+create unique index IF not exists moderation_cases_index_0 on content.moderation_cases using btree (target_type, target_id) TABLESPACE pg_default where status <> 'resolved';
+create index IF not exists moderation_cases_index_1 on content.moderation_cases using btree (status, created_at desc, id desc) TABLESPACE pg_default;
+create unique index IF not exists reports_index_0 on content.reports using btree (case_id, reporter_id) TABLESPACE pg_default;
+create index IF not exists moderation_decisions_index_0 on content.moderation_decisions using btree (target_type, target_id, created_at desc) TABLESPACE pg_default;
+create index IF not exists moderation_decisions_index_1 on content.moderation_decisions using btree (owner_id, created_at desc, id desc) TABLESPACE pg_default;
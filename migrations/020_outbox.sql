@@ -0,0 +1,32 @@
+-- events saved with the state changes, published to Kafka and deleted by the outbox relays
+create table IF not exists content.outbox (
+    id bigint generated always as identity,
+    aggregate_id character varying(64) not null,
+    topic character varying(255) not null,
+    payload jsonb not null,
+    attempts integer not null default 0,
+    last_error text null,
+    next_attempt_at timestamp with time zone not null,
+    locked_until timestamp with time zone null,
+    created_at timestamp with time zone not null,
+    constraint outbox_pkey primary key (id)
+);
+
+create index IF not exists outbox_index_0 on content.outbox using btree (aggregate_id, id) TABLESPACE pg_default;
+create index IF not exists outbox_index_1 on content.outbox using btree (next_attempt_at, id) TABLESPACE pg_default;
+
+create table IF not exists authorization_service.outbox (
+    id bigint generated always as identity,
+    aggregate_id character varying(64) not null,
+    topic character varying(255) not null,
+    payload jsonb not null,
+    attempts integer not null default 0,
+    last_error text null,
+    next_attempt_at timestamp with time zone not null,
+    locked_until timestamp with time zone null,
+    created_at timestamp with time zone not null,
+    constraint outbox_pkey primary key (id)
+);
+
+create index IF not exists outbox_index_0 on authorization_service.outbox using btree (aggregate_id, id) TABLESPACE pg_default;
+create index IF not exists outbox_index_1 on authorization_service.outbox using btree (next_attempt_at, id) TABLESPACE pg_default;
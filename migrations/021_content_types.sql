@@ -0,0 +1,30 @@
+-- content types become data: rules for items and uploads are kept with the type
+alter table content.content_types add column IF not exists display_name character varying(255) null;
+alter table content.content_types add column IF not exists mime_types text not null default '';
+alter table content.content_types add column IF not exists max_size bigint not null default 0;
+alter table content.content_types add column IF not exists required_fields character varying(255) not null default '';
+alter table content.content_types add column IF not exists text_allowed boolean not null default true;
+alter table content.content_types add column IF not exists built_in boolean not null default false;
+alter table content.content_types add column IF not exists created_at timestamp with time zone not null default now();
+alter table content.content_types add column IF not exists updated_at timestamp with time zone not null default now();
+
+insert into content.content_types (name, display_name, mime_types, max_size, required_fields, text_allowed, built_in, created_at, updated_at) values
+    ('photo', 'Фото', 'image/jpeg,image/png,image/webp,image/gif', 20971520, 'mediaId', true, true, now(), now()),
+    ('video', 'Видео', 'video/mp4,video/webm,video/quicktime', 1073741824, 'mediaId', true, true, now(), now()),
+    ('audio', 'Аудио', 'audio/mpeg,audio/wave,audio/aiff,application/ogg', 104857600, 'mediaId', true, true, now(), now()),
+    ('document', 'Документ', 'application/pdf,application/zip,text/plain', 52428800, 'mediaId', true, true, now(), now()),
+    ('link', 'Ссылка', '', 0, 'mediaUrl', true, true, now(), now()),
+    ('text', 'Текст', '', 0, 'text', true, true, now(), now())
+on conflict (name) do update set
+    display_name = excluded.display_name,
+    mime_types = excluded.mime_types,
+    max_size = excluded.max_size,
+    required_fields = excluded.required_fields,
+    built_in = true,
+    updated_at = excluded.updated_at;
+
+update content.content_types set display_name = name where display_name is null;
+alter table content.content_types alter column display_name set not null;
+
+-- links keep their full address
+alter table content.content alter column media_url type character varying(2048);
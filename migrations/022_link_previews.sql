@@ -0,0 +1,12 @@
+-- previews of link items unfurled by the background worker
+alter table content.content add column IF not exists link_preview jsonb null;
+
+create index IF not exists content_index_6 on content.content using btree (media_url) TABLESPACE pg_default where type = 'link';
+
+create table IF not exists content.link_previews (
+    url character varying(2048) not null,
+    status character varying(32) not null,
+    preview jsonb null,
+    fetched_at timestamp with time zone not null,
+    constraint link_previews_pkey primary key (url)
+);
@@ -0,0 +1,24 @@
+-- long form text: markdown source is stored with the rendered HTML
+-- the search vector is generated from the text, so it is recreated with the new column type
+alter table content.content drop column IF exists search_vector;
+alter table content.content alter column text type text;
+alter table content.content add column search_vector tsvector generated always as (
+    setweight(to_tsvector('russian', display_name), 'A') ||
+    setweight(to_tsvector('english', display_name), 'A') ||
+    setweight(to_tsvector('russian', COALESCE(text, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(text, '')), 'B')
+) stored;
+
+create index IF not exists content_index_1 on content.content using gin (search_vector) TABLESPACE pg_default;
+
+alter table content.content add column IF not exists text_format character varying(16) not null default 'plain';
+alter table content.content add column IF not exists text_html text not null default '';
+
+alter table content.content drop constraint IF exists content_text_format_check;
+alter table content.content add constraint content_text_format_check check (text_format in ('plain', 'markdown'));
+
+-- existing plain text is escaped, links, mentions and hashtags are rendered when the text is changed
+update content.content
+set text_html = '<p>' || replace(replace(replace(replace(replace(replace(
+        text, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'), E'\n', E'<br>\n') || '</p>'
+where text_html = '' and COALESCE(text, '') <> '';